@@ -2,6 +2,7 @@ package game
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/ghthor/aodd/game/datastore"
 	"github.com/ghthor/filu/rpg2d"
@@ -40,6 +41,17 @@ type actorEntity struct {
 
 	lastStartedCharge stime.Time
 
+	cast *castAction
+
+	// Emote being animated
+	animation *animationAction
+
+	equipment Equipment
+
+	// The power the actor serves and its guild
+	allegiance datastore.Allegiance
+	guild      string
+
 	// Health and Mana
 	hp, hpMax,
 	mp, mpMax int
@@ -63,6 +75,19 @@ type ActorEntityState struct {
 
 	PathAction *coord.PathActionState `json:"pathAction"`
 
+	// Skill being cast or channeled
+	Cast *CastState `json:"cast"`
+
+	// Emote being animated
+	Animation *AnimationState `json:"animation"`
+
+	// Items the actor has equipped
+	Equipment Equipment `json:"equipment"`
+
+	// The power the actor serves and its guild
+	Allegiance datastore.Allegiance `json:"allegiance"`
+	Guild      string               `json:"guild"`
+
 	// Health and Mana
 	Hp    int `json:"hp"`
 	HpMax int `json:"hpMax"`
@@ -76,14 +101,71 @@ type actor struct {
 	actorEntity
 	undoLastMoveAction func()
 
-	// Store the last assail me made
-	lastAssail assailEntity
+	// Steps being taken to move to a cell
+	path *movePath
+
+	// The last move to command that has arrived
+	// at its cell or couldn't find a path to it.
+	finishedMoveCmd *moveCmd
+
+	// When each skill will be available to use again
+	cooldowns cooldownTable
+
+	// The last use command that was rejected
+	rejectedUseCmd *useCmd
+
+	// Spent to sculpt terrain and build structures
+	resources Resources
+
+	// The item being crafted
+	craft *craftAction
+
+	// Records the actor's history into its legend
+	legend      *legendRecorder
+	connectedAt time.Time
+
+	// Delivers messages sent to channels other than say
+	chat *chatChannels
+
+	chatMod  *chatModeration
+	chatRate chatRate
+
+	// The party the actor is a member of
+	parties         *partyTable
+	lastPartyStatus PartyMsg
+
+	// The trade the actor is making and the actor
+	// it has last requested to trade with.
+	trade          *trade
+	tradeRequested *actor
+
+	// Standing with the power the actor serves
+	standing int
+	factions *factionTable
+
+	// The slash commands the actor can issue
+	permission Permission
+
+	// Where the actor is respawned when it dies
+	spawn coord.Cell
+
+	// Called instead of respawning at its spawn
+	// when the actor dies. Used by npcs which are
+	// respawned by their spawner.
+	onDeath func()
+
+	// The zone simulating the actor if the
+	// world is divided into zones.
+	zone *localZone
+
+	// Tagged with the actor's connection and name
+	logger *Logger
 
 	actorConn
 }
 
 func NewActor(id entity.Id, dsactor datastore.Actor, stateWriter InitialStateWriter) *actor {
-	return &actor{
+	a := &actor{
 		id: dsactor.Id,
 
 		actorEntity: actorEntity{
@@ -102,15 +184,22 @@ func NewActor(id entity.Id, dsactor datastore.Actor, stateWriter InitialStateWri
 				To:   dsactor.Facing,
 			},
 
-			hp:    100,
-			hpMax: 100,
+			hp:    baseHpMax,
+			hpMax: baseHpMax,
 
 			createdAt: 0,
 			flags:     entity.FlagNew,
 		},
 
+		resources: startingResources.clone(),
+
+		spawn: origin,
+
 		actorConn: newActorConn(stateWriter),
 	}
+
+	a.sendResources(0)
+	return a
 }
 
 func (a actor) Id() rpg2d.ActorId      { return a.id }
@@ -143,6 +232,20 @@ func (e actorEntity) ToState() entity.State {
 		pathAction = &pa
 	}
 
+	var cast *CastState
+
+	if e.cast != nil {
+		c := e.cast.ToState()
+		cast = &c
+	}
+
+	var animation *AnimationState
+
+	if e.animation != nil {
+		a := e.animation.ToState()
+		animation = &a
+	}
+
 	return ActorEntityState{
 		Id: e.id,
 
@@ -155,6 +258,15 @@ func (e actorEntity) ToState() entity.State {
 
 		PathAction: pathAction,
 
+		Cast: cast,
+
+		Animation: animation,
+
+		Equipment: e.equipment,
+
+		Allegiance: e.allegiance,
+		Guild:      e.guild,
+
 		Hp:    e.hp,
 		HpMax: e.hpMax,
 		Mp:    e.mp,
@@ -162,6 +274,35 @@ func (e actorEntity) ToState() entity.State {
 	}
 }
 
+// Reduces the actor's health. If the actor dies it
+// is respawned at its spawn unless it has an onDeath.
+// Returns true if the actor died.
+func (a *actor) takeDamage(damage int) (died bool) {
+	// An npc that has already died is waiting to be respawned
+	if damage <= 0 || a.hp <= 0 {
+		return false
+	}
+
+	a.interruptCast()
+	a.hp -= damage
+
+	if a.hp <= 0 {
+		if a.onDeath != nil {
+			a.onDeath()
+			return true
+		}
+
+		a.hp = a.hpMax
+
+		a.actorEntity.cell = a.spawn
+		a.actorEntity.facing = coord.South
+		a.actorEntity.pathAction = nil
+		return true
+	}
+
+	return false
+}
+
 func (e actorEntity) HasChanged(next entity.State, now stime.Time) bool {
 	return e.lastState.IsDifferentFrom(next)
 }
@@ -194,6 +335,22 @@ func (e ActorEntityState) IsDifferentFrom(other entity.State) (different bool) {
 			return true
 		case e.Mp != o.Mp || e.MpMax != o.MpMax:
 			return true
+
+		case (e.Cast == nil) != (o.Cast == nil):
+			return true
+		case e.Cast != nil && *e.Cast != *o.Cast:
+			return true
+
+		case (e.Animation == nil) != (o.Animation == nil):
+			return true
+		case e.Animation != nil && *e.Animation != *o.Animation:
+			return true
+
+		case e.Equipment != o.Equipment:
+			return true
+
+		case e.Allegiance != o.Allegiance || e.Guild != o.Guild:
+			return true
 		}
 
 		return false
@@ -0,0 +1,329 @@
+package game
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// How long the admin api waits for every zone
+// to record a snapshot of its entities.
+const adminSnapshotTimeout = time.Second
+
+// The name broadcasts from the admin api are sent from.
+const adminBroadcastFrom = "server"
+
+// Sent to an actor's connection when it has been kicked.
+// The actor is no longer part of any simulation.
+type KickedMsg struct {
+	Time   stime.Time `json:"time"`
+	Reason string     `json:"reason"`
+}
+
+// An actor being simulated by the shard.
+type AdminActorState struct {
+	Id    rpg2d.ActorId `json:"id"`
+	Name  string        `json:"name"`
+	Cell  coord.Cell    `json:"cell"`
+	Hp    int           `json:"hp"`
+	HpMax int           `json:"hpMax"`
+
+	// Npcs aren't controlled by a connection
+	Npc         bool      `json:"npc"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+var errActorNotFound = errors.New("actor isn't in this world")
+
+var (
+	errCellOutsideWorld = errors.New("cell isn't in this world")
+	errCellBlocked      = errors.New("cell is blocked")
+)
+
+// Returns an estimate of the simulation's current time.
+func (w *shardWorld) now() stime.Time {
+	// TODO parametize server fps
+	return stime.Time(time.Since(w.beganAt) * 40 / time.Second)
+}
+
+// Must be called with the lock held.
+func (s *shard) actorNamed(name string) *actor {
+	for _, a := range s.world.zones.allActors() {
+		if a.hasLegend() && strings.EqualFold(a.name, name) {
+			return a
+		}
+	}
+	return nil
+}
+
+// The cell and health of each actor are read from a snapshot
+// of the world because they're written by its zone's update
+// phase. Actors that aren't in the snapshot are omitted.
+func (s *shard) actors() ([]AdminActorState, error) {
+	s.mu.RLock()
+	w := s.world
+	s.mu.RUnlock()
+
+	state, err := w.zones.snapshot(w.bounds, adminSnapshotTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := make(map[entity.Id]ActorEntityState)
+	for _, e := range state.Entities {
+		if e, ok := e.(ActorEntityState); ok {
+			entities[e.Id] = e
+		}
+	}
+
+	actors := w.zones.allActors()
+	states := make([]AdminActorState, 0, len(actors))
+	for _, a := range actors {
+		e, ok := entities[a.actorEntity.id]
+		if !ok {
+			continue
+		}
+
+		states = append(states, AdminActorState{
+			Id:          a.Id(),
+			Name:        a.name,
+			Cell:        e.Cell,
+			Hp:          e.Hp,
+			HpMax:       e.HpMax,
+			Npc:         !a.hasLegend(),
+			ConnectedAt: a.connectedAt,
+		})
+	}
+	return states, nil
+}
+
+// The actor is sent the reason it was kicked, removed
+// from the world and disconnected. It must reconnect
+// to rejoin the world.
+func (s *shard) kick(name, reason string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a := s.actorNamed(name)
+	if a == nil {
+		return errActorNotFound
+	}
+
+	now := s.world.now()
+	a.deliverMsg(KickedMsg{now, reason})
+	s.world.zones.RemoveActor(a)
+	a.closeConn()
+	a.logger.Info("actor kicked", "tick", now, "reason", reason)
+	return nil
+}
+
+// The actor can only be teleported onto a cell that it
+// could walk into and isn't blocked by another entity.
+func (s *shard) teleport(name string, cell coord.Cell) error {
+	s.mu.RLock()
+	w := s.world
+	a := s.actorNamed(name)
+	s.mu.RUnlock()
+
+	if !w.bounds.Contains(cell) {
+		return errCellOutsideWorld
+	}
+
+	if a == nil {
+		return errActorNotFound
+	}
+
+	state, err := w.zones.snapshot(coord.Bounds{cell, cell}, adminSnapshotTimeout)
+	if err != nil {
+		return err
+	}
+
+	if w.zones.isBlocked(state, a.actorEntity.id)(cell) {
+		return errCellBlocked
+	}
+
+	if !w.zones.teleport(a, cell) {
+		return errActorNotFound
+	}
+
+	return nil
+}
+
+// Sends the message to every actor controlled by a connection.
+func (s *shard) broadcast(msg string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	chatMsg := ChatMsg{
+		Time:    s.world.now(),
+		Channel: CR_ANNOUNCE,
+		From:    adminBroadcastFrom,
+		Msg:     msg,
+	}
+
+	for _, a := range s.world.zones.allActors() {
+		if a.hasLegend() {
+			a.deliverMsg(chatMsg)
+		}
+	}
+}
+
+func (s *shard) snapshot() (rpg2d.WorldState, error) {
+	s.mu.RLock()
+	w := s.world
+	s.mu.RUnlock()
+
+	return w.zones.snapshot(w.bounds, adminSnapshotTimeout)
+}
+
+// Serves the admin api. Every request must be authorized
+// with the admin token as a bearer token.
+//
+//	GET  /admin/actors
+//	POST /admin/actors/{name}/kick      reason=...
+//	POST /admin/actors/{name}/teleport  x=...&y=...
+//	POST /admin/broadcast               msg=...
+//	GET  /admin/state
+type adminHandler struct {
+	shard *shard
+	token string
+}
+
+func (h adminHandler) isAuthorized(r *http.Request) bool {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h adminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.token == "" || !h.isAuthorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "actors":
+		if allowMethod(w, r, http.MethodGet) {
+			h.serveActors(w)
+		}
+
+	case path == "state":
+		if allowMethod(w, r, http.MethodGet) {
+			h.serveState(w)
+		}
+
+	case path == "broadcast":
+		if allowMethod(w, r, http.MethodPost) {
+			h.serveBroadcast(w, r)
+		}
+
+	case len(parts) == 3 && parts[0] == "actors" && parts[2] == "kick":
+		if allowMethod(w, r, http.MethodPost) {
+			writeAdminResult(w, h.shard.kick(parts[1], r.FormValue("reason")))
+		}
+
+	case len(parts) == 3 && parts[0] == "actors" && parts[2] == "teleport":
+		if allowMethod(w, r, http.MethodPost) {
+			h.serveTeleport(w, r, parts[1])
+		}
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h adminHandler) serveActors(w http.ResponseWriter) {
+	actors, err := h.shard.actors()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	h.writeJSON(w, actors)
+}
+
+func (h adminHandler) serveState(w http.ResponseWriter) {
+	state, err := h.shard.snapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	h.writeJSON(w, state)
+}
+
+func (h adminHandler) serveBroadcast(w http.ResponseWriter, r *http.Request) {
+	msg := strings.TrimSpace(r.FormValue("msg"))
+	if msg == "" {
+		http.Error(w, "msg is required", http.StatusBadRequest)
+		return
+	}
+
+	h.shard.broadcast(msg)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h adminHandler) serveTeleport(w http.ResponseWriter, r *http.Request, name string) {
+	x, errX := strconv.Atoi(r.FormValue("x"))
+	y, errY := strconv.Atoi(r.FormValue("y"))
+	if errX != nil || errY != nil {
+		http.Error(w, "x and y must be numbers", http.StatusBadRequest)
+		return
+	}
+
+	err := h.shard.teleport(name, coord.Cell{x, y})
+	if err == errCellOutsideWorld || err == errCellBlocked {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeAdminResult(w, err)
+}
+
+// Returns false and responds with an error
+// if the request doesn't use the method.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeAdminResult(w http.ResponseWriter, err error) {
+	switch err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errActorNotFound:
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h adminHandler) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		h.shard.logger.Warn("error writing admin response", "err", err)
+	}
+}
@@ -0,0 +1,290 @@
+package game
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+const adminTestToken = "secret"
+
+func serveAdmin(h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// Simulates zones ticking by calling record with the time of
+// each tick until stop is closed. The first tick is recorded
+// before returning.
+func tickZones(stop <-chan struct{}, record func(now stime.Time)) {
+	record(1)
+
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for now := stime.Time(2); ; now++ {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			record(now)
+		}
+	}()
+}
+
+func DescribeAdminApi(c gospec.Context) {
+	west, _ := newTestZone(coord.Bounds{coord.Cell{1, -1}, coord.Cell{64, -64}})
+	east, _ := newTestZone(coord.Bounds{coord.Cell{65, -1}, coord.Cell{128, -64}})
+
+	r := newZoneRouterOf([]*localZone{west, east}, coord.Bounds{coord.Cell{1, -1}, coord.Cell{128, -64}}, nil, []coord.Cell{{70, -10}})
+	s := &shard{world: &shardWorld{
+		bounds:  coord.Bounds{coord.Cell{1, -1}, coord.Cell{128, -64}},
+		zones:   r,
+		beganAt: time.Now(),
+	}}
+
+	h := adminHandler{s, adminTestToken}
+
+	a := &actor{}
+	a.id = 1
+	a.actorEntity.actorId = 1
+	a.name = "Alice"
+	r.ConnectActor(a)
+
+	c.Specify("the admin api", func() {
+		c.Specify("rejects requests without the token", func() {
+			c.Expect(serveAdmin(h, "GET", "/admin/actors", "").Code, Equals, http.StatusUnauthorized)
+			c.Expect(serveAdmin(h, "GET", "/admin/actors", "wrong").Code, Equals, http.StatusUnauthorized)
+		})
+
+		c.Specify("rejects every request if there is no token", func() {
+			h := adminHandler{s, ""}
+			c.Expect(serveAdmin(h, "GET", "/admin/actors", "").Code, Equals, http.StatusUnauthorized)
+		})
+
+		c.Specify("lists the connected actors", func() {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			tickZones(stop, func(now stime.Time) {
+				east.recordSnapshot(a.Entity(), now)
+			})
+
+			w := serveAdmin(h, "GET", "/admin/actors", adminTestToken)
+			c.Assume(w.Code, Equals, http.StatusOK)
+
+			var actors []AdminActorState
+			c.Assume(json.NewDecoder(w.Body).Decode(&actors), IsNil)
+			c.Expect(len(actors), Equals, 1)
+			c.Expect(actors[0].Name, Equals, "Alice")
+			c.Expect(actors[0].Cell, Equals, coord.Cell{70, -10})
+		})
+
+		c.Specify("kicks an actor", func() {
+			w := serveAdmin(h, "POST", "/admin/actors/alice/kick?reason=spam", adminTestToken)
+			c.Expect(w.Code, Equals, http.StatusNoContent)
+			c.Expect(east.hasActor(a), IsFalse)
+
+			c.Specify("that must be connected", func() {
+				w := serveAdmin(h, "POST", "/admin/actors/alice/kick", adminTestToken)
+				c.Expect(w.Code, Equals, http.StatusNotFound)
+			})
+		})
+
+		c.Specify("disconnects a kicked actor", func() {
+			ds := datastore.NewMemDatastore()
+			_, err := ds.AddActor("Bob", "password")
+			c.Assume(err, IsNil)
+
+			type connection struct {
+				Conn
+				client net.Conn
+				actor  *actor
+				closed <-chan error
+			}
+
+			// Logs bob in over a pipe and writes the initial
+			// world state to the client.
+			connect := func() connection {
+				server, client := net.Pipe()
+				connected := make(chan *actor, 1)
+				closed := make(chan error, 1)
+
+				go func() {
+					conn := newShardConn(server, func() { server.Close() }, nil)
+					closed <- LoginAndConnectActor(NewPreLoginConn(conn, ds, nil),
+						func(dsactor datastore.Actor, stateWriter InitialStateWriter, _ *Logger) (InputReceiver, entity.State) {
+							b := NewActor(2, dsactor, stateWriter)
+							r.ConnectActor(b)
+							connected <- b
+							return inputReceiver{b, func() { r.RemoveActor(b) }}, b.Entity().ToState()
+						})
+				}()
+
+				conn := connection{Conn: NewGobConn(client), client: client, closed: closed}
+				c.Assume(conn.EncodeAndSend(ET_REQ_LOGIN, ReqLogin{"Bob", "password"}), IsNil)
+
+				t, err := conn.ReadNextType()
+				c.Assume(err, IsNil)
+				c.Assume(t, Equals, ET_RESP_LOGIN_SUCCESS)
+				c.Assume(conn.Decode(&RespLoginSuccess{}), IsNil)
+
+				c.Assume(conn.EncodeAndSend(ET_REQ_CONNECT, ReqConnect{"Bob"}), IsNil)
+
+				t, err = conn.ReadNextType()
+				c.Assume(err, IsNil)
+				c.Assume(t, Equals, ET_CONNECTED)
+				c.Assume(conn.Decode(&ActorEntityState{}), IsNil)
+
+				conn.actor = <-connected
+				conn.actor.actorConn.WriteState(rpg2d.WorldState{})
+
+				t, err = conn.ReadNextType()
+				c.Assume(err, IsNil)
+				c.Assume(t, Equals, ET_WORLD_STATE)
+				c.Assume(conn.Decode(&rpg2d.WorldState{}), IsNil)
+				return conn
+			}
+
+			conn := connect()
+
+			msgs := make(chan ActorMsgs)
+			go func() {
+				defer close(msgs)
+				for {
+					t, err := conn.ReadNextType()
+					if err != nil || t != ET_ACTOR_MSGS {
+						return
+					}
+
+					var m ActorMsgs
+					if conn.Decode(&m) != nil {
+						return
+					}
+					msgs <- m
+				}
+			}()
+
+			w := serveAdmin(h, "POST", "/admin/actors/bob/kick?reason=spam", adminTestToken)
+			c.Assume(w.Code, Equals, http.StatusNoContent)
+
+			m := <-msgs
+			c.Assume(len(m.Msgs) > 0, IsTrue)
+			c.Expect(m.Msgs[len(m.Msgs)-1].(KickedMsg).Reason, Equals, "spam")
+
+			_, open := <-msgs
+			c.Expect(open, IsFalse)
+			c.Expect(<-conn.closed, Not(IsNil))
+
+			c.Specify("so it can connect again", func() {
+				dsactor, _ := ds.ActorExists("Bob")
+				c.Expect(dsactor.CanBeConnected(), IsTrue)
+
+				conn := connect()
+				conn.client.Close()
+				c.Expect(<-conn.closed, Not(IsNil))
+			})
+		})
+
+		c.Specify("teleports an actor", func() {
+			stop := make(chan struct{})
+			defer close(stop)
+
+			tickZones(stop, func(now stime.Time) {
+				west.recordSnapshot(wallEntity{id: 20, cell: coord.Cell{20, -10}}, now)
+				east.recordSnapshot(wallEntity{id: 21, cell: coord.Cell{100, -10}}, now)
+			})
+
+			w := serveAdmin(h, "POST", "/admin/actors/alice/teleport?x=10&y=-10", adminTestToken)
+			c.Expect(w.Code, Equals, http.StatusNoContent)
+			c.Expect(a.Cell(), Equals, coord.Cell{10, -10})
+			c.Expect(west.hasActor(a), IsTrue)
+			c.Expect(east.hasActor(a), IsFalse)
+
+			c.Specify("only to a cell in the world", func() {
+				w := serveAdmin(h, "POST", "/admin/actors/alice/teleport?x=500&y=-10", adminTestToken)
+				c.Expect(w.Code, Equals, http.StatusBadRequest)
+				c.Expect(a.Cell(), Equals, coord.Cell{10, -10})
+			})
+
+			c.Specify("only to a cell that isn't blocked", func() {
+				w := serveAdmin(h, "POST", "/admin/actors/alice/teleport?x=20&y=-10", adminTestToken)
+				c.Expect(w.Code, Equals, http.StatusBadRequest)
+				c.Expect(w.Body.String(), Equals, errCellBlocked.Error()+"\n")
+				c.Expect(a.Cell(), Equals, coord.Cell{10, -10})
+			})
+		})
+
+		c.Specify("broadcasts a message to every actor", func() {
+			w := serveAdmin(h, "POST", "/admin/broadcast?msg=restarting+soon", adminTestToken)
+			c.Assume(w.Code, Equals, http.StatusNoContent)
+
+			msgs := a.deliveredMsgs()
+			c.Assume(len(msgs), Equals, 1)
+
+			msg := msgs[0].(ChatMsg)
+			c.Expect(msg.Channel, Equals, CR_ANNOUNCE)
+			c.Expect(msg.From, Equals, adminBroadcastFrom)
+			c.Expect(msg.Msg, Equals, "restarting soon")
+		})
+
+		c.Specify("requires the method of the request", func() {
+			w := serveAdmin(h, "GET", "/admin/broadcast?msg=hi", adminTestToken)
+			c.Expect(w.Code, Equals, http.StatusMethodNotAllowed)
+		})
+	})
+
+	c.Specify("a snapshot of the world", func() {
+		stop := make(chan struct{})
+		defer close(stop)
+
+		tick := func(zones ...*localZone) {
+			tickZones(stop, func(now stime.Time) {
+				for i, z := range zones {
+					z.recordSnapshot(actorEntity{id: entity.Id(10 + i), cell: z.bounds.TopL}, now)
+				}
+			})
+		}
+
+		c.Specify("contains the entities of every zone", func() {
+			tick(west, east)
+
+			state, err := r.snapshot(s.world.bounds, time.Second)
+			c.Assume(err, IsNil)
+			c.Expect(len(state.Entities), Equals, 2)
+			c.Expect(state.Bounds, Equals, s.world.bounds)
+		})
+
+		c.Specify("is empty for a zone that isn't ticking", func() {
+			tick(west)
+
+			state, err := r.snapshot(s.world.bounds, time.Second)
+			c.Assume(err, IsNil)
+			c.Expect(len(state.Entities), Equals, 1)
+			c.Expect(state.Entities[0].EntityId(), Equals, entity.Id(10))
+		})
+	})
+
+	if r.actors[a] != nil {
+		r.RemoveActor(a)
+	}
+}
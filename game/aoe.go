@@ -0,0 +1,271 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+type AoeShape int
+
+//go:generate stringer -type=AoeShape
+const (
+	AS_ERROR AoeShape = iota
+	AS_CONE
+	AS_LINE
+	AS_RADIUS
+)
+
+// The definition of a skill that damages an area
+// of cells instead of the single cell an assail hits.
+type aoeSkill struct {
+	shape AoeShape
+	// Length of a line, depth of a cone or
+	// manhattan distance of a radius in cells.
+	size int
+
+	// Damage applied to the cells nearest the
+	// caster. Every cell further away will
+	// receive falloff less damage.
+	damage, falloff int
+
+	// Whether the area damages actors that are
+	// friendly with the caster. The caster is
+	// never damaged by their own skill.
+	friendlyFire bool
+
+	// In frames
+	cooldown stime.Time
+
+	// Frames the skill must be cast for before
+	// it resolves. Zero for an instant skill.
+	castTime stime.Time
+}
+
+var aoeSkills = map[string]aoeSkill{
+	"cleave": {
+		shape:   AS_CONE,
+		size:    2,
+		damage:  30,
+		falloff: 10,
+
+		// 2s
+		cooldown: 40 * 2,
+	},
+
+	"lance": {
+		shape:   AS_LINE,
+		size:    4,
+		damage:  30,
+		falloff: 5,
+
+		// 3s
+		cooldown: 40 * 3,
+	},
+
+	"quake": {
+		shape:   AS_RADIUS,
+		size:    2,
+		damage:  40,
+		falloff: 15,
+
+		friendlyFire: true,
+
+		// 5s
+		cooldown: 40 * 5,
+		// 1s
+		castTime: 40,
+	},
+}
+
+// A cell covered by an area of effect and
+// the damage it will do to an actor in it.
+type AoeCell struct {
+	Cell   coord.Cell `json:"cell"`
+	Damage int        `json:"damage"`
+}
+
+type aoeEntity struct {
+	id entity.Id
+
+	skill string
+	shape AoeShape
+
+	spawnedBy      entity.Id
+	spawnedByActor rpg2d.ActorId
+	spawnedAt      stime.Time
+
+	cells  []AoeCell
+	bounds coord.Bounds
+	flags  entity.Flag
+
+	friendlyFire bool
+}
+
+type AoeEntityState struct {
+	Type string `json:"type"`
+
+	Id entity.Id `json:"id"`
+
+	Skill string   `json:"skill"`
+	Shape AoeShape `json:"shape"`
+
+	SpawnedBy entity.Id  `json:"spawnedBy"`
+	SpawnedAt stime.Time `json:"spawnedAt"`
+
+	Cells []AoeCell `json:"cells"`
+}
+
+// Returns the cells covered by a shape cast from the
+// origin towards the facing direction. The damage of
+// each cell is reduced by the falloff for every cell
+// of distance from the origin.
+func (s aoeSkill) cellsFrom(origin coord.Cell, facing coord.Direction) []AoeCell {
+	// Unit vector in the facing direction and
+	// the vector perpendicular to it.
+	ahead := origin.Neighbor(facing)
+	dx, dy := ahead.X-origin.X, ahead.Y-origin.Y
+	px, py := dy, -dx
+
+	damageAt := func(distance int) int {
+		damage := s.damage - s.falloff*distance
+		if damage < 0 {
+			return 0
+		}
+		return damage
+	}
+
+	var cells []AoeCell
+
+	switch s.shape {
+	case AS_LINE:
+		for i := 0; i < s.size; i++ {
+			cells = append(cells, AoeCell{
+				Cell:   origin.Add(dx*(i+1), dy*(i+1)),
+				Damage: damageAt(i),
+			})
+		}
+
+	case AS_CONE:
+		for i := 0; i < s.size; i++ {
+			row := origin.Add(dx*(i+1), dy*(i+1))
+			for j := -i; j <= i; j++ {
+				cells = append(cells, AoeCell{
+					Cell:   row.Add(px*j, py*j),
+					Damage: damageAt(i),
+				})
+			}
+		}
+
+	case AS_RADIUS:
+		for x := -s.size; x <= s.size; x++ {
+			for y := -s.size; y <= s.size; y++ {
+				distance := abs(x) + abs(y)
+				if distance == 0 || distance > s.size {
+					continue
+				}
+
+				cells = append(cells, AoeCell{
+					Cell:   origin.Add(x, y),
+					Damage: damageAt(distance - 1),
+				})
+			}
+		}
+	}
+
+	return cells
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// Returns the smallest bounds that contains all the cells.
+func boundsOfCells(cells []AoeCell) coord.Bounds {
+	if len(cells) == 0 {
+		return coord.Bounds{}
+	}
+
+	bounds := coord.Bounds{cells[0].Cell, cells[0].Cell}
+	for _, c := range cells[1:] {
+		if c.Cell.X < bounds.TopL.X {
+			bounds.TopL.X = c.Cell.X
+		}
+		if c.Cell.X > bounds.BotR.X {
+			bounds.BotR.X = c.Cell.X
+		}
+		if c.Cell.Y > bounds.TopL.Y {
+			bounds.TopL.Y = c.Cell.Y
+		}
+		if c.Cell.Y < bounds.BotR.Y {
+			bounds.BotR.Y = c.Cell.Y
+		}
+	}
+
+	return bounds
+}
+
+func newAoeEntity(id entity.Id, skill string, a *actor, now stime.Time) aoeEntity {
+	s := aoeSkills[skill]
+	cells := s.cellsFrom(a.Cell(), a.facing)
+
+	return aoeEntity{
+		id: id,
+
+		skill: skill,
+		shape: s.shape,
+
+		spawnedBy:      a.actorEntity.Id(),
+		spawnedByActor: a.Id(),
+		spawnedAt:      now,
+
+		cells:  cells,
+		bounds: boundsOfCells(cells),
+		flags:  entity.FlagNew,
+
+		friendlyFire: s.friendlyFire,
+	}
+}
+
+func (e aoeEntity) Id() entity.Id        { return e.id }
+func (e aoeEntity) Cell() coord.Cell     { return e.bounds.TopL }
+func (e aoeEntity) Bounds() coord.Bounds { return e.bounds }
+func (e aoeEntity) Flags() entity.Flag   { return e.flags }
+
+func (e aoeEntity) ToState() entity.State {
+	return AoeEntityState{
+		Type: "aoe",
+
+		Id: e.id,
+
+		Skill: e.skill,
+		Shape: e.shape,
+
+		SpawnedBy: e.spawnedBy,
+		SpawnedAt: e.spawnedAt,
+
+		Cells: e.cells,
+	}
+}
+
+// Returns the damage the area does to the cell.
+func (e aoeEntity) damageAt(c coord.Cell) (int, bool) {
+	for _, cell := range e.cells {
+		if cell.Cell == c {
+			return cell.Damage, true
+		}
+	}
+
+	return 0, false
+}
+
+func (e AoeEntityState) EntityId() entity.Id  { return e.Id }
+func (e AoeEntityState) Bounds() coord.Bounds { return boundsOfCells(e.Cells) }
+
+func (e AoeEntityState) IsDifferentFrom(entity.State) bool {
+	return true
+}
@@ -0,0 +1,107 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeAoeSkills(c gospec.Context) {
+	c.Specify("an area of effect", func() {
+		c.Specify("shaped as a line", func() {
+			s := aoeSkill{shape: AS_LINE, size: 3, damage: 30, falloff: 10}
+
+			c.Specify("covers the cells in front of the caster", func() {
+				c.Expect(s.cellsFrom(cell(0, 0), coord.North), ContainsExactly, []AoeCell{
+					{cell(0, 1), 30},
+					{cell(0, 2), 20},
+					{cell(0, 3), 10},
+				})
+
+				c.Expect(s.cellsFrom(cell(0, 0), coord.West), ContainsExactly, []AoeCell{
+					{cell(-1, 0), 30},
+					{cell(-2, 0), 20},
+					{cell(-3, 0), 10},
+				})
+			})
+		})
+
+		c.Specify("shaped as a cone", func() {
+			s := aoeSkill{shape: AS_CONE, size: 2, damage: 30, falloff: 10}
+
+			c.Specify("widens as it moves away from the caster", func() {
+				c.Expect(s.cellsFrom(cell(0, 0), coord.East), ContainsExactly, []AoeCell{
+					{cell(1, 0), 30},
+					{cell(2, 1), 20},
+					{cell(2, 0), 20},
+					{cell(2, -1), 20},
+				})
+			})
+		})
+
+		c.Specify("shaped as a radius", func() {
+			s := aoeSkill{shape: AS_RADIUS, size: 2, damage: 40, falloff: 15}
+			cells := s.cellsFrom(cell(0, 0), coord.South)
+
+			c.Specify("surrounds but doesn't include the caster", func() {
+				c.Expect(len(cells), Equals, 12)
+				c.Expect(boundsOfCells(cells), Equals, coord.Bounds{cell(-2, 2), cell(2, -2)})
+
+				for _, c2 := range cells {
+					c.Expect(c2.Cell, Not(Equals), cell(0, 0))
+				}
+			})
+
+			c.Specify("does less damage further from the caster", func() {
+				c.Expect(cells, Contains, AoeCell{cell(0, 1), 40})
+				c.Expect(cells, Contains, AoeCell{cell(1, 1), 25})
+				c.Expect(cells, Contains, AoeCell{cell(0, -2), 25})
+			})
+		})
+
+		c.Specify("never does negative damage", func() {
+			s := aoeSkill{shape: AS_LINE, size: 3, damage: 10, falloff: 10}
+			c.Expect(s.cellsFrom(cell(0, 0), coord.North)[2].Damage, Equals, 0)
+		})
+
+		c.Specify("that collides with a stationary actor", func() {
+			caster := &actor{
+				id: 0,
+				actorEntity: actorEntity{
+					id:      0,
+					actorId: 0,
+					cell:    cell(0, 0),
+					facing:  coord.North,
+				},
+			}
+
+			target := &actor{
+				id: 1,
+				actorEntity: actorEntity{
+					id:      1,
+					actorId: 1,
+					cell:    cell(0, 2),
+					hp:      100,
+					hpMax:   100,
+				},
+			}
+
+			phase := newNarrowPhase(ActorIndex{0: caster, 1: target})
+
+			c.Specify("damages the actor by the damage of its cell", func() {
+				aoe := newAoeEntity(entity.Id(2), "lance", caster, 0)
+				phase.solveActorAoe(target, aoe, 0)
+				c.Expect(target.hp, Equals, 75)
+			})
+
+			c.Specify("doesn't damage the caster", func() {
+				caster.hp = 100
+				aoe := newAoeEntity(entity.Id(2), "quake", caster, 0)
+				phase.solveActorAoe(caster, aoe, 0)
+				c.Expect(caster.hp, Equals, 100)
+			})
+		})
+	})
+}
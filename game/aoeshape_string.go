@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=AoeShape"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[AS_ERROR-0]
+	_ = x[AS_CONE-1]
+	_ = x[AS_LINE-2]
+	_ = x[AS_RADIUS-3]
+}
+
+const _AoeShape_name = "AS_ERRORAS_CONEAS_LINEAS_RADIUS"
+
+var _AoeShape_index = [...]uint8{0, 8, 15, 22, 31}
+
+func (i AoeShape) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_AoeShape_index)-1 {
+		return "AoeShape(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _AoeShape_name[_AoeShape_index[idx]:_AoeShape_index[idx+1]]
+}
@@ -1,6 +1,8 @@
 package game
 
 import (
+	"time"
+
 	"github.com/ghthor/filu/rpg2d/coord"
 	"github.com/ghthor/filu/rpg2d/entity"
 	"github.com/ghthor/filu/rpg2d/quad"
@@ -37,15 +39,28 @@ func (w WallEntityState) IsDifferentFrom(entity.State) bool {
 	return false
 }
 
-func addWalls(quad quad.Quad, nextId func() entity.Id) quad.Quad {
-	c := func(x, y int) coord.Cell { return coord.Cell{x, y} }
-	newWall := func(c coord.Cell) {
+// Inserts a wall into the quad tree at each cell.
+func addWalls(quad quad.Quad, cells []coord.Cell, nextId func() entity.Id) quad.Quad {
+	for _, c := range cells {
 		quad = quad.Insert(wallEntity{
 			id:   nextId(),
 			cell: c,
 		})
 	}
 
+	return quad
+}
+
+// Walls that enclose the arena.
+var ArenaWalls = arenaWalls()
+
+func arenaWalls() []coord.Cell {
+	var walls []coord.Cell
+	c := func(x, y int) coord.Cell { return coord.Cell{x, y} }
+	newWall := func(c coord.Cell) {
+		walls = append(walls, c)
+	}
+
 	// Outer Wall
 	// {c(30, -30), c(99, -30)},
 	for x := 30; x < 100; x++ {
@@ -88,5 +103,57 @@ func addWalls(quad quad.Quad, nextId func() entity.Id) quad.Quad {
 		newWall(c(45, y))
 	}
 
-	return quad
+	return walls
+}
+
+// Npcs that populate the walled arena.
+var ArenaNpcs = []NpcSpawn{{
+	Name:   "Gatekeeper",
+	Cell:   coord.Cell{28, -99},
+	Facing: coord.South,
+
+	Behavior:     NpcGuard{Radius: 4},
+	RespawnDelay: 30 * time.Second,
+}, {
+	Name:   "Brute",
+	Cell:   coord.Cell{38, -38},
+	Facing: coord.South,
+
+	Behavior:     NpcChase{Sight: 8},
+	RespawnDelay: 15 * time.Second,
+}, {
+	Name:   "Rabbit",
+	Cell:   coord.Cell{10, -10},
+	Facing: coord.South,
+
+	Behavior:     NpcFlee{Sight: 5},
+	RespawnDelay: 10 * time.Second,
+}, {
+	Name:   "Wanderer",
+	Cell:   coord.Cell{20, -20},
+	Facing: coord.South,
+
+	Behavior:     NpcWander{Radius: 6},
+	RespawnDelay: 10 * time.Second,
+}, {
+	Name:   "Dread Lord",
+	Cell:   coord.Cell{65, -65},
+	Facing: coord.South,
+
+	// Slaying the Dread Lord ends the world
+	// and a new one is generated in its place.
+	Behavior:     NpcGuard{Radius: 6},
+	RespawnDelay: -1,
+	EndsWorld:    true,
+}}
+
+// Resource nodes that can be gathered from inside the arena.
+var ArenaResourceNodes = []ResourceNodeSpawn{
+	{"tree", coord.Cell{60, -60}},
+	{"tree", coord.Cell{61, -60}},
+	{"tree", coord.Cell{60, -61}},
+
+	{"ore", coord.Cell{70, -70}},
+	{"ore", coord.Cell{71, -70}},
+	{"ore", coord.Cell{70, -71}},
 }
@@ -0,0 +1,145 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+)
+
+// Returns the direction that moves the furthest along
+// the axis with the largest distance between the cells.
+// Returns false if the cells are the same.
+func directionToward(from, to coord.Cell) (coord.Direction, bool) {
+	dx, dy := to.X-from.X, to.Y-from.Y
+
+	switch {
+	case dx == 0 && dy == 0:
+		return coord.North, false
+	case abs(dx) > abs(dy) && dx > 0:
+		return coord.East, true
+	case abs(dx) > abs(dy):
+		return coord.West, true
+	case dy > 0:
+		return coord.North, true
+	default:
+		return coord.South, true
+	}
+}
+
+func distance(a, b coord.Cell) int {
+	return abs(a.X-b.X) + abs(a.Y-b.Y)
+}
+
+// Returns the actor closest to the cell that is
+// within the range. Returns false if there isn't one.
+func nearestActor(actors []ActorEntityState, c coord.Cell, within int) (ActorEntityState, bool) {
+	var nearest ActorEntityState
+	found := false
+
+	for _, a := range actors {
+		d := distance(a.Cell, c)
+		if d > within {
+			continue
+		}
+
+		if !found || d < distance(nearest.Cell, c) {
+			nearest, found = a, true
+		}
+	}
+
+	return nearest, found
+}
+
+// Paths to the cell. Stands still once it has arrived.
+func moveTo(from, to coord.Cell) NpcDecision {
+	if from == to {
+		return NpcDecision{}
+	}
+	return NpcDecision{MoveTo: &to}
+}
+
+// Paths toward the target and assails it once
+// the target is in an adjacent cell.
+func attack(self, target coord.Cell) NpcDecision {
+	if distance(self, target) > 1 {
+		return moveTo(self, target)
+	}
+
+	d, _ := directionToward(self, target)
+	return NpcDecision{Move: &d, Use: "assail"}
+}
+
+// Wanders randomly around its spawn.
+type NpcWander struct {
+	// Max distance the npc will wander from its spawn
+	Radius int
+}
+
+func (b NpcWander) Decide(v NpcView) NpcDecision {
+	if distance(v.Self.Cell, v.Spawn) > b.Radius {
+		return moveTo(v.Self.Cell, v.Spawn)
+	}
+
+	// Keep doing what it was doing most of the time
+	if v.Rand.Intn(4) != 0 {
+		return NpcDecision{Move: v.Moving}
+	}
+
+	// Stand still as often as it moves in any direction
+	if v.Rand.Intn(2) == 0 {
+		return NpcDecision{}
+	}
+
+	d := coord.Direction(v.Rand.Intn(int(coord.West) + 1))
+	return NpcDecision{Move: &d}
+}
+
+// Attacks actors that come within a radius of its
+// spawn and returns to its spawn when there are none.
+type NpcGuard struct {
+	Radius int
+}
+
+func (b NpcGuard) Decide(v NpcView) NpcDecision {
+	target, found := nearestActor(v.Actors, v.Spawn, b.Radius)
+	if !found {
+		return moveTo(v.Self.Cell, v.Spawn)
+	}
+
+	return attack(v.Self.Cell, target.Cell)
+}
+
+// Attacks the nearest actor it can see.
+type NpcChase struct {
+	// Max distance the npc will notice an actor from
+	Sight int
+}
+
+func (b NpcChase) Decide(v NpcView) NpcDecision {
+	target, found := nearestActor(v.Actors, v.Self.Cell, b.Sight)
+	if !found {
+		return NpcDecision{}
+	}
+
+	return attack(v.Self.Cell, target.Cell)
+}
+
+// Runs away from the nearest actor it can see.
+type NpcFlee struct {
+	Sight int
+}
+
+func (b NpcFlee) Decide(v NpcView) NpcDecision {
+	threat, found := nearestActor(v.Actors, v.Self.Cell, b.Sight)
+	if !found {
+		return NpcDecision{}
+	}
+
+	// Move toward the cell opposite of the threat
+	away := v.Self.Cell.Add(v.Self.Cell.X-threat.Cell.X, v.Self.Cell.Y-threat.Cell.Y)
+	d, ok := directionToward(v.Self.Cell, away)
+	if !ok {
+		// Standing on the same cell, any direction is away
+		d = v.Self.Facing
+	}
+
+	return NpcDecision{Move: &d}
+}
@@ -0,0 +1,493 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Max distance from an actor to a cell it can build on.
+const buildRange = 1
+
+// What it costs to sculpt a cell into each type of terrain.
+// Terrain that isn't in the table can't be sculpted.
+var sculptCosts = map[rpg2d.TerrainType]Resources{
+	rpg2d.TT_GRASS: {"wood": 1},
+	rpg2d.TT_DIRT:  {"stone": 1},
+	rpg2d.TT_ROCK:  {"stone": 3},
+	TT_WATER:       {"wood": 2},
+}
+
+// What it costs to build a structure. The cost is
+// refunded when the structure is demolished.
+var structureCost = Resources{
+	"stone": 4,
+	"wood":  2,
+}
+
+type BuildRequestType int
+
+//go:generate stringer -type=BuildRequestType
+const (
+	BR_ERROR BuildRequestType = iota
+	BR_SCULPT
+	BR_BUILD
+	BR_DEMOLISH
+	BR_SIZE
+)
+
+type BuildRequest struct {
+	BuildRequestType
+	stime.Time
+	Cell coord.Cell
+
+	// Type of terrain a BR_SCULPT request
+	// will change the cell into
+	Terrain rpg2d.TerrainType
+}
+
+type buildCmd struct {
+	BuildRequestType
+	stime.Time
+	cell    coord.Cell
+	terrain rpg2d.TerrainType
+}
+
+func newBuildRequest(t BuildRequestType, timeIssued stime.Time, params string) (BuildRequest, error) {
+	r := BuildRequest{
+		BuildRequestType: t,
+		Time:             timeIssued,
+	}
+
+	var err error
+	switch t {
+	case BR_SCULPT:
+		var tt string
+		_, err = fmt.Sscanf(params, "%d,%d,%s", &r.Cell.X, &r.Cell.Y, &tt)
+		if err == nil && len(tt) != 1 {
+			err = fmt.Errorf("invalid terrain type: %s", tt)
+		}
+
+		if err == nil {
+			r.Terrain = rpg2d.TerrainType(tt[0])
+		}
+
+	default:
+		_, err = fmt.Sscanf(params, "%d,%d", &r.Cell.X, &r.Cell.Y)
+	}
+
+	if err != nil {
+		return BuildRequest{}, err
+	}
+
+	return r, nil
+}
+
+type BuildRejectedReason int
+
+//go:generate stringer -type=BuildRejectedReason
+const (
+	BRR_ERROR BuildRejectedReason = iota
+	BRR_OUT_OF_RANGE
+	BRR_UNKNOWN_TERRAIN
+	BRR_RESOURCES
+	BRR_OCCUPIED
+	BRR_NO_STRUCTURE
+	BRR_NOT_BUILDER
+)
+
+// Sent to an actor's connection when a build
+// request could not be performed.
+type BuildRejectedMsg struct {
+	Time    stime.Time          `json:"time"`
+	Request BuildRequestType    `json:"request"`
+	Cell    coord.Cell          `json:"cell"`
+	Reason  BuildRejectedReason `json:"reason"`
+}
+
+type structureEntity struct {
+	id    entity.Id
+	cell  coord.Cell
+	flags entity.Flag
+
+	// Name of the actor that built the structure
+	builtBy string
+}
+
+type StructureEntityState struct {
+	Type    string     `json:"type"`
+	Id      entity.Id  `json:"id"`
+	Cell    coord.Cell `json:"cell"`
+	BuiltBy string     `json:"builtBy"`
+}
+
+func (e structureEntity) Id() entity.Id        { return e.id }
+func (e structureEntity) Cell() coord.Cell     { return e.cell }
+func (e structureEntity) Bounds() coord.Bounds { return coord.Bounds{e.cell, e.cell} }
+func (e structureEntity) Flags() entity.Flag   { return e.flags }
+
+func (e structureEntity) ToState() entity.State {
+	return StructureEntityState{
+		Type:    "structure",
+		Id:      e.id,
+		Cell:    e.cell,
+		BuiltBy: e.builtBy,
+	}
+}
+
+func (e StructureEntityState) EntityId() entity.Id  { return e.Id }
+func (e StructureEntityState) Bounds() coord.Bounds { return coord.Bounds{e.Cell, e.Cell} }
+func (e StructureEntityState) IsDifferentFrom(entity.State) bool {
+	return false
+}
+
+// A change to the terrain that will be sent
+// to the actors that can see the cell.
+type terrainEdit struct {
+	at    stime.Time
+	slice rpg2d.TerrainMapStateSlice
+}
+
+// Edits are kept long enough for every actor
+// to have written the state they were made during.
+const terrainEditLifetime = 40
+
+// The changes players have made to the world. Shared by
+// the input phase, which makes the changes, and the actors,
+// which send the changes to the terrain to their connection.
+type worldEdits struct {
+	mu sync.Mutex
+
+	// The generation of the world being edited
+	epoch int
+
+	logger *Logger
+
+	terrain *terrainIndex
+
+	// The terrain that has been sculpted
+	sculpted map[coord.Cell]rpg2d.TerrainType
+
+	// Structures indexed by the cell they were built on
+	structures map[coord.Cell]structureEntity
+
+	// Structures that will be removed from the
+	// world during the next input phase
+	demolished map[entity.Id]bool
+
+	// Changes to the terrain made during recent ticks
+	recent []terrainEdit
+
+	// Signals the saver that the edits have changed
+	changed chan struct{}
+}
+
+func newWorldEdits(terrain *terrainIndex) *worldEdits {
+	return &worldEdits{
+		terrain:    terrain,
+		sculpted:   make(map[coord.Cell]rpg2d.TerrainType),
+		structures: make(map[coord.Cell]structureEntity),
+		demolished: make(map[entity.Id]bool),
+		changed:    make(chan struct{}, 1),
+	}
+}
+
+// Applies edits loaded from a datastore. Structures are
+// inserted into the quad tree like the walls of the arena.
+func (w *worldEdits) load(edits datastore.WorldEdits, quad quad.Quad, nextId func() entity.Id) quad.Quad {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.epoch = edits.Epoch
+
+	for _, e := range edits.Terrain {
+		if _, exists := w.terrain.typeAt(e.Cell); !exists {
+			continue
+		}
+
+		w.terrain.setType(e.Type, e.Cell)
+		w.sculpted[e.Cell] = e.Type
+	}
+
+	for _, s := range edits.Structures {
+		e := structureEntity{
+			id:      nextId(),
+			cell:    s.Cell,
+			builtBy: s.BuiltBy,
+		}
+
+		quad = quad.Insert(e)
+		w.structures[e.cell] = e
+	}
+
+	return quad
+}
+
+// Returns a copy of the edits that can be stored.
+func (w *worldEdits) snapshot() datastore.WorldEdits {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	edits := datastore.WorldEdits{Epoch: w.epoch}
+
+	for c, tt := range w.sculpted {
+		edits.Terrain = append(edits.Terrain, datastore.TerrainEdit{c, tt})
+	}
+
+	for c, s := range w.structures {
+		edits.Structures = append(edits.Structures, datastore.Structure{c, s.builtBy})
+	}
+
+	less := func(a, b coord.Cell) bool {
+		if a.Y != b.Y {
+			return a.Y > b.Y
+		}
+		return a.X < b.X
+	}
+
+	sort.Slice(edits.Terrain, func(i, j int) bool {
+		return less(edits.Terrain[i].Cell, edits.Terrain[j].Cell)
+	})
+
+	sort.Slice(edits.Structures, func(i, j int) bool {
+		return less(edits.Structures[i].Cell, edits.Structures[j].Cell)
+	})
+
+	return edits
+}
+
+// Must be called while holding the lock.
+func (w *worldEdits) hasChanged() {
+	select {
+	case w.changed <- struct{}{}:
+	default:
+	}
+}
+
+// Saves the edits every time they have changed. Should be
+// run on its own go routine so the simulation never waits
+// for the store to finish saving.
+func (w *worldEdits) saveTo(store datastore.WorldStore) {
+	for range w.changed {
+		err := store.SaveWorldEdits(w.snapshot())
+		if err != nil {
+			w.logger.Error("error saving world edits", "err", err)
+		}
+	}
+}
+
+func (w *worldEdits) sculpt(tt rpg2d.TerrainType, c coord.Cell, now stime.Time) error {
+	slice, err := rpg2d.NewTerrainMap(coord.Bounds{c, c}, string(tt))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.terrain.setType(tt, c)
+	w.sculpted[c] = tt
+
+	// Remove edits every actor has already sent
+	recent := w.recent[:0]
+	for _, e := range w.recent {
+		if e.at+terrainEditLifetime > now {
+			recent = append(recent, e)
+		}
+	}
+
+	w.recent = append(recent, terrainEdit{now, rpg2d.TerrainMapStateSlice{
+		Bounds:  coord.Bounds{c, c},
+		Terrain: slice.String(),
+	}})
+
+	w.hasChanged()
+	return nil
+}
+
+// Returns the changes to the terrain inside the bounds
+// that were made after one time up until another.
+// A nil set of edits has never been changed.
+func (w *worldEdits) terrainBetween(after, upTo stime.Time, bounds coord.Bounds) []rpg2d.TerrainMapStateSlice {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var slices []rpg2d.TerrainMapStateSlice
+	for _, e := range w.recent {
+		if e.at > after && e.at <= upTo && bounds.Contains(e.slice.Bounds.TopL) {
+			slices = append(slices, e.slice)
+		}
+	}
+
+	return slices
+}
+
+// Returns false if a structure has already been built on the cell.
+func (w *worldEdits) build(e structureEntity) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.structures[e.cell]; exists {
+		return false
+	}
+
+	w.structures[e.cell] = e
+	w.hasChanged()
+	return true
+}
+
+func (w *worldEdits) structureAt(c coord.Cell) (structureEntity, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e, exists := w.structures[c]
+	return e, exists
+}
+
+func (w *worldEdits) demolish(e structureEntity) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.structures, e.cell)
+	w.demolished[e.id] = true
+	w.hasChanged()
+}
+
+// Returns true once for every structure that has been
+// demolished and must be removed from the world.
+func (w *worldEdits) takeDemolished(id entity.Id) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.demolished[id] {
+		return false
+	}
+
+	delete(w.demolished, id)
+	return true
+}
+
+func (a *actor) ReadBuildCmd() *buildCmd {
+	return <-a.readBuildCmd
+}
+
+// Sends the reason a build command was rejected to
+// the actor's connection.
+func (a *actor) rejectBuildCmd(cmd *buildCmd, reason BuildRejectedReason, now stime.Time) {
+	a.queueMsg(BuildRejectedMsg{now, cmd.BuildRequestType, cmd.cell, reason})
+}
+
+// Returns true if the cell is occupied by the actor,
+// other actors or something they can't move through.
+func (a *actor) isOccupied(c coord.Cell) bool {
+	if a.Cell() == c || (a.pathAction != nil && a.pathAction.Dest == c) {
+		return true
+	}
+
+	return blockedCells(a.lastWorldState(), a.actorEntity.id)[c]
+}
+
+func (phase inputPhase) processBuildCmd(a *actor, now stime.Time) []entity.Entity {
+	cmd := a.ReadBuildCmd()
+	if cmd == nil || phase.edits == nil {
+		return nil
+	}
+
+	current, exists := phase.edits.terrain.typeAt(cmd.cell)
+	if !exists || distance(a.Cell(), cmd.cell) > buildRange {
+		a.rejectBuildCmd(cmd, BRR_OUT_OF_RANGE, now)
+		return nil
+	}
+
+	switch cmd.BuildRequestType {
+	case BR_SCULPT:
+		cost, exists := sculptCosts[cmd.terrain]
+		if !exists {
+			a.rejectBuildCmd(cmd, BRR_UNKNOWN_TERRAIN, now)
+			return nil
+		}
+
+		if current == cmd.terrain {
+			return nil
+		}
+
+		// Actors can't be left standing on terrain they can't walk on
+		if !terrainTable[cmd.terrain].walkable && a.isOccupied(cmd.cell) {
+			a.rejectBuildCmd(cmd, BRR_OCCUPIED, now)
+			return nil
+		}
+
+		if !a.resources.has(cost) {
+			a.rejectBuildCmd(cmd, BRR_RESOURCES, now)
+			return nil
+		}
+
+		err := phase.edits.sculpt(cmd.terrain, cmd.cell, now)
+		if err != nil {
+			a.logger.Error("error sculpting terrain", "tick", now, "cell", cmd.cell, "err", err)
+			return nil
+		}
+
+		a.resources.spend(cost)
+		a.sendResources(now)
+
+	case BR_BUILD:
+		if !a.resources.has(structureCost) {
+			a.rejectBuildCmd(cmd, BRR_RESOURCES, now)
+			return nil
+		}
+
+		if a.isOccupied(cmd.cell) {
+			a.rejectBuildCmd(cmd, BRR_OCCUPIED, now)
+			return nil
+		}
+
+		e := structureEntity{
+			id:      phase.nextId(),
+			cell:    cmd.cell,
+			flags:   entity.FlagNew,
+			builtBy: a.name,
+		}
+
+		if !phase.edits.build(e) {
+			a.rejectBuildCmd(cmd, BRR_OCCUPIED, now)
+			return nil
+		}
+
+		a.resources.spend(structureCost)
+		a.sendResources(now)
+		a.recordLegend(datastore.LE_STRUCTURE_BUILT, "")
+
+		return []entity.Entity{e}
+
+	case BR_DEMOLISH:
+		e, exists := phase.edits.structureAt(cmd.cell)
+		if !exists {
+			a.rejectBuildCmd(cmd, BRR_NO_STRUCTURE, now)
+			return nil
+		}
+
+		if e.builtBy != a.name {
+			a.rejectBuildCmd(cmd, BRR_NOT_BUILDER, now)
+			return nil
+		}
+
+		phase.edits.demolish(e)
+
+		a.resources.add(structureCost)
+		a.sendResources(now)
+	}
+
+	return nil
+}
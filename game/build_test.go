@@ -0,0 +1,222 @@
+package game
+
+import (
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeBuilding(c gospec.Context) {
+	c.Specify("a build request", func() {
+		c.Specify("to sculpt includes the terrain type", func() {
+			r, err := newBuildRequest(BR_SCULPT, 1, "2,-3,R")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, BuildRequest{BR_SCULPT, 1, cell(2, -3), rpg2d.TT_ROCK})
+		})
+
+		c.Specify("to build only includes the cell", func() {
+			r, err := newBuildRequest(BR_BUILD, 1, "2,-3")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, BuildRequest{BR_BUILD, 1, cell(2, -3), 0})
+		})
+
+		c.Specify("is invalid without a cell", func() {
+			_, err := newBuildRequest(BR_DEMOLISH, 1, "R")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("an actor's resources", func() {
+		r := Resources{"stone": 3, "wood": 1}
+
+		c.Specify("can pay a cost they have", func() {
+			c.Expect(r.has(Resources{"stone": 3}), IsTrue)
+		})
+
+		c.Specify("can't pay a cost they don't have", func() {
+			c.Expect(r.has(Resources{"stone": 1, "wood": 2}), Not(IsTrue))
+			c.Expect(r.has(Resources{"gold": 1}), Not(IsTrue))
+		})
+
+		c.Specify("are reduced by spending", func() {
+			r.spend(Resources{"stone": 2})
+			c.Expect(r["stone"], Equals, 1)
+			c.Expect(r["wood"], Equals, 1)
+		})
+	})
+
+	c.Specify("an actor building", func() {
+		// G G G
+		// G G G
+		// G G G
+		terrain := terrainIndexOf(rpg2d.TerrainMap{
+			Bounds: coord.Bounds{cell(-1, 1), cell(1, -1)},
+			TerrainTypes: [][]rpg2d.TerrainType{
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+			},
+		})
+		edits := newWorldEdits(terrain)
+
+		cmds := make(chan *buildCmd, 1)
+		a := &actor{
+			actorEntity: actorEntity{
+				name:   "builder",
+				cell:   cell(0, 0),
+				facing: coord.North,
+			},
+			resources: Resources{"stone": 5, "wood": 5},
+		}
+		a.readBuildCmd = cmds
+
+		phase := inputPhase{ActorIndex{0: a}, entity.NewIdGenerator(), terrain, edits, nil}
+
+		process := func(cmd buildCmd, now stime.Time) []entity.Entity {
+			a.msgs = nil
+			cmds <- &cmd
+			return phase.processBuildCmd(a, now)
+		}
+
+		rejectedWith := func() BuildRejectedReason {
+			for _, msg := range a.msgs {
+				if msg, isRejected := msg.(BuildRejectedMsg); isRejected {
+					return msg.Reason
+				}
+			}
+			return BRR_ERROR
+		}
+
+		c.Specify("can sculpt an adjacent cell", func() {
+			process(buildCmd{BR_SCULPT, 1, cell(0, 1), rpg2d.TT_DIRT}, 1)
+
+			tt, _ := terrain.typeAt(cell(0, 1))
+			c.Expect(tt, Equals, rpg2d.TT_DIRT)
+			c.Expect(a.resources["stone"], Equals, 4)
+
+			c.Specify("and is sent its resources", func() {
+				c.Assume(len(a.msgs), Equals, 1)
+				c.Expect(a.msgs[0].(ResourcesMsg).Resources["stone"], Equals, 4)
+			})
+
+			c.Specify("and the change is sent to actors that can see it", func() {
+				slices := edits.terrainBetween(0, 1, coord.Bounds{cell(-1, 1), cell(1, -1)})
+				c.Assume(len(slices), Equals, 1)
+				c.Expect(slices[0].Bounds, Equals, coord.Bounds{cell(0, 1), cell(0, 1)})
+
+				c.Specify("only once", func() {
+					c.Expect(len(edits.terrainBetween(1, 2, terrain.bounds)), Equals, 0)
+				})
+
+				c.Specify("but not to actors that can't", func() {
+					c.Expect(len(edits.terrainBetween(0, 1, coord.Bounds{cell(5, 5), cell(6, 4)})), Equals, 0)
+				})
+			})
+
+			c.Specify("and the change will be saved", func() {
+				store := datastore.NewMemWorldStore()
+				close(edits.changed)
+				edits.saveTo(store)
+
+				saved, err := store.LoadWorldEdits()
+				c.Assume(err, IsNil)
+				c.Expect(saved.Terrain, ContainsExactly, []datastore.TerrainEdit{{cell(0, 1), rpg2d.TT_DIRT}})
+			})
+		})
+
+		c.Specify("can't sculpt", func() {
+			c.Specify("a cell out of range", func() {
+				process(buildCmd{BR_SCULPT, 1, cell(1, 1), rpg2d.TT_DIRT}, 1)
+				c.Expect(rejectedWith(), Equals, BRR_OUT_OF_RANGE)
+			})
+
+			c.Specify("terrain that doesn't exist", func() {
+				process(buildCmd{BR_SCULPT, 1, cell(0, 1), 'X'}, 1)
+				c.Expect(rejectedWith(), Equals, BRR_UNKNOWN_TERRAIN)
+			})
+
+			c.Specify("without enough resources", func() {
+				a.resources["stone"] = 2
+				process(buildCmd{BR_SCULPT, 1, cell(0, 1), rpg2d.TT_ROCK}, 1)
+				c.Expect(rejectedWith(), Equals, BRR_RESOURCES)
+
+				tt, _ := terrain.typeAt(cell(0, 1))
+				c.Expect(tt, Equals, rpg2d.TT_GRASS)
+			})
+
+			c.Specify("water under itself", func() {
+				process(buildCmd{BR_SCULPT, 1, cell(0, 0), TT_WATER}, 1)
+				c.Expect(rejectedWith(), Equals, BRR_OCCUPIED)
+			})
+		})
+
+		c.Specify("can build a structure", func() {
+			entities := process(buildCmd{BR_BUILD, 1, cell(1, 0), 0}, 1)
+			c.Assume(len(entities), Equals, 1)
+
+			e := entities[0].(structureEntity)
+			c.Expect(e.Cell(), Equals, cell(1, 0))
+			c.Expect(e.builtBy, Equals, "builder")
+			c.Expect(a.resources["stone"], Equals, 1)
+			c.Expect(a.resources["wood"], Equals, 3)
+
+			c.Specify("that blocks other structures", func() {
+				a.resources = Resources{"stone": 5, "wood": 5}
+				c.Expect(len(process(buildCmd{BR_BUILD, 2, cell(1, 0), 0}, 2)), Equals, 0)
+				c.Expect(rejectedWith(), Equals, BRR_OCCUPIED)
+			})
+
+			c.Specify("and demolish it", func() {
+				process(buildCmd{BR_DEMOLISH, 2, cell(1, 0), 0}, 2)
+				c.Expect(a.resources["stone"], Equals, 5)
+
+				removed := phase.ApplyInputsTo(e, 3)
+				c.Assume(len(removed), Equals, 1)
+				_, isRemoved := removed[0].(entity.Removed)
+				c.Expect(isRemoved, IsTrue)
+			})
+
+			c.Specify("that can't be demolished by another actor", func() {
+				a.name = "other"
+				process(buildCmd{BR_DEMOLISH, 2, cell(1, 0), 0}, 2)
+				c.Expect(rejectedWith(), Equals, BRR_NOT_BUILDER)
+			})
+		})
+
+		c.Specify("can't demolish where there isn't a structure", func() {
+			process(buildCmd{BR_DEMOLISH, 1, cell(1, 0), 0}, 1)
+			c.Expect(rejectedWith(), Equals, BRR_NO_STRUCTURE)
+		})
+	})
+
+	c.Specify("saved world edits", func() {
+		terrain := terrainIndexOf(rpg2d.TerrainMap{
+			Bounds:       coord.Bounds{cell(0, 0), cell(1, 0)},
+			TerrainTypes: [][]rpg2d.TerrainType{{rpg2d.TT_GRASS, rpg2d.TT_GRASS}},
+		})
+		edits := newWorldEdits(terrain)
+
+		quadTree, err := quad.New(terrain.bounds, quadMaxSize, nil)
+		c.Assume(err, IsNil)
+
+		c.Specify("are loaded into the terrain and structures", func() {
+			edits.load(datastore.WorldEdits{
+				Terrain:    []datastore.TerrainEdit{{cell(1, 0), rpg2d.TT_ROCK}},
+				Structures: []datastore.Structure{{cell(0, 0), "builder"}},
+			}, quadTree, entity.NewIdGenerator())
+
+			tt, _ := terrain.typeAt(cell(1, 0))
+			c.Expect(tt, Equals, rpg2d.TT_ROCK)
+
+			s, exists := edits.structureAt(cell(0, 0))
+			c.Assume(exists, IsTrue)
+			c.Expect(s.builtBy, Equals, "builder")
+		})
+	})
+}
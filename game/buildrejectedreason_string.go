@@ -0,0 +1,30 @@
+// Code generated by "stringer -type=BuildRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[BRR_ERROR-0]
+	_ = x[BRR_OUT_OF_RANGE-1]
+	_ = x[BRR_UNKNOWN_TERRAIN-2]
+	_ = x[BRR_RESOURCES-3]
+	_ = x[BRR_OCCUPIED-4]
+	_ = x[BRR_NO_STRUCTURE-5]
+	_ = x[BRR_NOT_BUILDER-6]
+}
+
+const _BuildRejectedReason_name = "BRR_ERRORBRR_OUT_OF_RANGEBRR_UNKNOWN_TERRAINBRR_RESOURCESBRR_OCCUPIEDBRR_NO_STRUCTUREBRR_NOT_BUILDER"
+
+var _BuildRejectedReason_index = [...]uint8{0, 9, 25, 44, 57, 69, 85, 100}
+
+func (i BuildRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_BuildRejectedReason_index)-1 {
+		return "BuildRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _BuildRejectedReason_name[_BuildRejectedReason_index[idx]:_BuildRejectedReason_index[idx+1]]
+}
@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=BuildRequestType"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[BR_ERROR-0]
+	_ = x[BR_SCULPT-1]
+	_ = x[BR_BUILD-2]
+	_ = x[BR_DEMOLISH-3]
+	_ = x[BR_SIZE-4]
+}
+
+const _BuildRequestType_name = "BR_ERRORBR_SCULPTBR_BUILDBR_DEMOLISHBR_SIZE"
+
+var _BuildRequestType_index = [...]uint8{0, 8, 17, 25, 36, 43}
+
+func (i BuildRequestType) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_BuildRequestType_index)-1 {
+		return "BuildRequestType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _BuildRequestType_name[_BuildRequestType_index[idx]:_BuildRequestType_index[idx+1]]
+}
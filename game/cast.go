@@ -0,0 +1,121 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// A skill that is being cast or channeled by an actor.
+// A cast resolves once when it has completed. A channel
+// applies its effect repeatedly until it has completed.
+// Both are interrupted if the actor moves, takes damage
+// or releases the skill with a UR_USE_CANCEL request.
+type castAction struct {
+	skill      string
+	start, end stime.Time
+
+	channeled bool
+}
+
+type CastState struct {
+	Skill string `json:"skill"`
+
+	Start stime.Time `json:"start"`
+	End   stime.Time `json:"end"`
+
+	Channeled bool `json:"channeled"`
+}
+
+func (c castAction) ToState() CastState {
+	return CastState{
+		Skill: c.skill,
+
+		Start: c.start,
+		End:   c.end,
+
+		Channeled: c.channeled,
+	}
+}
+
+// A skill that applies a periodic effect
+// to the actor channeling it.
+type channelSkill struct {
+	// In frames
+	duration, period, cooldown stime.Time
+
+	// Applied to the actor every period
+	heal int
+}
+
+var channelSkills = map[string]channelSkill{
+	"ioc": {
+		// 3s
+		duration: 40 * 3,
+		// 0.25s
+		period: 10,
+		// 10s
+		cooldown: 40 * 10,
+
+		heal: 3,
+	},
+}
+
+func (a *actor) startCast(skill string, castTime stime.Time, channeled bool, now stime.Time) {
+	a.cast = &castAction{
+		skill:     skill,
+		start:     now,
+		end:       now + castTime,
+		channeled: channeled,
+	}
+}
+
+// Interrupts the skill the actor is casting or channeling.
+func (a *actor) interruptCast() {
+	a.cast = nil
+}
+
+// Called every input phase while an actor is casting. The use command
+// is the actor's current use command. If it has been canceled, or
+// replaced by another skill, the cast is interrupted.
+func (phase inputPhase) processCast(a *actor, cmd *useCmd, now stime.Time) []entity.Entity {
+	cast := *a.cast
+
+	if cmd == nil || cmd.skill != cast.skill {
+		a.interruptCast()
+		return nil
+	}
+
+	if cast.channeled {
+		skill := channelSkills[cast.skill]
+
+		elapsed := now - cast.start
+		if elapsed > 0 && elapsed%skill.period == 0 {
+			a.hp += skill.heal
+			if a.hp > a.hpMax {
+				a.hp = a.hpMax
+			}
+		}
+
+		if cast.end <= now {
+			a.interruptCast()
+		}
+
+		return nil
+	}
+
+	if cast.end > now {
+		return nil
+	}
+
+	// The cast has completed
+	a.cast = nil
+
+	if cast.skill == "gather" {
+		phase.finishGather(a, cmd, now)
+		return nil
+	}
+
+	a.startCooldown(cast.skill, now)
+
+	return []entity.Entity{newAoeEntity(phase.nextId(), cast.skill, a, now)}
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeCasting(c gospec.Context) {
+	c.Specify("an actor that is casting", func() {
+		a := &actor{
+			id: 0,
+			actorEntity: actorEntity{
+				id:      0,
+				actorId: 0,
+				cell:    cell(0, 0),
+				facing:  coord.North,
+
+				hp:    50,
+				hpMax: 100,
+			},
+			cooldowns: make(cooldownTable),
+		}
+
+		phase := inputPhase{ActorIndex{0: a}, entity.NewIdGenerator(), nil, nil, nil}
+
+		c.Specify("a skill with a cast time", func() {
+			cmd := &useCmd{skill: "quake"}
+			a.startCast("quake", aoeSkills["quake"].castTime, false, 0)
+
+			c.Specify("will not resolve before the cast time", func() {
+				c.Expect(len(phase.processCast(a, cmd, 39)), Equals, 0)
+				c.Expect(a.cast, Not(IsNil))
+			})
+
+			c.Specify("will resolve after the cast time", func() {
+				entities := phase.processCast(a, cmd, 40)
+				c.Assume(len(entities), Equals, 1)
+
+				_, isAoe := entities[0].(aoeEntity)
+				c.Expect(isAoe, IsTrue)
+				c.Expect(a.cast, IsNil)
+				c.Expect(a.cooldowns["quake"], Equals, 40+cooldownOf("quake"))
+			})
+
+			c.Specify("will be interrupted", func() {
+				c.Specify("if the skill is canceled", func() {
+					c.Expect(len(phase.processCast(a, nil, 10)), Equals, 0)
+					c.Expect(a.cast, IsNil)
+				})
+
+				c.Specify("if another skill is used", func() {
+					c.Expect(len(phase.processCast(a, &useCmd{skill: "assail"}, 10)), Equals, 0)
+					c.Expect(a.cast, IsNil)
+				})
+
+				c.Specify("if the actor takes damage", func() {
+					a.takeDamage(10)
+					c.Expect(a.cast, IsNil)
+				})
+
+				c.Specify("if the actor moves", func() {
+					path := pa(10, 15, cell(0, 0), cell(0, 1))
+					a.applyPathAction(&path)
+					c.Expect(a.cast, IsNil)
+
+					c.Specify("unless the movement is reverted", func() {
+						a.revertMoveAction()
+						c.Expect(a.cast, Not(IsNil))
+					})
+				})
+			})
+
+			c.Specify("is visible in the actor's state", func() {
+				state := a.ToState().(ActorEntityState)
+				c.Assume(state.Cast, Not(IsNil))
+				c.Expect(*state.Cast, Equals, CastState{
+					Skill: "quake",
+					Start: 0,
+					End:   40,
+				})
+
+				a.interruptCast()
+				c.Expect(state.IsDifferentFrom(a.ToState()), IsTrue)
+			})
+		})
+
+		c.Specify("a channeled skill", func() {
+			cmd := &useCmd{skill: "ioc"}
+			skill := channelSkills["ioc"]
+			a.startCast("ioc", skill.duration, true, 0)
+
+			c.Specify("will apply its effect every period", func() {
+				for now := stime.Time(1); now <= skill.period*2; now++ {
+					phase.processCast(a, cmd, now)
+				}
+
+				c.Expect(a.hp, Equals, 50+skill.heal*2)
+				c.Expect(a.cast, Not(IsNil))
+			})
+
+			c.Specify("will end after its duration", func() {
+				phase.processCast(a, cmd, skill.duration)
+				c.Expect(a.cast, IsNil)
+			})
+		})
+	})
+}
@@ -0,0 +1,466 @@
+package game
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Distance in cells a shout can be heard from. Further
+// than an actor can see so shouts are sent as messages
+// instead of entities like the things actors say.
+const shoutRadius = 60
+
+// An actor can send this many chat messages during the
+// rate period before its messages are rejected.
+const (
+	chatRateLimit  = 5
+	chatRatePeriod = 10 * 40 // 10 seconds in frames
+)
+
+// Inspects every chat message before it's sent. Can be
+// used to censor messages or reject them entirely.
+type ChatFilter interface {
+	// Returns the message that will be sent in place of
+	// the message or false if it must not be sent.
+	FilterChat(channel ChatRequestType, from, msg string) (string, bool)
+}
+
+// Adapts a func to the ChatFilter interface.
+type ChatFilterFunc func(channel ChatRequestType, from, msg string) (string, bool)
+
+func (f ChatFilterFunc) FilterChat(channel ChatRequestType, from, msg string) (string, bool) {
+	return f(channel, from, msg)
+}
+
+// The actors of a world that can be sent messages on the
+// chat channels other than say. Actors join when they
+// connect to the world and leave when they disconnect.
+type chatChannels struct {
+	mu sync.RWMutex
+
+	// Indexed by the actor's name in lower case
+	actors map[string]*actor
+
+	// The state of each actor as of its last update phase.
+	// Actors in other zones are only read through this copy.
+	presence map[*actor]chatPresence
+
+	// May be nil
+	filter ChatFilter
+}
+
+// The state of an actor published to the chat channels.
+type chatPresence struct {
+	cell coord.Cell
+
+	allegiance datastore.Allegiance
+	guild      string
+}
+
+func newChatChannels(filter ChatFilter) *chatChannels {
+	return &chatChannels{
+		actors:   make(map[string]*actor),
+		presence: make(map[*actor]chatPresence),
+		filter:   filter,
+	}
+}
+
+// Must be called before the actor is being simulated.
+func (c *chatChannels) join(a *actor) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.actors[strings.ToLower(a.name)] = a
+	c.presence[a] = a.chatPresence()
+	c.mu.Unlock()
+}
+
+// Is called by the actor's update phase.
+func (c *chatChannels) publish(a *actor) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	if _, joined := c.presence[a]; joined {
+		c.presence[a] = a.chatPresence()
+	}
+	c.mu.Unlock()
+}
+
+func (a *actor) chatPresence() chatPresence {
+	return chatPresence{
+		cell:       a.Cell(),
+		allegiance: a.allegiance,
+		guild:      a.guild,
+	}
+}
+
+func (c *chatChannels) leave(a *actor) {
+	if c == nil {
+		return
+	}
+
+	name := strings.ToLower(a.name)
+
+	c.mu.Lock()
+	if c.actors[name] == a {
+		delete(c.actors, name)
+	}
+	delete(c.presence, a)
+	c.mu.Unlock()
+}
+
+// Returns nil if no actor with the name has joined.
+func (c *chatChannels) actorNamed(name string) *actor {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.actors[strings.ToLower(name)]
+}
+
+func (c *chatChannels) all() []*actor {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	actors := make([]*actor, 0, len(c.actors))
+	for _, a := range c.actors {
+		actors = append(actors, a)
+	}
+	return actors
+}
+
+func (c *chatChannels) filterMsg(channel ChatRequestType, from, msg string) (string, bool) {
+	if c == nil || c.filter == nil {
+		return msg, true
+	}
+
+	return c.filter.FilterChat(channel, from, msg)
+}
+
+// Returns the actors within the radius of the cell.
+func (c *chatChannels) actorsNear(cell coord.Cell, radius int) []*actor {
+	if c == nil {
+		return nil
+	}
+
+	bounds := coord.Bounds{
+		cell.Add(-radius, radius),
+		cell.Add(radius, -radius),
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var actors []*actor
+	for _, a := range c.actors {
+		if bounds.Contains(c.presence[a].cell) {
+			actors = append(actors, a)
+		}
+	}
+	return actors
+}
+
+// Sent to the connections of the actors that receive
+// a message sent to a channel other than say.
+type ChatMsg struct {
+	Time    stime.Time      `json:"time"`
+	Channel ChatRequestType `json:"channel"`
+
+	From string `json:"from"`
+
+	// The recipient of a whisper
+	To string `json:"to"`
+
+	Msg string `json:"msg"`
+}
+
+type ChatRejectedReason int
+
+//go:generate stringer -type=ChatRejectedReason
+const (
+	CHRR_ERROR ChatRejectedReason = iota
+	CHRR_NO_RECIPIENT
+	CHRR_NO_PARTY
+	CHRR_RATE_LIMITED
+	CHRR_MUTED
+	CHRR_IGNORED
+	CHRR_FILTERED
+	CHRR_NO_FACTION
+	CHRR_NO_GUILD
+
+	// Requests were replaced by a newer request
+	// before the simulation could read them.
+	CHRR_DROPPED
+)
+
+// Sent to an actor's connection when a chat
+// message could not be sent.
+type ChatRejectedMsg struct {
+	Time    stime.Time         `json:"time"`
+	Channel ChatRequestType    `json:"channel"`
+	To      string             `json:"to"`
+	Reason  ChatRejectedReason `json:"reason"`
+
+	// The number of requests that were dropped
+	// if the reason is CHRR_DROPPED.
+	Dropped int `json:"dropped"`
+}
+
+func (a *actor) rejectChatCmd(cmd *chatCmd, reason ChatRejectedReason, now stime.Time) {
+	a.queueMsg(ChatRejectedMsg{now, cmd.ChatRequestType, cmd.to, reason, 0})
+}
+
+// Tells the sender about the requests that were replaced by
+// the command and counts them against its rate limit.
+func (a *actor) reportDroppedChatCmds(cmd *chatCmd, now stime.Time) {
+	if cmd.dropped == 0 {
+		return
+	}
+
+	a.chatRate.drop(cmd.dropped, now)
+	a.queueMsg(ChatRejectedMsg{now, cmd.ChatRequestType, cmd.to, CHRR_DROPPED, cmd.dropped})
+}
+
+// Sends the message to the recipients. The sender
+// receives the message on the same tick it was sent.
+func (a *actor) sendChat(cmd *chatCmd, recipients []*actor, now stime.Time) {
+	msg := ChatMsg{
+		Time:    now,
+		Channel: cmd.ChatRequestType,
+		From:    a.name,
+		To:      cmd.to,
+		Msg:     cmd.msg,
+	}
+
+	for _, r := range recipients {
+		switch {
+		case r == a:
+			a.queueMsg(msg)
+		case !r.chatMod.ignores(a.name):
+			r.deliverMsg(msg)
+		}
+	}
+}
+
+// Things actors say are entities every nearby actor can see
+// instead of messages. They're removed from the state the
+// actor is sent if it ignores the actor that said them. The
+// names of the actors are found from their entities.
+func (a *actor) withoutIgnoredSays(state rpg2d.WorldState) rpg2d.WorldState {
+	if !a.chatMod.ignoresAnyone() {
+		return state
+	}
+
+	ignored := make(map[entity.Id]bool)
+	for _, e := range state.Entities {
+		if e, isActor := e.(ActorEntityState); isActor && a.chatMod.ignores(e.Name) {
+			ignored[e.Id] = true
+		}
+	}
+
+	if len(ignored) == 0 {
+		return state
+	}
+
+	// The entities may share a backing array
+	// with a previous state and must be copied.
+	entities := make(entity.StateSlice, 0, len(state.Entities))
+	for _, e := range state.Entities {
+		if say, isSay := e.(SayEntityState); isSay && ignored[say.SaidBy] {
+			continue
+		}
+		entities = append(entities, e)
+	}
+
+	state.Entities = entities
+	return state
+}
+
+// The sender receives a copy of the whisper
+// so they can see who it was sent to.
+func (a *actor) whisper(cmd *chatCmd, now stime.Time) {
+	to := a.chat.actorNamed(cmd.to)
+	if to == nil {
+		a.rejectChatCmd(cmd, CHRR_NO_RECIPIENT, now)
+		return
+	}
+
+	cmd.to = to.name
+
+	if to.chatMod.ignores(a.name) {
+		a.rejectChatCmd(cmd, CHRR_IGNORED, now)
+		return
+	}
+
+	if to == a {
+		a.sendChat(cmd, []*actor{a}, now)
+	} else {
+		a.sendChat(cmd, []*actor{to, a}, now)
+	}
+}
+
+// The chat settings of an actor that are read while
+// messages are delivered by other actors and changed
+// by administrators.
+type chatModeration struct {
+	mu sync.Mutex
+
+	// The actor can't chat until this time
+	mutedUntil time.Time
+
+	// Names of the actors whose messages aren't
+	// delivered, in lower case
+	ignoring map[string]bool
+}
+
+func newChatModeration() *chatModeration {
+	return &chatModeration{
+		ignoring: make(map[string]bool),
+	}
+}
+
+func (m *chatModeration) mute(until time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.mutedUntil = until
+	m.mu.Unlock()
+}
+
+func (m *chatModeration) isMuted() bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return time.Now().Before(m.mutedUntil)
+}
+
+func (m *chatModeration) ignore(name string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	m.ignoring[strings.ToLower(name)] = true
+	m.mu.Unlock()
+}
+
+func (m *chatModeration) unignore(name string) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	delete(m.ignoring, strings.ToLower(name))
+	m.mu.Unlock()
+}
+
+func (m *chatModeration) ignores(name string) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ignoring[strings.ToLower(name)]
+}
+
+func (m *chatModeration) ignoresAnyone() bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.ignoring) > 0
+}
+
+// The times of the chat messages an actor has sent
+// during the rate period.
+type chatRate []stime.Time
+
+// Forgets the messages that were sent before the rate period.
+func (r *chatRate) prune(now stime.Time) {
+	sent := (*r)[:0]
+	for _, t := range *r {
+		if t+chatRatePeriod > now {
+			sent = append(sent, t)
+		}
+	}
+	*r = sent
+}
+
+// Records a message sent at the time and returns
+// false if the actor has exceeded the rate limit.
+func (r *chatRate) allow(now stime.Time) bool {
+	r.prune(now)
+
+	if len(*r) >= chatRateLimit {
+		return false
+	}
+
+	*r = append(*r, now)
+	return true
+}
+
+// Records requests that were dropped at the time. Requests
+// beyond the rate limit aren't recorded as they can't
+// make the actor wait any longer.
+func (r *chatRate) drop(n int, now stime.Time) {
+	r.prune(now)
+
+	for i := 0; i < n && len(*r) < chatRateLimit; i++ {
+		*r = append(*r, now)
+	}
+}
+
+// Returns false if the message must not be sent and
+// tells the sender why. The message is replaced if it
+// has been changed by the chat filter.
+func (a *actor) moderateChatCmd(cmd *chatCmd, now stime.Time) bool {
+	if !a.chatRate.allow(now) {
+		a.rejectChatCmd(cmd, CHRR_RATE_LIMITED, now)
+		return false
+	}
+
+	return a.moderateChatMsg(cmd, now)
+}
+
+// Moderates the message of a chat command that has
+// already been counted against the actor's rate limit.
+func (a *actor) moderateChatMsg(cmd *chatCmd, now stime.Time) bool {
+	if a.chatMod.isMuted() {
+		a.rejectChatCmd(cmd, CHRR_MUTED, now)
+		return false
+	}
+
+	msg, ok := a.chat.filterMsg(cmd.ChatRequestType, a.name, cmd.msg)
+	if !ok {
+		a.rejectChatCmd(cmd, CHRR_FILTERED, now)
+		return false
+	}
+
+	cmd.msg = msg
+	return true
+}
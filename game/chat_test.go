@@ -0,0 +1,250 @@
+package game
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func newChatTestActor(chat *chatChannels, name string, cell coord.Cell) *actor {
+	a := &actor{}
+	a.name = name
+	a.actorEntity.cell = cell
+	a.inbox = &msgInbox{}
+	a.chat = chat
+	a.chatMod = newChatModeration()
+	chat.join(a)
+	return a
+}
+
+func (a *actor) deliveredMsgs() []ActorMsg {
+	a.queueDeliveredMsgs()
+	msgs := a.msgs
+	a.msgs = nil
+	return msgs
+}
+
+// Makes the cmd the actor's next chat cmd.
+func (a *actor) withChatCmd(cmd *chatCmd) *actor {
+	cmds := make(chan *chatCmd, 1)
+	cmds <- cmd
+	a.readChatCmd = cmds
+	return a
+}
+
+func DescribeChatChannels(c gospec.Context) {
+	c.Specify("a whisper request", func() {
+		c.Specify("begins with the name of the recipient", func() {
+			r, err := newChatRequest(CR_WHISPER, 1, "bob hello there")
+			c.Assume(err, IsNil)
+			c.Expect(r.To, Equals, "bob")
+			c.Expect(r.Msg, Equals, "hello there")
+		})
+
+		c.Specify("is invalid without a message", func() {
+			_, err := newChatRequest(CR_WHISPER, 1, "bob")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("chat channels", func() {
+		chat := newChatChannels(nil)
+
+		alice := newChatTestActor(chat, "Alice", coord.Cell{0, 0})
+		bob := newChatTestActor(chat, "Bob", coord.Cell{40, 0})
+		carol := newChatTestActor(chat, "Carol", coord.Cell{200, 0})
+
+		c.Specify("deliver a whisper to the named actor", func() {
+			alice.whisper(&chatCmd{CR_WHISPER, 1, "hi", "bob", 0}, 1)
+
+			msg := ChatMsg{1, CR_WHISPER, "Alice", "Bob", "hi"}
+			c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{msg})
+			c.Expect(len(carol.deliveredMsgs()), Equals, 0)
+
+			c.Specify("and a copy to the sender", func() {
+				c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{msg})
+			})
+		})
+
+		c.Specify("reject a whisper to an actor that isn't connected", func() {
+			alice.whisper(&chatCmd{CR_WHISPER, 1, "hi", "dave", 0}, 1)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{1, CR_WHISPER, "dave", CHRR_NO_RECIPIENT, 0},
+			})
+		})
+
+		c.Specify("stop delivering to an actor that has left", func() {
+			chat.leave(bob)
+			c.Expect(chat.actorNamed("bob"), IsNil)
+		})
+
+		c.Specify("deliver a shout to the actors within its radius", func() {
+			alice.sendChat(&chatCmd{CR_SHOUT, 1, "hey", "", 0}, chat.actorsNear(alice.Cell(), shoutRadius), 1)
+
+			c.Expect(len(alice.deliveredMsgs()), Equals, 1)
+			c.Expect(len(bob.deliveredMsgs()), Equals, 1)
+			c.Expect(len(carol.deliveredMsgs()), Equals, 0)
+		})
+
+		c.Specify("find the actors near a cell where they were last published", func() {
+			carol.actorEntity.cell = coord.Cell{10, 0}
+			c.Expect(chat.actorsNear(alice.Cell(), shoutRadius), ContainsExactly, []*actor{alice, bob})
+
+			chat.publish(carol)
+			c.Expect(chat.actorsNear(alice.Cell(), shoutRadius), ContainsExactly, []*actor{alice, bob, carol})
+		})
+
+		c.Specify("deliver a global message to every actor", func() {
+			alice.sendChat(&chatCmd{CR_GLOBAL, 1, "hey", "", 0}, chat.all(), 1)
+
+			for _, a := range []*actor{alice, bob, carol} {
+				c.Expect(a.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatMsg{1, CR_GLOBAL, "Alice", "", "hey"},
+				})
+			}
+		})
+	})
+
+	c.Specify("chat moderation", func() {
+		chat := newChatChannels(ChatFilterFunc(func(_ ChatRequestType, _, msg string) (string, bool) {
+			if strings.Contains(msg, "spam") {
+				return "", false
+			}
+			return strings.Replace(msg, "darn", "****", -1), true
+		}))
+
+		alice := newChatTestActor(chat, "Alice", coord.Cell{0, 0})
+		bob := newChatTestActor(chat, "Bob", coord.Cell{1, 0})
+
+		cmd := func(msg string) *chatCmd {
+			return &chatCmd{CR_GLOBAL, 1, msg, "", 0}
+		}
+
+		c.Specify("limits the rate an actor can chat", func() {
+			for i := 0; i < chatRateLimit; i++ {
+				c.Expect(alice.moderateChatCmd(cmd("hi"), stime.Time(i)), IsTrue)
+			}
+
+			c.Expect(alice.moderateChatCmd(cmd("hi"), chatRateLimit), IsFalse)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{chatRateLimit, CR_GLOBAL, "", CHRR_RATE_LIMITED, 0},
+			})
+
+			c.Specify("until the rate period has passed", func() {
+				c.Expect(alice.moderateChatCmd(cmd("hi"), chatRatePeriod), IsTrue)
+			})
+		})
+
+		c.Specify("tells the sender about requests that were dropped", func() {
+			dropped := cmd("hi")
+			dropped.dropped = 2
+
+			inputPhase{}.processChatCmd(alice.withChatCmd(dropped), 1)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{1, CR_GLOBAL, "", CHRR_DROPPED, 2},
+				ChatMsg{1, CR_GLOBAL, "Alice", "", "hi"},
+			})
+
+			c.Specify("and counts them against its rate limit", func() {
+				for i := 0; i < chatRateLimit-3; i++ {
+					c.Expect(alice.moderateChatCmd(cmd("hi"), 2), IsTrue)
+				}
+				c.Expect(alice.moderateChatCmd(cmd("hi"), 2), IsFalse)
+			})
+
+			c.Specify("after forgetting messages sent before the rate period", func() {
+				var rate chatRate
+				for i := 0; i < chatRateLimit; i++ {
+					rate.allow(0)
+				}
+
+				rate.drop(2, chatRatePeriod)
+				c.Expect(rate, ContainsExactly, chatRate{chatRatePeriod, chatRatePeriod})
+			})
+		})
+
+		c.Specify("tells the sender about requests that were dropped before an ignore", func() {
+			ignore := &chatCmd{CR_IGNORE, 1, "", "bob", 1}
+
+			inputPhase{}.processChatCmd(alice.withChatCmd(ignore), 1)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{1, CR_IGNORE, "bob", CHRR_DROPPED, 1},
+			})
+			c.Expect(alice.chatMod.ignores("bob"), IsTrue)
+		})
+
+		c.Specify("rejects messages from a muted actor", func() {
+			alice.chatMod.mute(time.Now().Add(time.Minute))
+			c.Expect(alice.moderateChatCmd(cmd("hi"), 1), IsFalse)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{1, CR_GLOBAL, "", CHRR_MUTED, 0},
+			})
+
+			c.Specify("until the mute has expired", func() {
+				alice.chatMod.mute(time.Now().Add(-time.Second))
+				c.Expect(alice.moderateChatCmd(cmd("hi"), 2), IsTrue)
+			})
+		})
+
+		c.Specify("filters messages", func() {
+			censored := cmd("darn it")
+			c.Expect(alice.moderateChatCmd(censored, 1), IsTrue)
+			c.Expect(censored.msg, Equals, "**** it")
+
+			c.Specify("and rejects messages the filter refuses", func() {
+				c.Expect(alice.moderateChatCmd(cmd("buy spam"), 2), IsFalse)
+				c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatRejectedMsg{2, CR_GLOBAL, "", CHRR_FILTERED, 0},
+				})
+			})
+		})
+
+		c.Specify("doesn't deliver messages from an ignored actor", func() {
+			bob.chatMod.ignore("ALICE")
+			alice.sendChat(cmd("hi"), chat.all(), 1)
+
+			c.Expect(len(bob.deliveredMsgs()), Equals, 0)
+			c.Expect(len(alice.deliveredMsgs()), Equals, 1)
+
+			c.Specify("and rejects whispers to the actor ignoring them", func() {
+				alice.whisper(&chatCmd{CR_WHISPER, 2, "hi", "bob", 0}, 2)
+				c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatRejectedMsg{2, CR_WHISPER, "Bob", CHRR_IGNORED, 0},
+				})
+			})
+
+			c.Specify("until they are unignored", func() {
+				bob.chatMod.unignore("alice")
+				alice.sendChat(cmd("hi"), chat.all(), 2)
+				c.Expect(len(bob.deliveredMsgs()), Equals, 1)
+			})
+		})
+
+		c.Specify("doesn't show what an ignored actor says", func() {
+			state := rpg2d.WorldState{Entities: entity.StateSlice{
+				ActorEntityState{Id: 1, Name: "Alice"},
+				ActorEntityState{Id: 3, Name: "Carol"},
+				SayEntityState{Id: 10, SaidBy: 1, Msg: "hi"},
+				SayEntityState{Id: 11, SaidBy: 3, Msg: "hello"},
+			}}
+
+			bob.chatMod.ignore("alice")
+			c.Expect(bob.withoutIgnoredSays(state).Entities, ContainsExactly, entity.StateSlice{
+				ActorEntityState{Id: 1, Name: "Alice"},
+				ActorEntityState{Id: 3, Name: "Carol"},
+				SayEntityState{Id: 11, SaidBy: 3, Msg: "hello"},
+			})
+
+			c.Specify("to the actor ignoring them", func() {
+				c.Expect(alice.withoutIgnoredSays(state).Entities, ContainsExactly, state.Entities)
+			})
+		})
+	})
+}
@@ -0,0 +1,33 @@
+// Code generated by "stringer -type=ChatRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[CHRR_ERROR-0]
+	_ = x[CHRR_NO_RECIPIENT-1]
+	_ = x[CHRR_NO_PARTY-2]
+	_ = x[CHRR_RATE_LIMITED-3]
+	_ = x[CHRR_MUTED-4]
+	_ = x[CHRR_IGNORED-5]
+	_ = x[CHRR_FILTERED-6]
+	_ = x[CHRR_NO_FACTION-7]
+	_ = x[CHRR_NO_GUILD-8]
+	_ = x[CHRR_DROPPED-9]
+}
+
+const _ChatRejectedReason_name = "CHRR_ERRORCHRR_NO_RECIPIENTCHRR_NO_PARTYCHRR_RATE_LIMITEDCHRR_MUTEDCHRR_IGNOREDCHRR_FILTEREDCHRR_NO_FACTIONCHRR_NO_GUILDCHRR_DROPPED"
+
+var _ChatRejectedReason_index = [...]uint8{0, 10, 27, 40, 57, 67, 79, 92, 107, 120, 132}
+
+func (i ChatRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_ChatRejectedReason_index)-1 {
+		return "ChatRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _ChatRejectedReason_name[_ChatRejectedReason_index[idx]:_ChatRejectedReason_index[idx+1]]
+}
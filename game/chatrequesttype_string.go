@@ -10,16 +10,27 @@ func _() {
 	var x [1]struct{}
 	_ = x[CR_ERROR-0]
 	_ = x[CR_SAY-1]
-	_ = x[CR_SIZE-2]
+	_ = x[CR_WHISPER-2]
+	_ = x[CR_SHOUT-3]
+	_ = x[CR_PARTY-4]
+	_ = x[CR_GLOBAL-5]
+	_ = x[CR_FACTION-6]
+	_ = x[CR_GUILD-7]
+	_ = x[CR_EMOTE-8]
+	_ = x[CR_ANNOUNCE-9]
+	_ = x[CR_IGNORE-10]
+	_ = x[CR_UNIGNORE-11]
+	_ = x[CR_SIZE-12]
 }
 
-const _ChatRequestType_name = "CR_ERRORCR_SAYCR_SIZE"
+const _ChatRequestType_name = "CR_ERRORCR_SAYCR_WHISPERCR_SHOUTCR_PARTYCR_GLOBALCR_FACTIONCR_GUILDCR_EMOTECR_ANNOUNCECR_IGNORECR_UNIGNORECR_SIZE"
 
-var _ChatRequestType_index = [...]uint8{0, 8, 14, 21}
+var _ChatRequestType_index = [...]uint8{0, 8, 14, 24, 32, 40, 49, 59, 67, 75, 86, 95, 106, 113}
 
 func (i ChatRequestType) String() string {
-	if i < 0 || i >= ChatRequestType(len(_ChatRequestType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_ChatRequestType_index)-1 {
 		return "ChatRequestType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _ChatRequestType_name[_ChatRequestType_index[i]:_ChatRequestType_index[i+1]]
+	return _ChatRequestType_name[_ChatRequestType_index[idx]:_ChatRequestType_index[idx+1]]
 }
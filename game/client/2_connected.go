@@ -7,14 +7,29 @@ import (
 	"github.com/ghthor/filu/rpg2d"
 )
 
+// An update received from the server. Either a world
+// state diff or the messages sent privately to the actor.
+type Update struct {
+	rpg2d.WorldStateDiff
+
+	// Sent before the diff of the tick they were queued during.
+	Msgs []game.ActorMsg
+}
+
 type UpdateConn interface {
-	NextUpdate() (rpg2d.WorldStateDiff, error)
+	NextUpdate() (Update, error)
 }
 
 type InputConn interface {
 	SendMoveRequest(game.MoveRequest)
 	SendUseRequest(game.UseRequest)
 	SendChatRequest(game.ChatRequest)
+	SendBuildRequest(game.BuildRequest)
+	SendCraftRequest(game.CraftRequest)
+	SendEquipRequest(game.EquipRequest)
+	SendEmoteRequest(game.EmoteRequest)
+	SendPartyRequest(game.PartyRequest)
+	SendTradeRequest(game.TradeRequest)
 }
 
 type InitialState struct {
@@ -25,38 +40,42 @@ type InitialState struct {
 	WorldState rpg2d.WorldState
 }
 
-func receiveUpdates(conn game.Conn, sendUpdate chan<- rpg2d.WorldStateDiff) (err error) {
-	var diff rpg2d.WorldStateDiff
+func receiveUpdates(conn game.Conn, sendUpdate chan<- Update) (err error) {
+	var update Update
 	for {
-		diff, err = receiveUpdate(conn)
+		update, err = receiveUpdate(conn)
 		if err != nil {
 			break
 		}
-		sendUpdate <- diff
+		sendUpdate <- update
 	}
 	return
 }
 
-func receiveUpdate(conn game.Conn) (diff rpg2d.WorldStateDiff, err error) {
+func receiveUpdate(conn game.Conn) (update Update, err error) {
 	eType, err := conn.ReadNextType()
 	if err != nil {
-		return diff, err
+		return update, err
 	}
 
 	switch eType {
 	default:
-		return diff, fmt.Errorf("unexpected encoded type %v waiting for state update diffs", eType)
+		return update, fmt.Errorf("unexpected encoded type %v waiting for state update diffs", eType)
 
 	case game.ET_WORLD_STATE_DIFF:
+		err = conn.Decode(&update.WorldStateDiff)
+
+	case game.ET_ACTOR_MSGS:
+		var msgs game.ActorMsgs
+		err = conn.Decode(&msgs)
+		update.Msgs = msgs.Msgs
 	}
 
-	err = conn.Decode(&diff)
 	if err != nil {
-		return diff, err
+		return update, err
 	}
 
-	return diff, nil
-
+	return update, nil
 }
 
 // An implementation of the UpdateConn interface
@@ -64,7 +83,7 @@ type updateReceiver struct {
 	conn game.Conn
 }
 
-func (c updateReceiver) NextUpdate() (rpg2d.WorldStateDiff, error) {
+func (c updateReceiver) NextUpdate() (Update, error) {
 	return receiveUpdate(c.conn)
 }
 
@@ -87,3 +106,33 @@ func (c requestSender) SendChatRequest(r game.ChatRequest) {
 	// TODO handle errors
 	c.conn.EncodeAndSend(game.ET_REQ_CHAT, r)
 }
+
+func (c requestSender) SendBuildRequest(r game.BuildRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_BUILD, r)
+}
+
+func (c requestSender) SendCraftRequest(r game.CraftRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_CRAFT, r)
+}
+
+func (c requestSender) SendEquipRequest(r game.EquipRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_EQUIP, r)
+}
+
+func (c requestSender) SendEmoteRequest(r game.EmoteRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_EMOTE, r)
+}
+
+func (c requestSender) SendPartyRequest(r game.PartyRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_PARTY, r)
+}
+
+func (c requestSender) SendTradeRequest(r game.TradeRequest) {
+	// TODO handle errors
+	c.conn.EncodeAndSend(game.ET_REQ_TRADE, r)
+}
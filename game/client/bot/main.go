@@ -140,9 +140,9 @@ func (b Bot) startRandomMove(ctx context.Context) {
 			if running {
 				dir := RandDir()
 				curDir = dir
-				b.SendMoveRequest(game.MoveRequest{game.MR_MOVE, 0, dir})
+				b.SendMoveRequest(game.MoveRequest{MoveRequestType: game.MR_MOVE, Direction: dir})
 			} else {
-				b.SendMoveRequest(game.MoveRequest{game.MR_MOVE_CANCEL, 0, curDir})
+				b.SendMoveRequest(game.MoveRequest{MoveRequestType: game.MR_MOVE_CANCEL, Direction: curDir})
 			}
 
 			select {
@@ -150,12 +150,12 @@ func (b Bot) startRandomMove(ctx context.Context) {
 				return
 			case <-stopCh:
 				if running {
-					b.SendChatRequest(game.ChatRequest{game.CR_SAY, 0, "stop"})
+					b.SendChatRequest(game.ChatRequest{game.CR_SAY, 0, "stop", ""})
 				}
 				running = false
 			case <-startCh:
 				if !running {
-					b.SendChatRequest(game.ChatRequest{game.CR_SAY, 0, "start"})
+					b.SendChatRequest(game.ChatRequest{game.CR_SAY, 0, "start", ""})
 				}
 				running = true
 			case <-next:
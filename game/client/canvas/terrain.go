@@ -74,6 +74,10 @@ func ApplyTerrainDiff(c TerrainContext, prevState rpg2d.WorldState, diff rpg2d.W
 
 	pb, nb := prevState.Bounds, diff.Bounds
 	switch {
+	case pb == nb:
+		// The viewport hasn't moved so only the
+		// tiles that have changed will be drawn.
+
 	case pb.Contains(nb.BotL()) && pb.Contains(nb.BotR):
 		c.Shift(TS_SOUTH, TerrainShiftMagnitudes{
 			coord.South: abs(nb.TopL.Y - pb.TopL.Y),
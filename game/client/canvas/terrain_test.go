@@ -266,5 +266,32 @@ DDDDDDDD
 				expectContextIsUpdated()
 			})
 		})
+
+		c.Specify("can have changed tiles drawn by a diff that hasn't moved", func() {
+			initialState := worldState.Cull(center)
+
+			context := &terrainContext{
+				TerrainMap: initialState.Clone().TerrainMap.TerrainMap,
+			}
+
+			changed, err := rpg2d.NewTerrainMap(coord.Bounds{
+				coord.Cell{0, 0},
+				coord.Cell{0, 0},
+			}, "D")
+			c.Assume(err, IsNil)
+
+			err = canvas.ApplyTerrainDiff(context, initialState, rpg2d.WorldStateDiff{
+				Bounds: center,
+				TerrainMapSlices: []rpg2d.TerrainMapStateSlice{{
+					Bounds:  changed.Bounds,
+					Terrain: changed.String(),
+				}},
+			})
+			c.Assume(err, IsNil)
+
+			expected := initialState.Clone().TerrainMap.TerrainMap
+			expected.SetType(rpg2d.TT_DIRT, coord.Cell{0, 0})
+			c.Expect(context.String(), Equals, expected.String())
+		})
 	})
 }
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
+	"github.com/ghthor/filu/rpg2d"
 	"github.com/ghthor/filu/rpg2d/coord"
 	"github.com/ghthor/filu/rpg2d/entity"
 	"github.com/ghthor/filu/rpg2d/quad"
@@ -13,10 +15,17 @@ import (
 
 type narrowPhaseLocker struct {
 	*ActorIndexLocker
+	terrain      *terrainIndex
+	friendlyFire bool
+	metrics      *tickMetrics
 }
 
 type narrowPhase struct {
 	actorIndex ActorIndex
+	terrain    *terrainIndex
+
+	// Actors can damage the members of their party
+	friendlyFire bool
 
 	// Reset at the beginning of every ResolveCollisions call
 	solved []quad.Collision
@@ -24,12 +33,12 @@ type narrowPhase struct {
 	collisionIndex quad.CollisionIndex
 }
 
-func newNarrowPhaseLocker(actorMap *ActorIndexLocker) narrowPhaseLocker {
-	return narrowPhaseLocker{actorMap}
+func newNarrowPhaseLocker(actorMap *ActorIndexLocker, terrain *terrainIndex, friendlyFire bool, metrics *tickMetrics) narrowPhaseLocker {
+	return narrowPhaseLocker{actorMap, terrain, friendlyFire, metrics}
 }
 
 func newNarrowPhase(actorIndex ActorIndex) narrowPhase {
-	return narrowPhase{actorIndex, make([]quad.Collision, 0, 10), nil}
+	return narrowPhase{actorIndex, nil, false, make([]quad.Collision, 0, 10), nil}
 }
 
 // Returns if the collision exists in the
@@ -46,8 +55,13 @@ func (phase narrowPhase) hasSolved(c quad.Collision) bool {
 }
 
 func (phase narrowPhaseLocker) ResolveCollisions(cg *quad.CollisionGroup, now stime.Time) ([]entity.Entity, []entity.Entity) {
+	defer phase.metrics.timePhase(TP_NARROW, now, time.Now())
 	defer phase.ActorIndexLocker.RUnlock()
-	return newNarrowPhase(phase.ActorIndexLocker.RLock()).ResolveCollisions(cg, now)
+
+	narrowPhase := newNarrowPhase(phase.ActorIndexLocker.RLock())
+	narrowPhase.terrain = phase.terrain
+	narrowPhase.friendlyFire = phase.friendlyFire
+	return narrowPhase.ResolveCollisions(cg, now)
 }
 
 // Implementation of the quad.NarrowPhaseHandler interface.
@@ -104,6 +118,13 @@ func (phase narrowPhase) ResolveCollisions(cg *quad.CollisionGroup, now stime.Ti
 }
 
 func (phase *narrowPhase) resolveActorEntity(a *actor, with entity.Entity, collision quad.Collision, now stime.Time) []entity.Entity {
+	// The terrain may have changed since the actor
+	// started moving. An actor can't win a destination
+	// it isn't able to walk on.
+	if a.pathAction != nil && !phase.terrain.propertiesAt(a.pathAction.Dest).walkable {
+		a.revertMoveAction()
+	}
+
 	switch e := with.(type) {
 	case actorEntity:
 		b := phase.actorIndex[e.ActorId()]
@@ -112,9 +133,20 @@ func (phase *narrowPhase) resolveActorEntity(a *actor, with entity.Entity, colli
 	case assailEntity:
 		return phase.solveActorAssail(a, e, collision, now)
 
+	case aoeEntity:
+		return phase.solveActorAoe(a, e, now)
+
 	case wallEntity:
 		a.revertMoveAction()
 		return []entity.Entity{a.Entity(), e}
+
+	case structureEntity:
+		a.revertMoveAction()
+		return []entity.Entity{a.Entity(), e}
+
+	case resourceNodeEntity:
+		a.revertMoveAction()
+		return []entity.Entity{a.Entity(), e}
 	}
 
 	return nil
@@ -126,6 +158,10 @@ func (phase *narrowPhase) solveActorAssail(a *actor, assail assailEntity, collis
 		return []entity.Entity{a.Entity()}
 	}
 
+	if !phase.friendlyFire && phase.isFriendly(assail.spawnedByActor, a) {
+		return []entity.Entity{a.Entity()}
+	}
+
 	var percentDamage float64
 
 	switch a.pathAction {
@@ -138,19 +174,62 @@ func (phase *narrowPhase) solveActorAssail(a *actor, assail assailEntity, collis
 		percentDamage = coordCollision.OverlapAt(now)
 	}
 
-	damage := int(math.Floor(float64(assail.damage) * percentDamage))
+	if a.takeDamage(a.mitigate(int(math.Floor(float64(assail.damage) * percentDamage)))) {
+		phase.recordKill(assail.spawnedByActor, a, now)
+	}
 
-	a.hp -= damage
+	return []entity.Entity{a.Entity()}
+}
 
-	if a.hp <= 0 {
-		a.hp = 100
+// Returns true if the target is considered friendly with
+// the source of damage. An actor is always friendly
+// with itself, the members of its party and the
+// actors that serve the same power.
+func (phase *narrowPhase) isFriendly(source rpg2d.ActorId, target *actor) bool {
+	if source == target.Id() || target.parties.inSameParty(target, source) {
+		return true
+	}
 
-		a.actorEntity.cell = origin
-		a.actorEntity.facing = coord.South
-		a.actorEntity.pathAction = nil
+	a, exists := phase.actorIndex[source]
+	return exists && a.isAllyOf(target.actorEntity)
+}
+
+func (phase *narrowPhase) solveActorAoe(a *actor, aoe aoeEntity, now stime.Time) []entity.Entity {
+	// Don't damage yourself
+	if aoe.spawnedBy == a.actorEntity.Id() {
+		return []entity.Entity{a.Entity(), aoe}
 	}
 
-	return []entity.Entity{a.Entity()}
+	if (!aoe.friendlyFire || !phase.friendlyFire) && phase.isFriendly(aoe.spawnedByActor, a) {
+		return []entity.Entity{a.Entity(), aoe}
+	}
+
+	var damage float64
+
+	switch a.pathAction {
+	case nil:
+		if d, exists := aoe.damageAt(a.Cell()); exists {
+			damage = float64(d)
+		}
+	default:
+		// A moving actor is only partially inside
+		// the cells at each end of its path.
+		for _, c := range [...]coord.Cell{a.pathAction.Orig, a.pathAction.Dest} {
+			d, exists := aoe.damageAt(c)
+			if !exists {
+				continue
+			}
+
+			coordCollision := coord.NewCellCollision(*a.pathAction, c)
+			damage += float64(d) * coordCollision.OverlapAt(now)
+		}
+	}
+
+	if a.takeDamage(a.mitigate(int(math.Floor(damage)))) {
+		phase.recordKill(aoe.spawnedByActor, a, now)
+	}
+
+	return []entity.Entity{a.Entity(), aoe}
 }
 
 func newActorActorCollision(a, b *actor) (*actor, *actor, coord.Collision) {
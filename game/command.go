@@ -0,0 +1,506 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// The commands an actor is permitted to issue.
+type Permission int
+
+//go:generate stringer -type=Permission
+const (
+	PERM_PLAYER Permission = iota
+	PERM_ADMIN
+)
+
+// Distance in cells an emote can be seen from.
+// Matches the distance an actor can see.
+const emoteRadius = 26
+
+// How long an actor must wait between using /stuck.
+const stuckCooldown = 60 * 40 // 60 seconds in frames
+
+// Distance in cells from its spawn an actor using /stuck
+// can be placed if its spawn is blocked.
+const stuckRadius = 3
+
+// Handles a slash command issued by an actor. The arguments
+// have been validated. The reply is sent to the connection
+// that issued the command unless it's empty.
+type slashCommandHandler func(a *actor, args []string, now stime.Time) (reply string, err error)
+
+// A command that can be issued by typing /name in chat.
+type slashCommand struct {
+	name  string
+	usage string
+	help  string
+
+	// The number of arguments the command accepts. The
+	// last argument contains the rest of the message,
+	// including its spaces, such as the text of a whisper.
+	minArgs, maxArgs int
+
+	permission Permission
+	handler    slashCommandHandler
+}
+
+// Indexed by the name of the command and its aliases.
+type slashCommandRegistry map[string]*slashCommand
+
+func (r slashCommandRegistry) register(cmd slashCommand, aliases ...string) {
+	c := &cmd
+	for _, name := range append([]string{cmd.name}, aliases...) {
+		if _, exists := r[name]; exists {
+			panic(fmt.Sprint("slash command already registered: ", name))
+		}
+
+		r[name] = c
+	}
+}
+
+// Returns the commands the permission allows sorted by name.
+func (r slashCommandRegistry) permitted(p Permission) []*slashCommand {
+	var cmds []*slashCommand
+	for name, cmd := range r {
+		// Skip aliases
+		if name == cmd.name && cmd.permission <= p {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].name < cmds[j].name
+	})
+
+	return cmds
+}
+
+var (
+	errUnknownCommand   = errors.New("unknown command, type /help for a list of commands")
+	errPermissionDenied = errors.New("you don't have permission to use that command")
+)
+
+// Splits the text following a command into at most max
+// arguments. The last argument is the remaining text.
+func splitCommandArgs(text string, max int) []string {
+	var args []string
+
+	text = strings.TrimSpace(text)
+	for text != "" {
+		if len(args) == max-1 {
+			return append(args, text)
+		}
+
+		i := strings.IndexAny(text, " \t")
+		if i < 0 {
+			return append(args, text)
+		}
+
+		args = append(args, text[:i])
+		text = strings.TrimSpace(text[i:])
+	}
+
+	return args
+}
+
+// Parses and runs the command in a message beginning with
+// a slash. Returns the reply to the issuing connection.
+func (r slashCommandRegistry) run(a *actor, msg string, now stime.Time) (string, error) {
+	name, text := strings.TrimPrefix(msg, "/"), ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, text = name[:i], name[i:]
+	}
+
+	cmd, exists := r[strings.ToLower(name)]
+	if !exists {
+		return "", errUnknownCommand
+	}
+
+	if a.permission < cmd.permission {
+		return "", errPermissionDenied
+	}
+
+	args := splitCommandArgs(text, cmd.maxArgs)
+	if len(args) < cmd.minArgs || len(args) > cmd.maxArgs {
+		return "", fmt.Errorf("usage: %s", cmd.usage)
+	}
+
+	return cmd.handler(a, args, now)
+}
+
+// Sent to the connection that issued a slash command.
+type CommandReplyMsg struct {
+	Time    stime.Time `json:"time"`
+	Command string     `json:"command"`
+	Reply   string     `json:"reply"`
+
+	// The reply explains why the command failed
+	Failed bool `json:"failed"`
+}
+
+// Runs a chat message that begins with a slash as a
+// slash command. Returns false if the message isn't a
+// command and should be sent to its channel.
+func (a *actor) runSlashCommand(cmd *chatCmd, now stime.Time) bool {
+	if !strings.HasPrefix(cmd.msg, "/") {
+		return false
+	}
+
+	var reply string
+	var err error
+
+	// Commands count against the chat rate limit
+	if a.chatRate.allow(now) {
+		reply, err = slashCommands.run(a, cmd.msg, now)
+	} else {
+		err = errors.New("you're sending commands too quickly")
+	}
+
+	switch {
+	case err != nil:
+		a.queueMsg(CommandReplyMsg{now, cmd.msg, err.Error(), true})
+	case reply != "":
+		a.queueMsg(CommandReplyMsg{now, cmd.msg, reply, false})
+	}
+
+	return true
+}
+
+var slashCommands = newSlashCommands()
+
+func newSlashCommands() slashCommandRegistry {
+	r := make(slashCommandRegistry)
+
+	r.register(slashCommand{
+		name:    "help",
+		usage:   "/help",
+		help:    "lists the commands you can use",
+		maxArgs: 0,
+		handler: func(a *actor, _ []string, _ stime.Time) (string, error) {
+			var lines []string
+			for _, cmd := range r.permitted(a.permission) {
+				lines = append(lines, cmd.usage+" - "+cmd.help)
+			}
+			return strings.Join(lines, "\n"), nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "who",
+		usage:   "/who",
+		help:    "lists the players in the world",
+		maxArgs: 0,
+		handler: func(a *actor, _ []string, _ stime.Time) (string, error) {
+			var names []string
+			for _, other := range a.chat.all() {
+				names = append(names, other.name)
+			}
+			sort.Strings(names)
+
+			return fmt.Sprintf("%d players: %s", len(names), strings.Join(names, ", ")), nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "whisper",
+		usage:   "/w name message",
+		help:    "sends a message only the named player can see",
+		minArgs: 2,
+		maxArgs: 2,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			cmd := &chatCmd{ChatRequestType: CR_WHISPER, Time: now, msg: args[1], to: args[0]}
+			if a.moderateChatMsg(cmd, now) {
+				a.whisper(cmd, now)
+			}
+			return "", nil
+		},
+	}, "w")
+
+	r.register(slashCommand{
+		name:    "emote",
+		usage:   "/emote action",
+		help:    "describes an action to the players near you",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			cmd := &chatCmd{ChatRequestType: CR_EMOTE, Time: now, msg: args[0]}
+			if a.moderateChatMsg(cmd, now) {
+				a.sendChat(cmd, a.chat.actorsNear(a.Cell(), emoteRadius), now)
+			}
+			return "", nil
+		},
+	}, "me")
+
+	r.register(slashCommand{
+		name:    "stuck",
+		usage:   "/stuck",
+		help:    "returns you to your spawn",
+		maxArgs: 0,
+		handler: func(a *actor, _ []string, now stime.Time) (string, error) {
+			if !a.cooldowns.isReady("stuck", now) {
+				return "", errors.New("you must wait before using /stuck again")
+			}
+
+			// The actor is moved by its zone once
+			// the input phase has finished.
+			ok := a.zone.teleportNear(a, a.spawn, stuckRadius, func(moved bool) {
+				reply := CommandReplyMsg{now, "/stuck", "you have been returned to your spawn", false}
+				if !moved {
+					reply.Reply, reply.Failed = "there isn't any room near your spawn", true
+				}
+				a.deliverMsg(reply)
+			})
+			if !ok {
+				return "", errors.New("you can't be returned to your spawn right now")
+			}
+
+			a.interruptCast()
+			a.path = nil
+			a.actorEntity.facing = coord.South
+
+			a.startCooldown("stuck", now)
+			return "", nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "ignore",
+		usage:   "/ignore name",
+		help:    "stops showing you the messages of the named player",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(a *actor, args []string, _ stime.Time) (string, error) {
+			a.chatMod.ignore(args[0])
+			return "ignoring " + args[0], nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "unignore",
+		usage:   "/unignore name",
+		help:    "shows you the messages of the named player again",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(a *actor, args []string, _ stime.Time) (string, error) {
+			a.chatMod.unignore(args[0])
+			return "no longer ignoring " + args[0], nil
+		},
+	})
+
+	// Rejected party commands are replied to with a PartyRejectedMsg
+	partyCommand := func(t PartyRequestType) slashCommandHandler {
+		return func(a *actor, args []string, now stime.Time) (string, error) {
+			cmd := &partyCmd{PartyRequestType: t, Time: now}
+			if len(args) > 0 {
+				cmd.name = args[0]
+			}
+
+			a.runPartyCmd(cmd, now)
+			return "", nil
+		}
+	}
+
+	r.register(slashCommand{
+		name:    "invite",
+		usage:   "/invite name",
+		help:    "invites the named player to join your party",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: partyCommand(PR_INVITE),
+	})
+
+	r.register(slashCommand{
+		name:    "accept",
+		usage:   "/accept [name]",
+		help:    "joins the party you were last invited to",
+		maxArgs: 1,
+		handler: partyCommand(PR_ACCEPT),
+	})
+
+	r.register(slashCommand{
+		name:    "leave",
+		usage:   "/leave",
+		help:    "leaves your party",
+		maxArgs: 0,
+		handler: partyCommand(PR_LEAVE),
+	})
+
+	r.register(slashCommand{
+		name:    "kick",
+		usage:   "/kick name",
+		help:    "removes the named player from the party you lead",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: partyCommand(PR_KICK),
+	})
+
+	// Rejected trade commands are replied to with a TradeRejectedMsg
+	r.register(slashCommand{
+		name:    "trade",
+		usage:   "/trade name|offer [item count]...|lock|confirm|cancel",
+		help:    "trades items with the named player standing next to you",
+		minArgs: 1,
+		maxArgs: 2,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			var params string
+			if len(args) > 1 {
+				params = args[1]
+			}
+
+			t := TR_REQUEST
+			switch args[0] {
+			case "offer":
+				t = TR_OFFER
+			case "lock":
+				t = TR_LOCK
+			case "confirm":
+				t = TR_CONFIRM
+			case "cancel":
+				t = TR_CANCEL
+			default:
+				params = strings.Join(args, " ")
+			}
+
+			r, err := newTradeRequest(t, now, params)
+			if err != nil {
+				return "", err
+			}
+
+			a.runTradeCmd(&tradeCmd{r.TradeRequestType, r.Time, r.Name, r.Items}, now)
+			return "", nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "pledge",
+		usage:   "/pledge good|bad",
+		help:    "pledges your allegiance to the Big Good's or the Big Bad's",
+		minArgs: 1,
+		maxArgs: 1,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			allegiance, err := parseAllegiance(args[0])
+			if err != nil {
+				return "", err
+			}
+
+			if err := a.pledge(allegiance, now); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("you now serve the %s", allegiance), nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "renounce",
+		usage:   "/renounce",
+		help:    "renounces your allegiance, leaving your guild and losing your standing",
+		maxArgs: 0,
+		handler: func(a *actor, _ []string, now stime.Time) (string, error) {
+			if err := a.renounce(now); err != nil {
+				return "", err
+			}
+			return "you no longer serve anyone", nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:    "guild",
+		usage:   "/guild [found name|join name|leave]",
+		help:    "founds, joins or leaves a guild of those who serve the same power",
+		maxArgs: 2,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			if len(args) == 0 {
+				if a.guild == "" {
+					return "you aren't in a guild", nil
+				}
+				return fmt.Sprintf("you're a member of %s, your standing is %d", a.guild, a.standing), nil
+			}
+
+			var err error
+			switch {
+			case args[0] == "found" && len(args) == 2:
+				err = a.foundGuild(args[1], now)
+			case args[0] == "join" && len(args) == 2:
+				err = a.joinGuild(args[1], now)
+			case args[0] == "leave" && len(args) == 1:
+				err = a.leaveGuild(now)
+			default:
+				err = errors.New("usage: /guild [found name|join name|leave]")
+			}
+
+			if err != nil {
+				return "", err
+			}
+
+			if a.guild == "" {
+				return "you have left your guild", nil
+			}
+			return "you're now a member of " + a.guild, nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:       "mute",
+		usage:      "/mute name minutes",
+		help:       "stops the named player from chatting",
+		minArgs:    2,
+		maxArgs:    2,
+		permission: PERM_ADMIN,
+		handler: func(a *actor, args []string, _ stime.Time) (string, error) {
+			minutes, err := strconv.Atoi(args[1])
+			if err != nil || minutes <= 0 {
+				return "", errors.New("minutes must be a positive number")
+			}
+
+			target := a.chat.actorNamed(args[0])
+			if target == nil {
+				return "", fmt.Errorf("%s isn't in this world", args[0])
+			}
+
+			target.chatMod.mute(time.Now().Add(time.Duration(minutes) * time.Minute))
+			return fmt.Sprintf("muted %s for %d minutes", target.name, minutes), nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:       "unmute",
+		usage:      "/unmute name",
+		help:       "allows the named player to chat again",
+		minArgs:    1,
+		maxArgs:    1,
+		permission: PERM_ADMIN,
+		handler: func(a *actor, args []string, _ stime.Time) (string, error) {
+			target := a.chat.actorNamed(args[0])
+			if target == nil {
+				return "", fmt.Errorf("%s isn't in this world", args[0])
+			}
+
+			target.chatMod.mute(time.Time{})
+			return "unmuted " + target.name, nil
+		},
+	})
+
+	r.register(slashCommand{
+		name:       "announce",
+		usage:      "/announce message",
+		help:       "sends a message to every player",
+		minArgs:    1,
+		maxArgs:    1,
+		permission: PERM_ADMIN,
+		handler: func(a *actor, args []string, now stime.Time) (string, error) {
+			cmd := &chatCmd{ChatRequestType: CR_ANNOUNCE, Time: now, msg: args[0]}
+			a.sendChat(cmd, a.chat.all(), now)
+			return "", nil
+		},
+	})
+
+	return r
+}
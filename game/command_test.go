@@ -0,0 +1,212 @@
+package game
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func (a *actor) issueCommand(msg string, now stime.Time) []ActorMsg {
+	a.runSlashCommand(&chatCmd{CR_SAY, now, msg, "", 0}, now)
+	return a.deliveredMsgs()
+}
+
+// Waits for messages to be delivered to the actor by another
+// go routine. Only the inbox is read so the actor can be
+// changed by the other go routine while waiting.
+func (a *actor) awaitDeliveredMsgs() []ActorMsg {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		a.inbox.mu.Lock()
+		msgs := a.inbox.msgs
+		a.inbox.msgs = nil
+		a.inbox.mu.Unlock()
+
+		if len(msgs) > 0 {
+			return msgs
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
+func DescribeSlashCommands(c gospec.Context) {
+	c.Specify("command arguments", func() {
+		c.Specify("are split by spaces", func() {
+			c.Expect(splitCommandArgs(" bob  10 ", 2), ContainsExactly, []string{"bob", "10"})
+		})
+
+		c.Specify("end with the rest of the text", func() {
+			c.Expect(splitCommandArgs("bob hello  there", 2), ContainsExactly, []string{"bob", "hello  there"})
+		})
+
+		c.Specify("may be empty", func() {
+			c.Expect(len(splitCommandArgs("  ", 1)), Equals, 0)
+		})
+	})
+
+	c.Specify("a slash command", func() {
+		chat := newChatChannels(nil)
+
+		alice := newChatTestActor(chat, "Alice", coord.Cell{0, 0})
+		bob := newChatTestActor(chat, "Bob", coord.Cell{1, 0})
+
+		c.Specify("is only run for messages beginning with a slash", func() {
+			c.Expect(alice.runSlashCommand(&chatCmd{CR_SAY, 1, "hello", "", 0}, 1), IsFalse)
+		})
+
+		c.Specify("replies to the issuing connection", func() {
+			c.Expect(alice.issueCommand("/who", 1), ContainsExactly, []ActorMsg{
+				CommandReplyMsg{1, "/who", "2 players: Alice, Bob", false},
+			})
+		})
+
+		c.Specify("that doesn't exist fails", func() {
+			c.Expect(alice.issueCommand("/dance", 1), ContainsExactly, []ActorMsg{
+				CommandReplyMsg{1, "/dance", errUnknownCommand.Error(), true},
+			})
+		})
+
+		c.Specify("with the wrong arguments replies with its usage", func() {
+			c.Expect(alice.issueCommand("/w bob", 1), ContainsExactly, []ActorMsg{
+				CommandReplyMsg{1, "/w bob", "usage: /w name message", true},
+			})
+		})
+
+		c.Specify("can whisper", func() {
+			c.Expect(alice.issueCommand("/w Bob hi there", 1), ContainsExactly, []ActorMsg{
+				ChatMsg{1, CR_WHISPER, "Alice", "Bob", "hi there"},
+			})
+			c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatMsg{1, CR_WHISPER, "Alice", "Bob", "hi there"},
+			})
+		})
+
+		c.Specify("can emote to nearby actors", func() {
+			alice.issueCommand("/me waves", 1)
+			c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatMsg{1, CR_EMOTE, "Alice", "", "waves"},
+			})
+		})
+
+		c.Specify("can return an actor to its spawn", func() {
+			zone := newLocalZone(coord.Bounds{coord.Cell{1, -1}, coord.Cell{64, -64}}, simulation{
+				ActorIndexLocker:  NewActorIndexLocker(make(ActorIndex)),
+				RunningSimulation: &zoneTestSimulation{connected: make(map[rpg2d.Actor]bool)},
+				chat:              chat,
+			})
+			r := newZoneRouterOf([]*localZone{zone}, zone.bounds, nil, []coord.Cell{{5, -5}})
+
+			r.ConnectActor(alice)
+			defer r.RemoveActor(alice)
+			alice.actorEntity.cell = coord.Cell{30, -30}
+
+			// Is issued during the input phase of the zone
+			stuck := func(now stime.Time) []ActorMsg {
+				defer zone.sim.ActorIndexLocker.RUnlock()
+				zone.sim.ActorIndexLocker.RLock()
+				return alice.issueCommand("/stuck", now)
+			}
+
+			c.Specify("once it has been moved by its zone", func() {
+				c.Expect(len(stuck(1)), Equals, 1)
+				c.Expect(alice.awaitDeliveredMsgs(), ContainsExactly, []ActorMsg{
+					CommandReplyMsg{1, "/stuck", "you have been returned to your spawn", false},
+				})
+				c.Expect(alice.Cell(), Equals, coord.Cell{5, -5})
+
+				c.Specify("once per cooldown", func() {
+					msgs := stuck(2)
+					c.Expect(msgs[0].(CommandReplyMsg).Failed, IsTrue)
+				})
+			})
+
+			c.Specify("to the closest unblocked cell if its spawn is blocked", func() {
+				stop := make(chan struct{})
+				defer close(stop)
+
+				tickZones(stop, func(now stime.Time) {
+					zone.recordSnapshot(wallEntity{id: 20, cell: coord.Cell{5, -5}}, now)
+				})
+
+				stuck(1)
+				c.Expect(len(alice.awaitDeliveredMsgs()), Equals, 1)
+				c.Expect(alice.Cell(), Equals, coord.Cell{4, -6})
+			})
+
+			c.Specify("to the closest walkable cell if its spawn is beside water", func() {
+				terrain := make([][]rpg2d.TerrainType, zone.bounds.Height())
+				for y := range terrain {
+					terrain[y] = make([]rpg2d.TerrainType, zone.bounds.Width())
+					for x := range terrain[y] {
+						terrain[y][x] = rpg2d.TT_GRASS
+					}
+				}
+				// Water at 4,-6
+				terrain[5][3] = TT_WATER
+
+				r.terrain = terrainIndexOf(rpg2d.TerrainMap{
+					Bounds:       zone.bounds,
+					TerrainTypes: terrain,
+				})
+
+				stop := make(chan struct{})
+				defer close(stop)
+
+				tickZones(stop, func(now stime.Time) {
+					zone.recordSnapshot(wallEntity{id: 20, cell: coord.Cell{5, -5}}, now)
+				})
+
+				stuck(1)
+				c.Expect(len(alice.awaitDeliveredMsgs()), Equals, 1)
+				c.Expect(alice.Cell(), Equals, coord.Cell{4, -5})
+			})
+
+			c.Specify("to the closest cell in the world if its spawn is at the edge", func() {
+				alice.spawn = coord.Cell{1, -1}
+
+				stop := make(chan struct{})
+				defer close(stop)
+
+				tickZones(stop, func(now stime.Time) {
+					zone.recordSnapshot(wallEntity{id: 20, cell: coord.Cell{1, -1}}, now)
+				})
+
+				stuck(1)
+				c.Expect(len(alice.awaitDeliveredMsgs()), Equals, 1)
+				c.Expect(alice.Cell(), Equals, coord.Cell{1, -2})
+			})
+		})
+
+		c.Specify("for administrators", func() {
+			c.Specify("is denied to players", func() {
+				c.Expect(alice.issueCommand("/mute bob 5", 1), ContainsExactly, []ActorMsg{
+					CommandReplyMsg{1, "/mute bob 5", errPermissionDenied.Error(), true},
+				})
+				c.Expect(bob.chatMod.isMuted(), IsFalse)
+			})
+
+			c.Specify("isn't listed for players", func() {
+				reply := alice.issueCommand("/help", 1)[0].(CommandReplyMsg).Reply
+				c.Expect(strings.Contains(reply, "/mute"), IsFalse)
+				c.Expect(strings.Contains(reply, "/who"), IsTrue)
+			})
+
+			c.Specify("can be used by an administrator", func() {
+				alice.permission = PERM_ADMIN
+
+				alice.issueCommand("/mute bob 5", 1)
+				c.Expect(bob.chatMod.isMuted(), IsTrue)
+
+				alice.issueCommand("/unmute bob", 2)
+				c.Expect(bob.chatMod.isMuted(), IsFalse)
+			})
+		})
+	})
+}
@@ -2,7 +2,7 @@ package game
 
 import (
 	"fmt"
-	"log"
+	"io"
 
 	"github.com/ghthor/aodd/game/datastore"
 	"github.com/ghthor/filu/rpg2d"
@@ -41,6 +41,15 @@ const (
 	ET_REQ_MOVE
 	ET_REQ_USE
 	ET_REQ_CHAT
+
+	ET_ACTOR_MSGS
+
+	ET_REQ_BUILD
+	ET_REQ_CRAFT
+	ET_REQ_EQUIP
+	ET_REQ_EMOTE
+	ET_REQ_PARTY
+	ET_REQ_TRADE
 )
 
 type Conn interface {
@@ -53,6 +62,12 @@ type InputReceiver interface {
 	SubmitMoveRequest(MoveRequest)
 	SubmitUseRequest(UseRequest)
 	SubmitChatRequest(ChatRequest)
+	SubmitBuildRequest(BuildRequest)
+	SubmitCraftRequest(CraftRequest)
+	SubmitEquipRequest(EquipRequest)
+	SubmitEmoteRequest(EmoteRequest)
+	SubmitPartyRequest(PartyRequest)
+	SubmitTradeRequest(TradeRequest)
 
 	Close()
 }
@@ -61,7 +76,8 @@ type PreLoginConn interface {
 	HandleLogin() (LoggedInConn, error)
 }
 
-type ActorConnector func(datastore.Actor, InitialStateWriter) (InputReceiver, entity.State)
+// The logger is tagged with the connection and the actor.
+type ActorConnector func(datastore.Actor, InitialStateWriter, *Logger) (InputReceiver, entity.State)
 
 type LoggedInConn interface {
 	HandleConnect(ActorConnector) (ConnectedActorConn, error)
@@ -74,6 +90,7 @@ type ConnectedActorConn interface {
 type preLoginConn struct {
 	Conn
 	datastore datastore.Datastore
+	logger    *Logger
 }
 
 type preLoginResult struct {
@@ -98,7 +115,7 @@ func (c *preLoginResult) handleLogin() (stateFn, error) {
 		return c.handleCreateReq, nil
 
 	default:
-		log.Println("unexpected encoded type: ", eType)
+		c.logger.Warn("unexpected encoded type", "type", eType)
 	}
 
 	return c.handleLogin, nil
@@ -113,6 +130,7 @@ func (c *preLoginResult) handleLoginReq() (stateFn, error) {
 
 	actor, exists := c.datastore.ActorExists(r.Name)
 	if !exists {
+		c.logger.Info("login rejected", "name", r.Name, "reason", "actor doesn't exist")
 		err := c.EncodeAndSend(ET_RESP_ACTOR_DOESNT_EXIST, RespActorDoesntExist{
 			r.Name, r.Password,
 		})
@@ -124,6 +142,7 @@ func (c *preLoginResult) handleLoginReq() (stateFn, error) {
 	}
 
 	if !actor.CanBeConnected() {
+		c.logger.Info("login rejected", "name", r.Name, "reason", "actor is already connected")
 		err := c.EncodeAndSend(ET_RESP_ACTOR_ALREADY_CONNECTED, RespActorAlreadyConnected{actor.Name})
 		if err != nil {
 			return nil, err
@@ -133,6 +152,7 @@ func (c *preLoginResult) handleLoginReq() (stateFn, error) {
 	}
 
 	if !actor.Authenticate(r.Name, r.Password) {
+		c.logger.Info("login rejected", "name", r.Name, "reason", "authentication failed")
 		err := c.EncodeAndSend(ET_RESP_AUTH_FAILED, RespAuthFailed{r.Name})
 		if err != nil {
 			return nil, err
@@ -160,6 +180,7 @@ func (c *preLoginResult) handleCreateReq() (stateFn, error) {
 
 	_, exists := c.datastore.ActorExists(r.Name)
 	if exists {
+		c.logger.Info("create rejected", "name", r.Name, "reason", "actor exists")
 		err := c.EncodeAndSend(ET_RESP_ACTOR_EXISTS, RespActorExists{r.Name})
 		if err != nil {
 			return nil, err
@@ -199,15 +220,21 @@ func (c preLoginConn) HandleLogin() (LoggedInConn, error) {
 		return nil, err
 	}
 
+	actor := result.loggedInActor
+	logger := c.logger.With("actor", actor.Name, "id", actor.Id)
+	logger.Info("logged in")
+
 	return loggedInConn{
 		Conn:          c.Conn,
-		loggedInActor: result.loggedInActor,
+		loggedInActor: actor,
+		logger:        logger,
 	}, nil
 }
 
 type loggedInConn struct {
 	Conn
 	loggedInActor datastore.Actor
+	logger        *Logger
 }
 
 type loggedInResult struct {
@@ -220,6 +247,8 @@ type loggedInResult struct {
 type initialStateWriter struct {
 	sendState  chan<- rpg2d.WorldState
 	diffWriter <-chan DiffWriter
+
+	conn Conn
 }
 
 func (c initialStateWriter) WriteWorldState(s rpg2d.WorldState) DiffWriter {
@@ -232,6 +261,15 @@ func (c initialStateWriter) WriteWorldState(s rpg2d.WorldState) DiffWriter {
 	return <-c.diffWriter
 }
 
+// Ends the connection so the client is disconnected.
+// Does nothing if the connection can't be closed.
+func (c initialStateWriter) Close() error {
+	if conn, ok := c.conn.(io.Closer); ok {
+		return conn.Close()
+	}
+	return nil
+}
+
 func (c loggedInResult) connect(connectActor ActorConnector) (InputReceiver, entity.State, <-chan rpg2d.WorldState, chan<- DiffWriter) {
 	initialStateCh := make(chan rpg2d.WorldState)
 	diffWriterCh := make(chan DiffWriter)
@@ -239,7 +277,9 @@ func (c loggedInResult) connect(connectActor ActorConnector) (InputReceiver, ent
 	actor, entity := connectActor(c.loggedInActor, initialStateWriter{
 		sendState:  initialStateCh,
 		diffWriter: diffWriterCh,
-	})
+
+		conn: c.Conn,
+	}, c.logger)
 	return actor, entity, initialStateCh, diffWriterCh
 }
 
@@ -268,6 +308,7 @@ func (c *loggedInResult) handleConnect(connectActor ActorConnector) stateFn {
 
 		if <-c.loggedInActor.IsConnected {
 			c.loggedInActor.IsConnected <- true
+			c.logger.Info("connect rejected", "reason", "actor is already connected")
 
 			err := c.EncodeAndSend(ET_RESP_ACTOR_ALREADY_CONNECTED, RespActorAlreadyConnected{c.loggedInActor.Name})
 			if err != nil {
@@ -290,11 +331,13 @@ func (c *loggedInResult) handleConnect(connectActor ActorConnector) stateFn {
 		}
 
 		c.loggedInActor.IsConnected <- true
+		c.logger.Info("actor connected")
 
 		c.connectedConn = connectedConn{
 			Conn:           c.Conn,
 			connectedActor: c.loggedInActor,
 			actor:          actor,
+			logger:         c.logger,
 		}
 
 		diffWriter <- c.connectedConn
@@ -326,7 +369,8 @@ type connectedConn struct {
 
 	connectedActor datastore.Actor
 
-	actor InputReceiver
+	actor  InputReceiver
+	logger *Logger
 }
 
 func (c *connectedConn) handleInputReq() (stateFn, error) {
@@ -345,6 +389,18 @@ func (c *connectedConn) handleInputReq() (stateFn, error) {
 		return c.handleUseReq, nil
 	case ET_REQ_CHAT:
 		return c.handleChatReq, nil
+	case ET_REQ_BUILD:
+		return c.handleBuildReq, nil
+	case ET_REQ_CRAFT:
+		return c.handleCraftReq, nil
+	case ET_REQ_EQUIP:
+		return c.handleEquipReq, nil
+	case ET_REQ_EMOTE:
+		return c.handleEmoteReq, nil
+	case ET_REQ_PARTY:
+		return c.handlePartyReq, nil
+	case ET_REQ_TRADE:
+		return c.handleTradeReq, nil
 	}
 
 	return c.handleInputReq, nil
@@ -383,12 +439,87 @@ func (c *connectedConn) handleChatReq() (stateFn, error) {
 	return c.handleInputReq, nil
 }
 
+func (c *connectedConn) handleBuildReq() (stateFn, error) {
+	var r BuildRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitBuildRequest(r)
+	return c.handleInputReq, nil
+}
+
+func (c *connectedConn) handleCraftReq() (stateFn, error) {
+	var r CraftRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitCraftRequest(r)
+	return c.handleInputReq, nil
+}
+
+func (c *connectedConn) handleEquipReq() (stateFn, error) {
+	var r EquipRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitEquipRequest(r)
+	return c.handleInputReq, nil
+}
+
+func (c *connectedConn) handleEmoteReq() (stateFn, error) {
+	var r EmoteRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitEmoteRequest(r)
+	return c.handleInputReq, nil
+}
+
+func (c *connectedConn) handlePartyReq() (stateFn, error) {
+	var r PartyRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitPartyRequest(r)
+	return c.handleInputReq, nil
+}
+
+func (c *connectedConn) handleTradeReq() (stateFn, error) {
+	var r TradeRequest
+	err := c.Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+
+	c.actor.SubmitTradeRequest(r)
+	return c.handleInputReq, nil
+}
+
+// Write errors are only logged. The connection is closed
+// by the conn if the client doesn't read in time and the
+// actor is disconnected once reading has failed.
 func (c connectedConn) WriteWorldStateDiff(s rpg2d.WorldStateDiff) {
-	// TODO Handle this potentional write error
-	// TODO This Write needs to timeout to avoid Denial-Of-Service attacks
-	//      by misbehaving clients that never read of the frames we send them.
-	//      Causes a deadlock during writes.
-	c.EncodeAndSend(ET_WORLD_STATE_DIFF, s)
+	err := c.EncodeAndSend(ET_WORLD_STATE_DIFF, s)
+	if err != nil {
+		c.logger.Debug("error writing world state diff", "tick", s.Time, "err", err)
+	}
+}
+
+func (c connectedConn) WriteActorMsgs(msgs ActorMsgs) {
+	err := c.EncodeAndSend(ET_ACTOR_MSGS, msgs)
+	if err != nil {
+		c.logger.Debug("error writing actor msgs", "err", err)
+	}
 }
 
 func (c connectedConn) HandleIO() (err error) {
@@ -398,6 +529,7 @@ func (c connectedConn) HandleIO() (err error) {
 	}
 
 	c.actor.Close()
+	c.logger.Info("actor disconnected", "err", err)
 
 	if <-c.connectedActor.IsConnected {
 		c.connectedActor.IsConnected <- false
@@ -406,10 +538,13 @@ func (c connectedConn) HandleIO() (err error) {
 	return
 }
 
-func NewPreLoginConn(conn Conn, ds datastore.Datastore) PreLoginConn {
+// The logger should be tagged with the connection.
+// A nil logger writes to stderr at the info level.
+func NewPreLoginConn(conn Conn, ds datastore.Datastore, logger *Logger) PreLoginConn {
 	return preLoginConn{
 		Conn:      conn,
 		datastore: ds,
+		logger:    logger,
 	}
 }
 
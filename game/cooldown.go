@@ -0,0 +1,122 @@
+package game
+
+import (
+	"sort"
+
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Stores when each skill an actor has used will
+// be available to be used again.
+type cooldownTable map[string]stime.Time
+
+// Returns the duration of the skill's cooldown in frames.
+func cooldownOf(skill string) stime.Time {
+	switch skill {
+	case "assail":
+		return assailCooldown
+	case "charge":
+		return chargeCooldown
+	case "gather":
+		return gatherCooldown
+	case "stuck":
+		return stuckCooldown
+	}
+
+	if s, exists := aoeSkills[skill]; exists {
+		return s.cooldown
+	}
+
+	if s, exists := channelSkills[skill]; exists {
+		return s.cooldown
+	}
+
+	return 0
+}
+
+func (t cooldownTable) isReady(skill string, now stime.Time) bool {
+	return t[skill] <= now
+}
+
+// Starts the cooldown of the skill. Expired cooldowns
+// are removed from the table.
+func (t cooldownTable) start(skill string, now stime.Time) {
+	for s, readyAt := range t {
+		if readyAt <= now {
+			delete(t, s)
+		}
+	}
+
+	t[skill] = now + cooldownOf(skill)
+}
+
+type Cooldown struct {
+	Skill   string     `json:"skill"`
+	ReadyAt stime.Time `json:"readyAt"`
+}
+
+// Sent to an actor's connection every time
+// one of the actor's cooldowns has started.
+type CooldownsMsg struct {
+	Time      stime.Time `json:"time"`
+	Cooldowns []Cooldown `json:"cooldowns"`
+}
+
+func (t cooldownTable) toMsg(now stime.Time) CooldownsMsg {
+	cooldowns := make([]Cooldown, 0, len(t))
+	for skill, readyAt := range t {
+		if readyAt > now {
+			cooldowns = append(cooldowns, Cooldown{skill, readyAt})
+		}
+	}
+
+	sort.Slice(cooldowns, func(i, j int) bool {
+		return cooldowns[i].Skill < cooldowns[j].Skill
+	})
+
+	return CooldownsMsg{now, cooldowns}
+}
+
+type UseRejectedReason int
+
+//go:generate stringer -type=UseRejectedReason
+const (
+	URR_ERROR UseRejectedReason = iota
+	URR_UNKNOWN_SKILL
+	URR_COOLDOWN
+	URR_MOVING
+	URR_NO_NODE
+	URR_DEPLETED
+)
+
+// Sent to an actor's connection when a use
+// request could not be performed.
+type UseRejectedMsg struct {
+	Time   stime.Time        `json:"time"`
+	Skill  string            `json:"skill"`
+	Reason UseRejectedReason `json:"reason"`
+}
+
+// Starts the cooldown of the skill and sends the
+// actor's updated cooldown table to its connection.
+func (a *actor) startCooldown(skill string, now stime.Time) {
+	if a.cooldowns == nil {
+		a.cooldowns = make(cooldownTable)
+	}
+
+	a.cooldowns.start(skill, now)
+	a.queueMsg(a.cooldowns.toMsg(now))
+}
+
+// Sends the reason a use command was rejected to the
+// actor's connection. A use command is repeated every
+// tick until it is canceled or replaced so the rejection
+// is only sent once for each command.
+func (a *actor) rejectUseCmd(cmd *useCmd, reason UseRejectedReason, now stime.Time) {
+	if a.rejectedUseCmd == cmd {
+		return
+	}
+
+	a.rejectedUseCmd = cmd
+	a.queueMsg(UseRejectedMsg{now, cmd.skill, reason})
+}
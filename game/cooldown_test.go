@@ -0,0 +1,80 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeCooldowns(c gospec.Context) {
+	c.Specify("a cooldown table", func() {
+		t := make(cooldownTable)
+
+		c.Specify("is ready for skills that haven't been used", func() {
+			c.Expect(t.isReady("assail", 0), IsTrue)
+		})
+
+		c.Specify("is not ready until the cooldown has expired", func() {
+			t.start("assail", 10)
+			c.Expect(t.isReady("assail", 10+assailCooldown-1), Not(IsTrue))
+			c.Expect(t.isReady("assail", 10+assailCooldown), IsTrue)
+		})
+
+		c.Specify("removes expired cooldowns", func() {
+			t.start("assail", 0)
+			t.start("lance", assailCooldown)
+			c.Expect(len(t), Equals, 1)
+		})
+
+		c.Specify("is sent in skill order", func() {
+			t.start("lance", 0)
+			t.start("assail", 0)
+
+			c.Expect(t.toMsg(0).Cooldowns, ContainsExactly, []Cooldown{
+				{"assail", assailCooldown},
+				{"lance", cooldownOf("lance")},
+			})
+		})
+	})
+
+	c.Specify("an actor", func() {
+		a := &actor{
+			id: 0,
+			actorEntity: actorEntity{
+				id:      0,
+				actorId: 0,
+				cell:    cell(0, 0),
+				facing:  coord.North,
+			},
+		}
+
+		c.Specify("sends its cooldowns when one has started", func() {
+			a.startCooldown("cleave", 5)
+			c.Assume(len(a.msgs), Equals, 1)
+
+			msg, isCooldowns := a.msgs[0].(CooldownsMsg)
+			c.Assume(isCooldowns, IsTrue)
+			c.Expect(msg.Cooldowns, ContainsExactly, []Cooldown{
+				{"cleave", 5 + cooldownOf("cleave")},
+			})
+		})
+
+		c.Specify("sends a rejected use command once", func() {
+			cmd := &useCmd{skill: "cleave"}
+			for now := stime.Time(0); now < 3; now++ {
+				a.rejectUseCmd(cmd, URR_COOLDOWN, now)
+			}
+
+			c.Expect(a.msgs, ContainsExactly, []ActorMsg{
+				UseRejectedMsg{0, "cleave", URR_COOLDOWN},
+			})
+
+			c.Specify("unless the command is replaced", func() {
+				a.rejectUseCmd(&useCmd{skill: "cleave"}, URR_COOLDOWN, 3)
+				c.Expect(len(a.msgs), Equals, 2)
+			})
+		})
+	})
+}
@@ -0,0 +1,198 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// A recipe transforms a set of resources
+// in an actor's inventory into an item.
+type recipe struct {
+	inputs, outputs Resources
+
+	// In frames
+	craftTime stime.Time
+
+	// The type of entity the actor must be adjacent
+	// to while crafting. Empty if the recipe can
+	// be crafted anywhere.
+	workstation string
+}
+
+var recipes = map[string]recipe{
+	"torch": {
+		inputs:  Resources{"wood": 1},
+		outputs: Resources{"torch": 1},
+
+		// 0.5s
+		craftTime: 20,
+	},
+
+	"boots": {
+		inputs:  Resources{"wood": 2},
+		outputs: Resources{"boots": 1},
+
+		// 1s
+		craftTime: 40,
+	},
+
+	"sword": {
+		inputs:  Resources{"stone": 3, "wood": 1},
+		outputs: Resources{"sword": 1},
+
+		// 2s
+		craftTime:   40 * 2,
+		workstation: "structure",
+	},
+
+	"armor": {
+		inputs:  Resources{"stone": 5, "wood": 2},
+		outputs: Resources{"armor": 1},
+
+		// 3s
+		craftTime:   40 * 3,
+		workstation: "structure",
+	},
+}
+
+type CraftRequest struct {
+	stime.Time
+	Recipe string
+}
+
+func newCraftRequest(timeIssued stime.Time, params string) (CraftRequest, error) {
+	if _, exists := recipes[params]; !exists {
+		return CraftRequest{}, fmt.Errorf("unknown recipe: %s", params)
+	}
+
+	return CraftRequest{timeIssued, params}, nil
+}
+
+type craftCmd struct {
+	stime.Time
+	recipe string
+}
+
+// An item an actor is crafting. The recipe's inputs are
+// removed from the actor's inventory when the craft begins
+// and the outputs are added when it has completed.
+type craftAction struct {
+	recipe     string
+	start, end stime.Time
+}
+
+type CraftRejectedReason int
+
+//go:generate stringer -type=CraftRejectedReason
+const (
+	CRR_ERROR CraftRejectedReason = iota
+	CRR_UNKNOWN_RECIPE
+	CRR_CRAFTING
+	CRR_RESOURCES
+	CRR_NO_WORKSTATION
+)
+
+// Sent to an actor's connection when a
+// craft request could not be performed.
+type CraftRejectedMsg struct {
+	Time   stime.Time          `json:"time"`
+	Recipe string              `json:"recipe"`
+	Reason CraftRejectedReason `json:"reason"`
+}
+
+// Sent to an actor's connection when a craft has
+// begun and again when it has completed.
+type CraftMsg struct {
+	Time   stime.Time `json:"time"`
+	Recipe string     `json:"recipe"`
+
+	Start stime.Time `json:"start"`
+	End   stime.Time `json:"end"`
+
+	Completed bool `json:"completed"`
+}
+
+func (a *actor) ReadCraftCmd() *craftCmd {
+	return <-a.readCraftCmd
+}
+
+func (a *actor) rejectCraftCmd(cmd *craftCmd, reason CraftRejectedReason, now stime.Time) {
+	a.queueMsg(CraftRejectedMsg{now, cmd.recipe, reason})
+}
+
+// Returns true if the actor is adjacent to an
+// entity of the type required by the recipe.
+func (phase inputPhase) isNearWorkstation(a *actor, workstation string) bool {
+	if workstation == "" {
+		return true
+	}
+
+	if phase.edits == nil {
+		return false
+	}
+
+	for _, dir := range []coord.Direction{coord.North, coord.East, coord.South, coord.West} {
+		c := a.Cell().Neighbor(dir)
+
+		switch workstation {
+		case "structure":
+			if _, exists := phase.edits.structureAt(c); exists {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Completes the actor's craft if it has finished and then
+// validates and begins any craft the actor has requested.
+// An actor can only craft one item at a time.
+func (phase inputPhase) processCraftCmd(a *actor, now stime.Time) {
+	if a.craft != nil && a.craft.end <= now {
+		craft := *a.craft
+		a.craft = nil
+
+		a.resources.add(recipes[craft.recipe].outputs)
+		a.queueMsg(CraftMsg{now, craft.recipe, craft.start, craft.end, true})
+		a.sendResources(now)
+		a.recordLegend(datastore.LE_ITEM_CRAFTED, craft.recipe)
+	}
+
+	cmd := a.ReadCraftCmd()
+	if cmd == nil {
+		return
+	}
+
+	r, exists := recipes[cmd.recipe]
+	switch {
+	case !exists:
+		a.rejectCraftCmd(cmd, CRR_UNKNOWN_RECIPE, now)
+		return
+
+	case a.craft != nil:
+		a.rejectCraftCmd(cmd, CRR_CRAFTING, now)
+		return
+
+	case !a.resources.has(r.inputs):
+		a.rejectCraftCmd(cmd, CRR_RESOURCES, now)
+		return
+
+	case !phase.isNearWorkstation(a, r.workstation):
+		a.rejectCraftCmd(cmd, CRR_NO_WORKSTATION, now)
+		return
+	}
+
+	a.resources.spend(r.inputs)
+	a.craft = &craftAction{
+		recipe: cmd.recipe,
+		start:  now,
+		end:    now + r.craftTime,
+	}
+
+	a.queueMsg(CraftMsg{now, cmd.recipe, a.craft.start, a.craft.end, false})
+	a.sendResources(now)
+}
@@ -0,0 +1,183 @@
+package game
+
+import (
+	"sync"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeCrafting(c gospec.Context) {
+	c.Specify("a craft request", func() {
+		c.Specify("includes the recipe", func() {
+			r, err := newCraftRequest(1, "sword")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, CraftRequest{1, "sword"})
+		})
+
+		c.Specify("is invalid for a recipe that doesn't exist", func() {
+			_, err := newCraftRequest(1, "cake")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("an actor crafting", func() {
+		terrain := terrainIndexOf(rpg2d.TerrainMap{
+			Bounds: coord.Bounds{cell(-1, 1), cell(1, -1)},
+			TerrainTypes: [][]rpg2d.TerrainType{
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+				{rpg2d.TT_GRASS, rpg2d.TT_GRASS, rpg2d.TT_GRASS},
+			},
+		})
+		edits := newWorldEdits(terrain)
+
+		newCrafter := func(name string, resources Resources) (*actor, chan *craftCmd) {
+			cmds := make(chan *craftCmd, 1)
+			a := &actor{
+				actorEntity: actorEntity{
+					name: name,
+					cell: cell(0, 0),
+				},
+				resources: resources,
+			}
+			a.readCraftCmd = cmds
+			return a, cmds
+		}
+
+		a, cmds := newCrafter("crafter", Resources{"wood": 3, "stone": 3})
+
+		phase := inputPhase{ActorIndex{0: a}, entity.NewIdGenerator(), terrain, edits, nil}
+
+		process := func(cmd *craftCmd, now stime.Time) {
+			a.msgs = nil
+			cmds <- cmd
+			phase.processCraftCmd(a, now)
+		}
+
+		rejectedWith := func() CraftRejectedReason {
+			for _, msg := range a.msgs {
+				if msg, isRejected := msg.(CraftRejectedMsg); isRejected {
+					return msg.Reason
+				}
+			}
+			return CRR_ERROR
+		}
+
+		c.Specify("spends the inputs when the craft begins", func() {
+			process(&craftCmd{1, "boots"}, 1)
+			c.Assume(a.craft, Not(IsNil))
+			c.Expect(a.craft.end, Equals, 1+recipes["boots"].craftTime)
+			c.Expect(a.resources["wood"], Equals, 1)
+			c.Expect(a.resources["boots"], Equals, 0)
+
+			c.Specify("and receives the outputs when it completes", func() {
+				process(nil, recipes["boots"].craftTime)
+				c.Expect(a.resources["boots"], Equals, 0)
+
+				process(nil, 1+recipes["boots"].craftTime)
+				c.Expect(a.craft, IsNil)
+				c.Expect(a.resources["boots"], Equals, 1)
+
+				var craft CraftMsg
+				for _, msg := range a.msgs {
+					if msg, isCraft := msg.(CraftMsg); isCraft {
+						craft = msg
+					}
+				}
+				c.Expect(craft.Completed, IsTrue)
+				c.Expect(craft.Recipe, Equals, "boots")
+			})
+
+			c.Specify("and can't begin another craft until it has completed", func() {
+				process(&craftCmd{2, "torch"}, 2)
+				c.Expect(rejectedWith(), Equals, CRR_CRAFTING)
+				c.Expect(a.craft.recipe, Equals, "boots")
+				c.Expect(a.resources["wood"], Equals, 1)
+
+				c.Specify("but can after", func() {
+					process(&craftCmd{3, "torch"}, 1+recipes["boots"].craftTime)
+					c.Assume(a.craft, Not(IsNil))
+					c.Expect(a.craft.recipe, Equals, "torch")
+					c.Expect(a.resources["boots"], Equals, 1)
+					c.Expect(a.resources["wood"], Equals, 0)
+				})
+			})
+		})
+
+		c.Specify("can't craft", func() {
+			c.Specify("a recipe that doesn't exist", func() {
+				process(&craftCmd{1, "cake"}, 1)
+				c.Expect(rejectedWith(), Equals, CRR_UNKNOWN_RECIPE)
+			})
+
+			c.Specify("without enough materials", func() {
+				a.resources = Resources{"wood": 1}
+				process(&craftCmd{1, "boots"}, 1)
+				c.Expect(rejectedWith(), Equals, CRR_RESOURCES)
+				c.Expect(a.craft, IsNil)
+				c.Expect(a.resources["wood"], Equals, 1)
+			})
+
+			c.Specify("without a workstation", func() {
+				process(&craftCmd{1, "sword"}, 1)
+				c.Expect(rejectedWith(), Equals, CRR_NO_WORKSTATION)
+				c.Expect(a.resources["stone"], Equals, 3)
+			})
+		})
+
+		c.Specify("can craft next to a workstation", func() {
+			edits.build(structureEntity{id: 1, cell: cell(1, 0), builtBy: "builder"})
+			process(&craftCmd{1, "sword"}, 1)
+			c.Assume(a.craft, Not(IsNil))
+			c.Expect(a.craft.recipe, Equals, "sword")
+		})
+
+		c.Specify("at the same time as other actors", func() {
+			crafters := make([]*actor, 0, 8)
+			crafterCmds := make([]chan *craftCmd, 0, 8)
+			for i := 0; i < 8; i++ {
+				a, cmds := newCrafter("crafter", Resources{"wood": 2})
+				crafters = append(crafters, a)
+				crafterCmds = append(crafterCmds, cmds)
+			}
+
+			craftAll := func(cmd *craftCmd, now stime.Time) {
+				var wg sync.WaitGroup
+				for i, a := range crafters {
+					wg.Add(1)
+					go func(a *actor, cmds chan *craftCmd) {
+						defer wg.Done()
+						cmds <- cmd
+						phase.processCraftCmd(a, now)
+					}(a, crafterCmds[i])
+				}
+				wg.Wait()
+			}
+
+			craftAll(&craftCmd{1, "boots"}, 1)
+			craftAll(nil, 1+recipes["boots"].craftTime)
+
+			for _, a := range crafters {
+				c.Expect(a.resources["wood"], Equals, 0)
+				c.Expect(a.resources["boots"], Equals, 1)
+			}
+
+			c.Specify("with each actor only spending their own materials", func() {
+				craftAll(&craftCmd{2, "torch"}, 2+recipes["boots"].craftTime)
+
+				for _, a := range crafters {
+					c.Expect(a.craft, IsNil)
+					c.Expect(a.msgs[len(a.msgs)-1], Equals, CraftRejectedMsg{
+						2 + recipes["boots"].craftTime, "torch", CRR_RESOURCES,
+					})
+				}
+			})
+		})
+	})
+}
@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=CraftRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[CRR_ERROR-0]
+	_ = x[CRR_UNKNOWN_RECIPE-1]
+	_ = x[CRR_CRAFTING-2]
+	_ = x[CRR_RESOURCES-3]
+	_ = x[CRR_NO_WORKSTATION-4]
+}
+
+const _CraftRejectedReason_name = "CRR_ERRORCRR_UNKNOWN_RECIPECRR_CRAFTINGCRR_RESOURCESCRR_NO_WORKSTATION"
+
+var _CraftRejectedReason_index = [...]uint8{0, 9, 27, 39, 52, 70}
+
+func (i CraftRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_CraftRejectedReason_index)-1 {
+		return "CraftRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _CraftRejectedReason_name[_CraftRejectedReason_index[idx]:_CraftRejectedReason_index[idx+1]]
+}
@@ -0,0 +1,207 @@
+package datastore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// The power a character serves. The world is contested
+// by the benevolent Big Good's and the malevolent Big Bad's.
+type Allegiance string
+
+const (
+	AL_NONE Allegiance = ""
+	AL_GOOD Allegiance = "good"
+	AL_BAD  Allegiance = "bad"
+)
+
+// A guild of characters that serve the same power.
+type Guild struct {
+	Name       string     `json:"name"`
+	Allegiance Allegiance `json:"allegiance"`
+
+	// Name of the character that founded the guild
+	Founder string `json:"founder"`
+}
+
+// A character's allegiance, guild and
+// standing with the power it serves.
+type FactionMember struct {
+	Actor      string     `json:"actor"`
+	Allegiance Allegiance `json:"allegiance"`
+
+	// Empty if the character isn't in a guild
+	Guild string `json:"guild,omitempty"`
+
+	Standing int `json:"standing"`
+}
+
+// The behavior required to store the factions characters
+// belong to. Factions are stored separately from the world
+// so they survive the world being reset.
+type FactionStore interface {
+	// Returns a member without an allegiance
+	// for characters that have never pledged.
+	FactionMember(actor string) (FactionMember, error)
+
+	// Replaces the member that is stored.
+	SaveFactionMember(FactionMember) error
+
+	Guilds() ([]Guild, error)
+
+	// Replaces the guild with the same name.
+	// Guild names are case insensitive.
+	SaveGuild(Guild) error
+}
+
+// The contents of a faction store
+type factions struct {
+	Members map[string]FactionMember `json:"members"`
+
+	// Indexed by the lower case name of the guild
+	Guilds map[string]Guild `json:"guilds"`
+}
+
+func newFactions() factions {
+	return factions{
+		Members: make(map[string]FactionMember),
+		Guilds:  make(map[string]Guild),
+	}
+}
+
+func (f factions) member(actor string) FactionMember {
+	m, exists := f.Members[actor]
+	if !exists {
+		m.Actor = actor
+	}
+	return m
+}
+
+func (f factions) guilds() []Guild {
+	guilds := make([]Guild, 0, len(f.Guilds))
+	for _, g := range f.Guilds {
+		guilds = append(guilds, g)
+	}
+	return guilds
+}
+
+type memFactionStore struct {
+	lock sync.Mutex
+	factions
+}
+
+// An implementation of the FactionStore interface that
+// will store the factions in memory. Is safe for concurrency.
+// Factions will be lost if process closes.
+func NewMemFactionStore() FactionStore {
+	return &memFactionStore{factions: newFactions()}
+}
+
+func (s *memFactionStore) FactionMember(actor string) (FactionMember, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.member(actor), nil
+}
+
+func (s *memFactionStore) SaveFactionMember(m FactionMember) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Members[m.Actor] = m
+	return nil
+}
+
+func (s *memFactionStore) Guilds() ([]Guild, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.guilds(), nil
+}
+
+func (s *memFactionStore) SaveGuild(g Guild) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.factions.Guilds[strings.ToLower(g.Name)] = g
+	return nil
+}
+
+type fileFactionStore struct {
+	lock sync.Mutex
+	path string
+}
+
+// An implementation of the FactionStore interface that
+// will store the factions as json in a file. Is safe
+// for concurrency. A file that doesn't exist is loaded
+// as if no character has ever pledged.
+func NewFileFactionStore(path string) FactionStore {
+	return &fileFactionStore{path: path}
+}
+
+// Must be called with the lock held.
+func (s *fileFactionStore) load() (factions, error) {
+	f := newFactions()
+
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+
+	if err != nil {
+		return f, err
+	}
+
+	err = json.Unmarshal(b, &f)
+	return f, err
+}
+
+func (s *fileFactionStore) FactionMember(actor string) (FactionMember, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.load()
+	if err != nil {
+		return FactionMember{Actor: actor}, err
+	}
+
+	return f.member(actor), nil
+}
+
+func (s *fileFactionStore) SaveFactionMember(m FactionMember) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	f.Members[m.Actor] = m
+	return writeJSON(s.path, f)
+}
+
+func (s *fileFactionStore) Guilds() ([]Guild, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return f.guilds(), nil
+}
+
+func (s *fileFactionStore) SaveGuild(g Guild) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	f, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	f.Guilds[strings.ToLower(g.Name)] = g
+	return writeJSON(s.path, f)
+}
@@ -0,0 +1,90 @@
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileFactionStoreLoadsNothingIfNeverSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileFactionStore(filepath.Join(dir, "factions.json"))
+
+	m, err := store.FactionMember("actor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m != (FactionMember{Actor: "actor"}) {
+		t.Errorf("unexpected member: %v", m)
+	}
+
+	guilds, err := store.Guilds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(guilds) != 0 {
+		t.Fail()
+	}
+}
+
+func TestFileFactionStoreLoadsWhatWasSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "factions.json")
+
+	member := FactionMember{Actor: "actor", Allegiance: AL_GOOD, Guild: "Dawn", Standing: 10}
+	guild := Guild{Name: "Dawn", Allegiance: AL_GOOD, Founder: "actor"}
+
+	err = NewFileFactionStore(path).SaveFactionMember(member)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = NewFileFactionStore(path).SaveGuild(guild)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileFactionStore(path)
+
+	m, err := store.FactionMember("actor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m != member {
+		t.Errorf("unexpected member: %v", m)
+	}
+
+	guilds, err := store.Guilds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(guilds) != 1 || guilds[0] != guild {
+		t.Errorf("unexpected guilds: %v", guilds)
+	}
+}
+
+func TestMemFactionStoreGuildNamesAreCaseInsensitive(t *testing.T) {
+	store := NewMemFactionStore()
+	store.SaveGuild(Guild{Name: "Dawn", Allegiance: AL_GOOD})
+	store.SaveGuild(Guild{Name: "dawn", Allegiance: AL_BAD})
+
+	guilds, _ := store.Guilds()
+	if len(guilds) != 1 || guilds[0].Allegiance != AL_BAD {
+		t.Errorf("unexpected guilds: %v", guilds)
+	}
+}
@@ -0,0 +1,150 @@
+package datastore
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+type LegendEventType string
+
+const (
+	LE_KILL            LegendEventType = "kill"
+	LE_DEATH           LegendEventType = "death"
+	LE_ITEM_CRAFTED    LegendEventType = "itemCrafted"
+	LE_STRUCTURE_BUILT LegendEventType = "structureBuilt"
+	LE_TIME_PLAYED     LegendEventType = "timePlayed"
+)
+
+// Something a character has done that is
+// remembered in the character's legend.
+type LegendEvent struct {
+	// Name of the character
+	Actor string `json:"actor"`
+
+	// The generation of the world the event happened in
+	Epoch int `json:"epoch"`
+
+	Type LegendEventType `json:"type"`
+	At   time.Time       `json:"at"`
+
+	// The character that was killed or killed the actor,
+	// or the item that was crafted.
+	Subject string `json:"subject,omitempty"`
+
+	// Time played during a session
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// The behavior required to store the history of every
+// character. Legends are never modified or removed,
+// events are only ever appended to them. A legend is
+// stored separately from the world so it survives the
+// world being reset.
+type LegendStore interface {
+	AppendLegendEvent(LegendEvent) error
+
+	// Returns the events in the order they were appended.
+	// An empty legend is returned for unknown characters.
+	Legend(actor string) ([]LegendEvent, error)
+}
+
+type memLegendStore struct {
+	lock    sync.Mutex
+	legends map[string][]LegendEvent
+}
+
+// An implementation of the LegendStore interface that
+// will store the legends in memory. Is safe for concurrency.
+// Legends will be lost if process closes.
+func NewMemLegendStore() LegendStore {
+	return &memLegendStore{
+		legends: make(map[string][]LegendEvent),
+	}
+}
+
+func (s *memLegendStore) AppendLegendEvent(e LegendEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.legends[e.Actor] = append(s.legends[e.Actor], e)
+	return nil
+}
+
+func (s *memLegendStore) Legend(actor string) ([]LegendEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	legend := make([]LegendEvent, len(s.legends[actor]))
+	copy(legend, s.legends[actor])
+	return legend, nil
+}
+
+type fileLegendStore struct {
+	lock sync.Mutex
+	path string
+}
+
+// An implementation of the LegendStore interface that
+// appends each event as a line of json to a file. Is
+// safe for concurrency. A file that doesn't exist is
+// loaded as if no events have been appended.
+func NewFileLegendStore(path string) LegendStore {
+	return &fileLegendStore{path: path}
+}
+
+func (s *fileLegendStore) AppendLegendEvent(e LegendEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(b, '\n'))
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *fileLegendStore) Legend(actor string) ([]LegendEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	legend := []LegendEvent{}
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return legend, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e LegendEvent
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			return nil, err
+		}
+
+		if e.Actor == actor {
+			legend = append(legend, e)
+		}
+	}
+
+	return legend, scanner.Err()
+}
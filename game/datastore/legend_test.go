@@ -0,0 +1,78 @@
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLegendStoreLoadsNothingIfNeverAppended(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileLegendStore(filepath.Join(dir, "legend.json"))
+
+	legend, err := store.Legend("actor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(legend) != 0 {
+		t.Fail()
+	}
+}
+
+func TestFileLegendStoreLoadsEventsInOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "legend.json")
+	at := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	events := []LegendEvent{
+		{Actor: "actor", Type: LE_KILL, At: at, Subject: "other"},
+		{Actor: "other", Type: LE_DEATH, At: at, Subject: "actor"},
+		{Actor: "actor", Type: LE_TIME_PLAYED, At: at.Add(time.Minute), Duration: time.Minute},
+	}
+
+	for _, e := range events {
+		err := NewFileLegendStore(path).AppendLegendEvent(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	legend, err := NewFileLegendStore(path).Legend("actor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(legend) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(legend))
+	}
+
+	if legend[0] != events[0] || legend[1] != events[2] {
+		t.Errorf("unexpected legend: %v", legend)
+	}
+}
+
+func TestMemLegendStoreLegendIsACopy(t *testing.T) {
+	store := NewMemLegendStore()
+	store.AppendLegendEvent(LegendEvent{Actor: "actor", Type: LE_KILL})
+
+	legend, _ := store.Legend("actor")
+	legend[0].Type = LE_DEATH
+
+	legend, _ = store.Legend("actor")
+	if legend[0].Type != LE_KILL {
+		t.Fail()
+	}
+}
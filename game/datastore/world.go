@@ -0,0 +1,218 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+)
+
+// A cell of terrain that has been sculpted by a player.
+type TerrainEdit struct {
+	Cell coord.Cell
+	Type rpg2d.TerrainType
+}
+
+// A structure that has been built by a player.
+type Structure struct {
+	Cell coord.Cell
+
+	// Name of the actor that built the structure
+	BuiltBy string
+}
+
+// The changes players have made to the world.
+type WorldEdits struct {
+	// The generation of the world the edits were made to
+	Epoch int
+
+	Terrain    []TerrainEdit
+	Structures []Structure
+}
+
+// The final state of a world that has ended.
+type WorldArchive struct {
+	Epoch   int
+	EndedAt time.Time
+	Reason  string
+
+	// The entities of the world as of its last tick. The
+	// types of the entity states must be registered with
+	// gob to be stored in a file.
+	State rpg2d.WorldState
+
+	Edits WorldEdits
+}
+
+var ErrWorldNotArchived = errors.New("world hasn't been archived")
+
+// The behavior required to store the changes players
+// have made to the world so they survive restarts.
+type WorldStore interface {
+	// Returns empty edits if nothing has been saved.
+	LoadWorldEdits() (WorldEdits, error)
+
+	// Replaces the edits that are stored.
+	SaveWorldEdits(WorldEdits) error
+
+	// Stores the final state of a world that has ended.
+	ArchiveWorld(WorldArchive) error
+
+	// Can return ErrWorldNotArchived if the
+	// world of the epoch hasn't ended.
+	LoadWorldArchive(epoch int) (WorldArchive, error)
+}
+
+type memWorldStore struct {
+	lock     sync.Mutex
+	edits    WorldEdits
+	archives map[int]WorldArchive
+}
+
+// An implementation of the WorldStore interface that
+// will store the edits in memory. Is safe for concurrency.
+// Edits will be lost if process closes.
+func NewMemWorldStore() WorldStore {
+	return &memWorldStore{
+		archives: make(map[int]WorldArchive),
+	}
+}
+
+func (s *memWorldStore) LoadWorldEdits() (WorldEdits, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.edits, nil
+}
+
+func (s *memWorldStore) SaveWorldEdits(edits WorldEdits) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.edits = edits
+	return nil
+}
+
+func (s *memWorldStore) ArchiveWorld(archive WorldArchive) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.archives[archive.Epoch] = archive
+	return nil
+}
+
+func (s *memWorldStore) LoadWorldArchive(epoch int) (WorldArchive, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	archive, exists := s.archives[epoch]
+	if !exists {
+		return archive, ErrWorldNotArchived
+	}
+
+	return archive, nil
+}
+
+type fileWorldStore struct {
+	lock sync.Mutex
+	path string
+}
+
+// An implementation of the WorldStore interface that
+// will store the edits as json in a file. Is safe
+// for concurrency. A file that doesn't exist is
+// loaded as if no edits have been made.
+func NewFileWorldStore(path string) WorldStore {
+	return &fileWorldStore{path: path}
+}
+
+func (s *fileWorldStore) LoadWorldEdits() (WorldEdits, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var edits WorldEdits
+
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return edits, nil
+	}
+
+	if err != nil {
+		return edits, err
+	}
+
+	err = json.Unmarshal(b, &edits)
+	return edits, err
+}
+
+func (s *fileWorldStore) SaveWorldEdits(edits WorldEdits) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return writeJSON(s.path, edits)
+}
+
+// Archives are stored next to the edits in a file
+// suffixed with the epoch of the world. They're gob
+// encoded because the entity states are interfaces
+// that can't be decoded from json.
+func (s *fileWorldStore) archivePath(epoch int) string {
+	return fmt.Sprintf("%s.epoch-%d", s.path, epoch)
+}
+
+func (s *fileWorldStore) ArchiveWorld(archive WorldArchive) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(archive)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(s.archivePath(archive.Epoch), b.Bytes())
+}
+
+func (s *fileWorldStore) LoadWorldArchive(epoch int) (WorldArchive, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var archive WorldArchive
+
+	b, err := ioutil.ReadFile(s.archivePath(epoch))
+	if os.IsNotExist(err) {
+		return archive, ErrWorldNotArchived
+	}
+
+	if err != nil {
+		return archive, err
+	}
+
+	err = gob.NewDecoder(bytes.NewReader(b)).Decode(&archive)
+	return archive, err
+}
+
+func writeJSON(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, b)
+}
+
+func writeFile(path string, b []byte) error {
+	// Write to a temporary file first so a crash
+	// while saving can't corrupt the stored file.
+	tmp := path + ".tmp"
+	err := ioutil.WriteFile(tmp, b, 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,62 @@
+package datastore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+)
+
+func TestFileWorldStoreLoadsNothingIfNeverSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := NewFileWorldStore(filepath.Join(dir, "world.json"))
+
+	edits, err := store.LoadWorldEdits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits.Terrain) != 0 || len(edits.Structures) != 0 {
+		t.Fail()
+	}
+}
+
+func TestFileWorldStoreLoadsWhatWasSaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aodd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "world.json")
+
+	err = NewFileWorldStore(path).SaveWorldEdits(WorldEdits{
+		Terrain:    []TerrainEdit{{coord.Cell{1, -2}, rpg2d.TT_ROCK}},
+		Structures: []Structure{{coord.Cell{3, -4}, "builder"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store simulates the server being restarted
+	edits, err := NewFileWorldStore(path).LoadWorldEdits()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(edits.Terrain) != 1 || edits.Terrain[0] != (TerrainEdit{coord.Cell{1, -2}, rpg2d.TT_ROCK}) {
+		t.Errorf("unexpected terrain %v", edits.Terrain)
+	}
+
+	if len(edits.Structures) != 1 || edits.Structures[0] != (Structure{coord.Cell{3, -4}, "builder"}) {
+		t.Errorf("unexpected structures %v", edits.Structures)
+	}
+}
@@ -0,0 +1,109 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/ghthor/filu/sim/stime"
+)
+
+type emote struct {
+	// Frames the animation plays for. 0 plays
+	// the animation until the actor moves.
+	duration stime.Time
+
+	// Sent to the actors nearby as an emote chat message
+	text string
+}
+
+var emotes = map[string]emote{
+	"wave":  {2 * 40, "waves"},
+	"bow":   {3 * 40 / 2, "bows"},
+	"cheer": {2 * 40, "cheers"},
+	"dance": {0, "dances"},
+	"sit":   {0, "sits down"},
+}
+
+// An emote the actor is animating. Interrupted
+// if the actor moves or begins casting a skill.
+type animationAction struct {
+	name string
+
+	// End is 0 if the animation loops until interrupted
+	start, end stime.Time
+}
+
+type AnimationState struct {
+	Name string `json:"name"`
+
+	Start stime.Time `json:"start"`
+	End   stime.Time `json:"end"`
+}
+
+func (a animationAction) ToState() AnimationState {
+	return AnimationState{
+		Name: a.name,
+
+		Start: a.start,
+		End:   a.end,
+	}
+}
+
+type EmoteRequest struct {
+	stime.Time
+	Emote string
+}
+
+// Params are the name of the emote.
+func newEmoteRequest(timeIssued stime.Time, params string) (EmoteRequest, error) {
+	if _, exists := emotes[params]; !exists {
+		return EmoteRequest{}, fmt.Errorf("unknown emote: %s", params)
+	}
+
+	return EmoteRequest{timeIssued, params}, nil
+}
+
+type emoteCmd struct {
+	stime.Time
+	emote string
+}
+
+func (a *actor) ReadEmoteCmd() *emoteCmd {
+	return <-a.readEmoteCmd
+}
+
+// Starts the emote's animation and describes
+// it to the actors that can see it.
+func (phase inputPhase) processEmoteCmd(a *actor, now stime.Time) {
+	cmd := a.ReadEmoteCmd()
+	if cmd == nil {
+		return
+	}
+
+	emote, exists := emotes[cmd.emote]
+	if !exists || a.pathAction != nil || a.cast != nil {
+		return
+	}
+
+	a.animation = &animationAction{name: cmd.emote, start: now}
+	if emote.duration > 0 {
+		a.animation.end = now + emote.duration
+	}
+
+	// The animation is played even if the description is rate limited
+	if a.chatRate.allow(now) {
+		chat := &chatCmd{ChatRequestType: CR_EMOTE, Time: now, msg: emote.text}
+		a.sendChat(chat, a.chat.actorsNear(a.Cell(), emoteRadius), now)
+	}
+}
+
+// Stops the actor's animation if it has finished
+// or the actor has begun moving or casting.
+func (e *actorEntity) updateAnimation(now stime.Time) {
+	switch {
+	case e.animation == nil:
+	case e.pathAction != nil, e.cast != nil:
+		e.animation = nil
+	case e.animation.end != 0 && e.animation.end <= now:
+		e.animation = nil
+	}
+}
@@ -0,0 +1,91 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeEmotes(c gospec.Context) {
+	c.Specify("an emote request", func() {
+		c.Specify("includes the name of the emote", func() {
+			r, err := newEmoteRequest(1, "wave")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, EmoteRequest{1, "wave"})
+		})
+
+		c.Specify("is invalid for an unknown emote", func() {
+			_, err := newEmoteRequest(1, "juggle")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("an actor emoting", func() {
+		chat := newChatChannels(nil)
+		alice := newChatTestActor(chat, "Alice", coord.Cell{0, 0})
+		bob := newChatTestActor(chat, "Bob", coord.Cell{1, 0})
+
+		cmds := make(chan *emoteCmd, 1)
+		alice.readEmoteCmd = cmds
+
+		phase := inputPhase{ActorIndex{0: alice}, entity.NewIdGenerator(), nil, nil, nil}
+
+		process := func(cmd emoteCmd, now stime.Time) {
+			cmds <- &cmd
+			phase.processEmoteCmd(alice, now)
+		}
+
+		c.Specify("animates the emote", func() {
+			process(emoteCmd{1, "wave"}, 1)
+			c.Assume(alice.animation, Not(IsNil))
+			c.Expect(*alice.animation, Equals, animationAction{"wave", 1, 1 + emotes["wave"].duration})
+
+			c.Specify("which is included in its state", func() {
+				state := alice.ToState().(ActorEntityState)
+				c.Assume(state.Animation, Not(IsNil))
+				c.Expect(state.Animation.Name, Equals, "wave")
+
+				c.Specify("and makes it different", func() {
+					alice.animation = nil
+					c.Expect(state.IsDifferentFrom(alice.ToState()), IsTrue)
+				})
+			})
+
+			c.Specify("until it has finished", func() {
+				alice.updateAnimation(emotes["wave"].duration)
+				c.Expect(alice.animation, Not(IsNil))
+
+				alice.updateAnimation(1 + emotes["wave"].duration)
+				c.Expect(alice.animation, IsNil)
+			})
+
+			c.Specify("and describes it to nearby actors", func() {
+				c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatMsg{1, CR_EMOTE, "Alice", "", "waves"},
+				})
+			})
+		})
+
+		c.Specify("loops an emote without a duration", func() {
+			process(emoteCmd{1, "sit"}, 1)
+			alice.updateAnimation(1000)
+			c.Expect(alice.animation, Not(IsNil))
+
+			c.Specify("until the actor moves", func() {
+				pathAction := pa(1000, 20, cell(0, 0), cell(0, 1))
+				alice.pathAction = &pathAction
+				alice.updateAnimation(1001)
+				c.Expect(alice.animation, IsNil)
+			})
+		})
+
+		c.Specify("can't emote while moving", func() {
+			alice.pathAction = &coord.PathAction{}
+			process(emoteCmd{1, "bow"}, 1)
+			c.Expect(alice.animation, IsNil)
+		})
+	})
+}
@@ -25,15 +25,23 @@ func _() {
 	_ = x[ET_REQ_MOVE-14]
 	_ = x[ET_REQ_USE-15]
 	_ = x[ET_REQ_CHAT-16]
+	_ = x[ET_ACTOR_MSGS-17]
+	_ = x[ET_REQ_BUILD-18]
+	_ = x[ET_REQ_CRAFT-19]
+	_ = x[ET_REQ_EQUIP-20]
+	_ = x[ET_REQ_EMOTE-21]
+	_ = x[ET_REQ_PARTY-22]
+	_ = x[ET_REQ_TRADE-23]
 }
 
-const _EncodedType_name = "ET_ERRORET_DISCONNECTET_REQ_LOGINET_REQ_CREATEET_RESP_ACTOR_ALREADY_CONNECTEDET_RESP_AUTH_FAILEDET_RESP_ACTOR_EXISTSET_RESP_ACTOR_DOESNT_EXISTET_RESP_LOGIN_SUCCESSET_RESP_CREATE_SUCCESSET_REQ_CONNECTET_CONNECTEDET_WORLD_STATEET_WORLD_STATE_DIFFET_REQ_MOVEET_REQ_USEET_REQ_CHAT"
+const _EncodedType_name = "ET_ERRORET_DISCONNECTET_REQ_LOGINET_REQ_CREATEET_RESP_ACTOR_ALREADY_CONNECTEDET_RESP_AUTH_FAILEDET_RESP_ACTOR_EXISTSET_RESP_ACTOR_DOESNT_EXISTET_RESP_LOGIN_SUCCESSET_RESP_CREATE_SUCCESSET_REQ_CONNECTET_CONNECTEDET_WORLD_STATEET_WORLD_STATE_DIFFET_REQ_MOVEET_REQ_USEET_REQ_CHATET_ACTOR_MSGSET_REQ_BUILDET_REQ_CRAFTET_REQ_EQUIPET_REQ_EMOTEET_REQ_PARTYET_REQ_TRADE"
 
-var _EncodedType_index = [...]uint16{0, 8, 21, 33, 46, 77, 96, 116, 142, 163, 185, 199, 211, 225, 244, 255, 265, 276}
+var _EncodedType_index = [...]uint16{0, 8, 21, 33, 46, 77, 96, 116, 142, 163, 185, 199, 211, 225, 244, 255, 265, 276, 289, 301, 313, 325, 337, 349, 361}
 
 func (i EncodedType) String() string {
-	if i < 0 || i >= EncodedType(len(_EncodedType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_EncodedType_index)-1 {
 		return "EncodedType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _EncodedType_name[_EncodedType_index[i]:_EncodedType_index[i+1]]
+	return _EncodedType_name[_EncodedType_index[idx]:_EncodedType_index[idx+1]]
 }
@@ -0,0 +1,427 @@
+package game
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Describes the world a shard will simulate
+// before any changes have been made by players.
+type WorldDef struct {
+	Bounds  coord.Bounds
+	Terrain string
+
+	// Used instead of the Terrain string if non-nil.
+	// Large worlds should use a source so the terrain
+	// of the world is never entirely in memory.
+	TerrainSource TerrainSource
+
+	// The maximum number of entities in a node of the
+	// quad tree before it is divided. Uses quadMaxSize
+	// if 0.
+	QuadMaxSize int
+
+	Walls         []coord.Cell
+	Npcs          []NpcSpawn
+	ResourceNodes []ResourceNodeSpawn
+
+	// Cells actors are spawned at. If empty
+	// actors are spawned at the origin.
+	Spawns []coord.Cell
+
+	// The width and height in cells of the zones the world
+	// is divided into. Each zone is a separate simulation.
+	// The world is a single zone if 0.
+	ZoneSize int
+
+	// Allows actors to damage the members of their party and
+	// the actors that serve the same power with assails and
+	// skills that damage friendly actors.
+	FriendlyFire bool
+}
+
+// Returns the definition of the world that will be
+// simulated during an epoch. Is called each time
+// a world has ended to generate the next one.
+type WorldGenerator func(epoch int) (WorldDef, error)
+
+// Returns a generator that always creates the walled arena.
+func ArenaWorld(npcs []NpcSpawn, nodes []ResourceNodeSpawn) WorldGenerator {
+	return func(int) (WorldDef, error) {
+		return WorldDef{
+			Bounds: coord.Bounds{
+				coord.Cell{1, -1},
+				coord.Cell{128, -128},
+			},
+			Terrain: startingTerrain,
+
+			Walls:         ArenaWalls,
+			Npcs:          npcs,
+			ResourceNodes: nodes,
+			Spawns:        []coord.Cell{origin},
+		}, nil
+	}
+}
+
+// Sent to every actor's connection when the world has
+// ended. The actor is no longer part of any simulation
+// and must reconnect to join the next world.
+type WorldEndedMsg struct {
+	// The time of the last state sent to the actor
+	Time stime.Time `json:"time"`
+
+	// The epoch of the world that has ended
+	Epoch  int    `json:"epoch"`
+	Reason string `json:"reason"`
+}
+
+// How often terrain chunks that haven't been used are unloaded.
+const terrainUnloadPeriod = 30 * time.Second
+
+// How long the zones have to tick before the
+// world ends without its final state.
+const worldEndSnapshotTimeout = time.Second
+
+// The state of a single world simulated by a shard.
+type shardWorld struct {
+	epoch int
+
+	bounds coord.Bounds
+	zones  *zoneRouter
+	nextId func() entity.Id
+
+	// When the simulation of the world began
+	beganAt time.Time
+
+	edits  *worldEdits
+	legend *legendRecorder
+	npcs   *npcSpawner
+
+	// Tagged with the epoch of the world
+	logger *Logger
+
+	// Closed to stop unloading unused terrain
+	stopUnloading chan struct{}
+
+	// Done when the edits and legend have been saved
+	// and the terrain is no longer being unloaded
+	saving sync.WaitGroup
+}
+
+// Returns a generator of entity ids that is safe to call
+// from every zone's simulation and connection concurrently.
+func newEntityIdGenerator() func() entity.Id {
+	var last int64
+	return func() entity.Id {
+		return entity.Id(atomic.AddInt64(&last, 1))
+	}
+}
+
+// Begins simulating a world. The edits will be applied
+// to the world before the simulation begins.
+func beginWorld(def WorldDef, saved datastore.WorldEdits, worldStore datastore.WorldStore, legendStore datastore.LegendStore, chatFilter ChatFilter, logger *Logger) (*shardWorld, error) {
+	logger = logger.With("epoch", saved.Epoch)
+
+	maxSize := def.QuadMaxSize
+	if maxSize == 0 {
+		maxSize = quadMaxSize
+	}
+
+	quadTree, err := quad.New(def.Bounds, maxSize, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Actors are sent terrain sliced from the index so the
+	// simulation is only given the terrain of the whole
+	// world if it's defined by the Terrain string.
+	var terrain *terrainIndex
+	terrainMap := rpg2d.TerrainMap{Bounds: def.Bounds}
+
+	if def.TerrainSource != nil {
+		terrain = newTerrainIndex(def.Bounds, def.TerrainSource)
+	} else {
+		terrainMap, err = rpg2d.NewTerrainMap(def.Bounds, def.Terrain)
+		if err != nil {
+			return nil, err
+		}
+
+		terrain = terrainIndexOf(terrainMap)
+	}
+
+	// Ids are unique across every zone of the world
+	entityIdGen := newEntityIdGenerator()
+
+	quadTree = addWalls(quadTree, def.Walls, entityIdGen)
+
+	quadTree, nodes, err := addResourceNodes(quadTree, def.ResourceNodes, entityIdGen)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := newWorldEdits(terrain)
+	edits.logger = logger
+	quadTree = edits.load(saved, quadTree, entityIdGen)
+
+	legend := newLegendRecorder(saved.Epoch)
+	legend.logger = logger
+
+	zones, err := newZoneRouter(zoneWorld{
+		def:     def,
+		maxSize: maxSize,
+
+		quadTree:   quadTree,
+		terrainMap: terrainMap,
+		terrain:    terrain,
+		edits:      edits,
+		nodes:      nodes,
+		legend:     legend,
+		chat:       newChatChannels(chatFilter),
+		parties:    newPartyTable(),
+		nextId:     entityIdGen,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	w := &shardWorld{
+		epoch:   saved.Epoch,
+		beganAt: time.Now(),
+
+		bounds: def.Bounds,
+		zones:  zones,
+		nextId: entityIdGen,
+
+		edits:  edits,
+		legend: legend,
+		logger: logger,
+
+		stopUnloading: make(chan struct{}),
+	}
+
+	w.saving.Add(3)
+	go func() {
+		defer w.saving.Done()
+		edits.saveTo(worldStore)
+	}()
+	go func() {
+		defer w.saving.Done()
+		w.legend.saveTo(legendStore)
+	}()
+	go func() {
+		defer w.saving.Done()
+		terrain.unloadUnusedEvery(terrainUnloadPeriod, w.stopUnloading)
+	}()
+
+	// Store the epoch of the world even if
+	// players never make any changes to it.
+	edits.hasChanged()
+
+	w.npcs = newNpcSpawner(zones, entityIdGen)
+
+	return w, nil
+}
+
+// Halts the world's simulation, sends the message to every
+// actor controlled by a connection and disconnects them so
+// they can connect to the next world. Returns the final
+// state of the world and the changes players made to it
+// once they are saved.
+func (w *shardWorld) end(msg WorldEndedMsg) (datastore.WorldArchive, error) {
+	// The state is taken while the zones are still ticking
+	state, err := w.zones.snapshot(w.bounds, worldEndSnapshotTimeout)
+	if err != nil {
+		w.logger.Warn("error taking the final state of the world", "err", err)
+	}
+
+	w.npcs.stopAll()
+
+	actors, err := w.zones.halt()
+	if err != nil {
+		return datastore.WorldArchive{}, err
+	}
+
+	for _, a := range actors {
+		// Actors that haven't received the initial world
+		// state have no connection to send a message to.
+		if a.hasLegend() && a.initialState != nil {
+			msg.Time = a.lastWorldState().Time
+			a.sendMsgs <- []ActorMsg{msg}
+		}
+
+		a.stopIO()
+		a.closeConn()
+		a.recordTimePlayed()
+	}
+
+	close(w.edits.changed)
+	w.legend.stop()
+	close(w.stopUnloading)
+	w.saving.Wait()
+
+	return datastore.WorldArchive{
+		Epoch:   w.epoch,
+		EndedAt: time.Now(),
+		Reason:  msg.Reason,
+
+		State: state,
+		Edits: w.edits.snapshot(),
+	}, nil
+}
+
+// Owns the world that is currently being simulated and
+// replaces it with a new world when it has ended.
+type shard struct {
+	mu    sync.RWMutex
+	world *shardWorld
+
+	newWorld    WorldGenerator
+	worldStore  datastore.WorldStore
+	legendStore datastore.LegendStore
+	chatFilter  ChatFilter
+
+	// Guilds and the membership of actors
+	// are shared by every world.
+	factionStore datastore.FactionStore
+	factions     *factionTable
+
+	// Indexed by the lower case names of actors that have
+	// more permissions than players. Isn't changed once
+	// the shard has been created.
+	permissions map[string]Permission
+
+	logger *Logger
+}
+
+// Begins simulating the world the edits were saved from.
+func newShard(newWorld WorldGenerator, worldStore datastore.WorldStore, legendStore datastore.LegendStore, factionStore datastore.FactionStore, chatFilter ChatFilter, logger *Logger) (*shard, error) {
+	saved, err := worldStore.LoadWorldEdits()
+	if err != nil {
+		return nil, err
+	}
+
+	guilds, err := factionStore.Guilds()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &shard{
+		newWorld:     newWorld,
+		worldStore:   worldStore,
+		legendStore:  legendStore,
+		chatFilter:   chatFilter,
+		factionStore: factionStore,
+		factions:     newFactionTable(guilds),
+		permissions:  make(map[string]Permission),
+		logger:       logger,
+	}
+
+	s.factions.logger = logger
+
+	go s.factions.saveTo(factionStore)
+
+	err = s.begin(saved)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Must be called with the lock held.
+func (s *shard) begin(saved datastore.WorldEdits) error {
+	def, err := s.newWorld(saved.Epoch)
+	if err != nil {
+		return err
+	}
+
+	w, err := beginWorld(def, saved, s.worldStore, s.legendStore, s.chatFilter, s.logger)
+	if err != nil {
+		return err
+	}
+
+	w.npcs.endWorld = func(reason string) {
+		s.endWorld(w.epoch, reason)
+	}
+	w.npcs.spawnAll(def.Npcs)
+
+	s.world = w
+	return nil
+}
+
+// Connects an actor to the current world. The returned
+// func will remove the actor from the world unless the
+// world has ended since the actor was connected. The
+// logger should be tagged with the actor's connection.
+func (s *shard) connect(dsactor datastore.Actor, stateWriter InitialStateWriter, logger *Logger) (*actor, func()) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w := s.world
+	a := NewActor(w.nextId(), dsactor, stateWriter)
+	a.permission = s.permissions[strings.ToLower(dsactor.Name)]
+	a.logger = logger.With("epoch", w.epoch)
+
+	m, err := s.factionStore.FactionMember(dsactor.Name)
+	if err != nil {
+		a.logger.Error("error loading faction", "err", err)
+	}
+
+	a.factions = s.factions
+	a.joinFaction(m)
+	a.sendFaction(0)
+
+	w.zones.ConnectActor(a)
+
+	return a, func() {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+
+		if s.world != w {
+			return
+		}
+
+		w.zones.RemoveActor(a)
+		a.logger.Info("actor removed", "tick", w.now())
+	}
+}
+
+// Ends the world of the epoch, archives its final state
+// and begins simulating a newly generated world. Does
+// nothing if the world of the epoch has already ended.
+func (s *shard) endWorld(epoch int, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w := s.world
+	if w.epoch != epoch {
+		return
+	}
+
+	w.logger.Info("world has ended", "tick", w.now(), "reason", reason)
+
+	archive, err := w.end(WorldEndedMsg{Epoch: w.epoch, Reason: reason})
+	if err != nil {
+		w.logger.Error("error ending world", "err", err)
+		return
+	}
+
+	err = s.worldStore.ArchiveWorld(archive)
+	if err != nil {
+		w.logger.Error("error archiving world", "err", err)
+	}
+
+	err = s.begin(datastore.WorldEdits{Epoch: w.epoch + 1})
+	if err != nil {
+		s.logger.Error("error beginning world", "epoch", w.epoch+1, "err", err)
+	}
+}
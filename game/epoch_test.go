@@ -0,0 +1,166 @@
+package game
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+// A simulation that accepts actors without simulating them.
+type idleSimulation struct {
+	rpg2d.RunningSimulation
+}
+
+func (idleSimulation) ConnectActor(rpg2d.Actor) {}
+func (idleSimulation) RemoveActor(rpg2d.Actor)  {}
+
+func DescribeWorldEpochs(c gospec.Context) {
+	c.Specify("the arena world", func() {
+		def, err := ArenaWorld(ArenaNpcs, ArenaResourceNodes)(1)
+		c.Assume(err, IsNil)
+
+		c.Specify("is enclosed by walls", func() {
+			c.Expect(len(def.Walls), Equals, len(ArenaWalls))
+			c.Expect(def.Bounds.Contains(coord.Cell{30, -30}), IsTrue)
+		})
+
+		c.Specify("has an npc that ends the world", func() {
+			endsWorld := 0
+			for _, n := range def.Npcs {
+				if n.EndsWorld {
+					endsWorld++
+					c.Expect(n.RespawnDelay < 0, IsTrue)
+				}
+			}
+			c.Expect(endsWorld, Equals, 1)
+		})
+	})
+
+	c.Specify("entity ids", func() {
+		nextId := newEntityIdGenerator()
+
+		c.Specify("are unique when generated concurrently", func() {
+			idCh := make(chan entity.Id)
+			for i := 0; i < 4; i++ {
+				go func() {
+					for j := 0; j < 100; j++ {
+						idCh <- nextId()
+					}
+				}()
+			}
+
+			ids := make(map[entity.Id]bool)
+			for i := 0; i < 400; i++ {
+				ids[<-idCh] = true
+			}
+			c.Expect(len(ids), Equals, 400)
+		})
+	})
+
+	c.Specify("world edits", func() {
+		edits := newWorldEdits(nil)
+		edits.load(datastore.WorldEdits{Epoch: 3}, nil, entity.NewIdGenerator())
+
+		c.Specify("are saved with the epoch they were loaded from", func() {
+			c.Expect(edits.snapshot().Epoch, Equals, 3)
+		})
+	})
+
+	c.Specify("a world archive", func() {
+		dir, err := ioutil.TempDir("", "aodd")
+		c.Assume(err, IsNil)
+		defer os.RemoveAll(dir)
+
+		store := datastore.NewFileWorldStore(filepath.Join(dir, "world.json"))
+		err = store.ArchiveWorld(datastore.WorldArchive{
+			Epoch: 4,
+			State: rpg2d.WorldState{
+				Time:     10,
+				Entities: entity.StateSlice{ActorEntityState{Id: 1, Name: "actor"}},
+			},
+		})
+		c.Assume(err, IsNil)
+
+		c.Specify("is stored with the final state of the world", func() {
+			archive, err := store.LoadWorldArchive(4)
+			c.Assume(err, IsNil)
+			c.Expect(archive.State.Time, Equals, stime.Time(10))
+			c.Assume(len(archive.State.Entities), Equals, 1)
+			c.Expect(archive.State.Entities[0], Equals, ActorEntityState{Id: 1, Name: "actor"})
+		})
+	})
+
+	c.Specify("legend events", func() {
+		store := datastore.NewMemLegendStore()
+		legend := newLegendRecorder(2)
+
+		a := &actor{legend: legend}
+		a.name = "actor"
+		a.recordLegend(datastore.LE_ITEM_CRAFTED, "torch")
+
+		legend.stop()
+		legend.saveTo(store)
+
+		c.Specify("are recorded with the epoch of the world", func() {
+			l, err := store.Legend("actor")
+			c.Assume(err, IsNil)
+			c.Assume(len(l), Equals, 1)
+			c.Expect(l[0].Epoch, Equals, 2)
+		})
+	})
+
+	c.Specify("an npc spawner", func() {
+		ended := make(chan string, 1)
+
+		spawner := newNpcSpawner(idleSimulation{}, entity.NewIdGenerator())
+		spawner.endWorld = func(reason string) { ended <- reason }
+		defer spawner.stopAll()
+
+		c.Specify("ends the world when an npc that ends it has died", func() {
+			spawner.spawn(-1, NpcSpawn{
+				Name:         "boss",
+				Behavior:     NpcGuard{},
+				RespawnDelay: -1,
+				EndsWorld:    true,
+			})
+
+			c.Assume(len(spawner.npcs), Equals, 1)
+			for _, n := range spawner.npcs {
+				n.actor.onDeath()
+			}
+
+			c.Expect(<-ended, Equals, "boss has been slain")
+		})
+
+		c.Specify("that has stopped", func() {
+			spawner.spawn(-1, NpcSpawn{Name: "npc", Behavior: NpcGuard{}})
+			c.Assume(len(spawner.npcs), Equals, 1)
+
+			var n *npc
+			for _, spawned := range spawner.npcs {
+				n = spawned
+			}
+
+			spawner.stopAll()
+
+			c.Specify("has closed its npcs", func() {
+				_, open := <-n.stop
+				c.Expect(open, Not(IsTrue))
+			})
+
+			c.Specify("won't spawn any more npcs", func() {
+				spawner.spawn(-2, NpcSpawn{Name: "npc", Behavior: NpcGuard{}})
+				c.Expect(len(spawner.npcs), Equals, 1)
+			})
+		})
+	})
+}
@@ -0,0 +1,216 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ghthor/filu/sim/stime"
+)
+
+type EquipmentSlot int
+
+//go:generate stringer -type=EquipmentSlot
+const (
+	ES_WEAPON EquipmentSlot = iota
+	ES_ARMOR
+	ES_FEET
+	ES_OFFHAND
+	ES_SIZE
+)
+
+// The item equipped in each slot. An empty
+// string means nothing is equipped in the slot.
+type Equipment [ES_SIZE]string
+
+// Modifiers that are applied to the
+// stats of the actor equipping an item.
+type itemStats struct {
+	damage, defense int
+
+	// Frames added to the time it takes the actor
+	// to move a cell. Negative values are faster.
+	speed int
+
+	hpMax int
+}
+
+type item struct {
+	slot EquipmentSlot
+	itemStats
+}
+
+var items = map[string]item{
+	"sword": {ES_WEAPON, itemStats{damage: 10}},
+	"torch": {ES_OFFHAND, itemStats{damage: 2}},
+	"armor": {ES_ARMOR, itemStats{defense: 5, speed: 2, hpMax: 25}},
+	"boots": {ES_FEET, itemStats{speed: -3}},
+}
+
+const (
+	assailDamage = 25
+	baseHpMax    = 100
+)
+
+// Returns the sum of the stats of all the equipped items.
+func (e Equipment) stats() (stats itemStats) {
+	for _, name := range e {
+		item := items[name]
+		stats.damage += item.damage
+		stats.defense += item.defense
+		stats.speed += item.speed
+		stats.hpMax += item.hpMax
+	}
+	return
+}
+
+func (e actorEntity) assailDamage() int {
+	return assailDamage + e.equipment.stats().damage
+}
+
+// Returns the damage the actor takes after it
+// has been reduced by the actor's defense.
+func (e actorEntity) mitigate(damage int) int {
+	damage -= e.equipment.stats().defense
+	if damage < 0 {
+		return 0
+	}
+	return damage
+}
+
+// Returns the actor's speed when it isn't charging.
+func (e actorEntity) baseSpeed() int {
+	speed := baseSpeed + e.equipment.stats().speed
+	if speed < chargeSpeed {
+		return chargeSpeed
+	}
+	return speed
+}
+
+// Recalculates the actor's stats after its equipment has changed.
+func (a *actor) applyEquipment(now stime.Time) {
+	a.hpMax = baseHpMax + a.equipment.stats().hpMax
+	if a.hp > a.hpMax {
+		a.hp = a.hpMax
+	}
+
+	if a.lastStartedCharge+chargeDuration <= now {
+		a.speed = a.baseSpeed()
+	}
+}
+
+type EquipRequest struct {
+	stime.Time
+	Slot EquipmentSlot
+
+	// Empty to unequip the slot
+	Item string
+}
+
+// Params are formatted as "slot,item" or "slot" to unequip
+// the slot. The slot is one of "weapon", "armor", "feet"
+// or "offhand".
+func newEquipRequest(timeIssued stime.Time, params string) (EquipRequest, error) {
+	parts := strings.SplitN(params, ",", 2)
+
+	slotName, item := parts[0], ""
+	if len(parts) == 2 {
+		item = parts[1]
+	}
+
+	var slot EquipmentSlot
+	switch slotName {
+	case "weapon":
+		slot = ES_WEAPON
+	case "armor":
+		slot = ES_ARMOR
+	case "feet":
+		slot = ES_FEET
+	case "offhand":
+		slot = ES_OFFHAND
+	default:
+		return EquipRequest{}, fmt.Errorf("unknown equipment slot: %s", slotName)
+	}
+
+	if item != "" {
+		if i, exists := items[item]; !exists || i.slot != slot {
+			return EquipRequest{}, fmt.Errorf("can't equip %s as %s", item, slotName)
+		}
+	}
+
+	return EquipRequest{timeIssued, slot, item}, nil
+}
+
+type equipCmd struct {
+	stime.Time
+	slot EquipmentSlot
+	item string
+}
+
+type EquipRejectedReason int
+
+//go:generate stringer -type=EquipRejectedReason
+const (
+	ERR_ERROR EquipRejectedReason = iota
+	ERR_UNKNOWN_ITEM
+	ERR_WRONG_SLOT
+	ERR_NOT_OWNED
+)
+
+// Sent to an actor's connection when an
+// equip request could not be performed.
+type EquipRejectedMsg struct {
+	Time   stime.Time          `json:"time"`
+	Slot   EquipmentSlot       `json:"slot"`
+	Item   string              `json:"item"`
+	Reason EquipRejectedReason `json:"reason"`
+}
+
+func (a *actor) ReadEquipCmd() *equipCmd {
+	return <-a.readEquipCmd
+}
+
+func (a *actor) rejectEquipCmd(cmd *equipCmd, reason EquipRejectedReason, now stime.Time) {
+	a.queueMsg(EquipRejectedMsg{now, cmd.slot, cmd.item, reason})
+}
+
+// Moves an item from the actor's inventory into the slot.
+// The item previously in the slot is returned to the
+// actor's inventory.
+func (phase inputPhase) processEquipCmd(a *actor, now stime.Time) {
+	cmd := a.ReadEquipCmd()
+	if cmd == nil {
+		return
+	}
+
+	if cmd.slot < 0 || cmd.slot >= ES_SIZE {
+		a.rejectEquipCmd(cmd, ERR_WRONG_SLOT, now)
+		return
+	}
+
+	if cmd.item != "" {
+		item, exists := items[cmd.item]
+		switch {
+		case !exists:
+			a.rejectEquipCmd(cmd, ERR_UNKNOWN_ITEM, now)
+			return
+
+		case item.slot != cmd.slot:
+			a.rejectEquipCmd(cmd, ERR_WRONG_SLOT, now)
+			return
+
+		case !a.resources.has(Resources{cmd.item: 1}):
+			a.rejectEquipCmd(cmd, ERR_NOT_OWNED, now)
+			return
+		}
+
+		a.resources.spend(Resources{cmd.item: 1})
+	}
+
+	if prev := a.equipment[cmd.slot]; prev != "" {
+		a.resources.add(Resources{prev: 1})
+	}
+
+	a.equipment[cmd.slot] = cmd.item
+	a.applyEquipment(now)
+	a.sendResources(now)
+}
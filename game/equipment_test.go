@@ -0,0 +1,142 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeEquipment(c gospec.Context) {
+	c.Specify("an equip request", func() {
+		c.Specify("includes the slot and item", func() {
+			r, err := newEquipRequest(1, "weapon,sword")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, EquipRequest{1, ES_WEAPON, "sword"})
+		})
+
+		c.Specify("without an item unequips the slot", func() {
+			r, err := newEquipRequest(1, "armor")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, EquipRequest{1, ES_ARMOR, ""})
+		})
+
+		c.Specify("is invalid for an item that doesn't fit the slot", func() {
+			_, err := newEquipRequest(1, "feet,sword")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("an actor equipping items", func() {
+		cmds := make(chan *equipCmd, 1)
+		a := &actor{
+			actorEntity: actorEntity{
+				cell:   cell(0, 0),
+				facing: coord.North,
+				speed:  baseSpeed,
+
+				lastStartedCharge: -chargeDuration,
+
+				hp:    baseHpMax,
+				hpMax: baseHpMax,
+			},
+			resources: Resources{"sword": 1, "armor": 1, "boots": 1},
+		}
+		a.readEquipCmd = cmds
+
+		phase := inputPhase{ActorIndex{0: a}, entity.NewIdGenerator(), nil, nil, nil}
+
+		process := func(cmd equipCmd, now stime.Time) {
+			a.msgs = nil
+			cmds <- &cmd
+			phase.processEquipCmd(a, now)
+		}
+
+		rejectedWith := func() EquipRejectedReason {
+			for _, msg := range a.msgs {
+				if msg, isRejected := msg.(EquipRejectedMsg); isRejected {
+					return msg.Reason
+				}
+			}
+			return ERR_ERROR
+		}
+
+		c.Specify("moves the item out of the actor's inventory", func() {
+			process(equipCmd{1, ES_WEAPON, "sword"}, 1)
+			c.Expect(a.equipment[ES_WEAPON], Equals, "sword")
+			c.Expect(a.resources["sword"], Equals, 0)
+
+			c.Specify("and back when it is unequipped", func() {
+				process(equipCmd{2, ES_WEAPON, ""}, 2)
+				c.Expect(a.equipment[ES_WEAPON], Equals, "")
+				c.Expect(a.resources["sword"], Equals, 1)
+			})
+		})
+
+		c.Specify("is visible in the actor's state", func() {
+			process(equipCmd{1, ES_ARMOR, "armor"}, 1)
+			state := a.ToState().(ActorEntityState)
+			c.Expect(state.Equipment[ES_ARMOR], Equals, "armor")
+
+			c.Specify("and changes the state", func() {
+				process(equipCmd{2, ES_ARMOR, ""}, 2)
+				c.Expect(state.IsDifferentFrom(a.ToState()), IsTrue)
+			})
+		})
+
+		c.Specify("with a weapon does more damage", func() {
+			c.Expect(a.assailDamage(), Equals, assailDamage)
+			process(equipCmd{1, ES_WEAPON, "sword"}, 1)
+			c.Expect(a.assailDamage(), Equals, assailDamage+items["sword"].damage)
+		})
+
+		c.Specify("with armor", func() {
+			process(equipCmd{1, ES_ARMOR, "armor"}, 1)
+
+			c.Specify("takes less damage", func() {
+				c.Expect(a.mitigate(25), Equals, 25-items["armor"].defense)
+				c.Expect(a.mitigate(1), Equals, 0)
+			})
+
+			c.Specify("has more max hp", func() {
+				c.Expect(a.hpMax, Equals, baseHpMax+items["armor"].hpMax)
+			})
+
+			c.Specify("moves slower", func() {
+				c.Expect(a.speed, Equals, baseSpeed+items["armor"].speed)
+			})
+
+			c.Specify("loses the max hp when it is unequipped", func() {
+				a.hp = a.hpMax
+				process(equipCmd{2, ES_ARMOR, ""}, 2)
+				c.Expect(a.hpMax, Equals, baseHpMax)
+				c.Expect(a.hp, Equals, baseHpMax)
+			})
+		})
+
+		c.Specify("with boots moves faster", func() {
+			process(equipCmd{1, ES_FEET, "boots"}, 1)
+			c.Expect(a.speed, Equals, baseSpeed+items["boots"].speed)
+		})
+
+		c.Specify("can't equip", func() {
+			c.Specify("an item it doesn't have", func() {
+				a.resources["sword"] = 0
+				process(equipCmd{1, ES_WEAPON, "sword"}, 1)
+				c.Expect(rejectedWith(), Equals, ERR_NOT_OWNED)
+			})
+
+			c.Specify("an item in the wrong slot", func() {
+				process(equipCmd{1, ES_FEET, "sword"}, 1)
+				c.Expect(rejectedWith(), Equals, ERR_WRONG_SLOT)
+			})
+
+			c.Specify("an item that doesn't exist", func() {
+				process(equipCmd{1, ES_WEAPON, "cake"}, 1)
+				c.Expect(rejectedWith(), Equals, ERR_UNKNOWN_ITEM)
+			})
+		})
+	})
+}
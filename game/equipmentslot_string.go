@@ -0,0 +1,28 @@
+// Code generated by "stringer -type=EquipmentSlot"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[ES_WEAPON-0]
+	_ = x[ES_ARMOR-1]
+	_ = x[ES_FEET-2]
+	_ = x[ES_OFFHAND-3]
+	_ = x[ES_SIZE-4]
+}
+
+const _EquipmentSlot_name = "ES_WEAPONES_ARMORES_FEETES_OFFHANDES_SIZE"
+
+var _EquipmentSlot_index = [...]uint8{0, 9, 17, 24, 34, 41}
+
+func (i EquipmentSlot) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_EquipmentSlot_index)-1 {
+		return "EquipmentSlot(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _EquipmentSlot_name[_EquipmentSlot_index[idx]:_EquipmentSlot_index[idx+1]]
+}
@@ -0,0 +1,27 @@
+// Code generated by "stringer -type=EquipRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[ERR_ERROR-0]
+	_ = x[ERR_UNKNOWN_ITEM-1]
+	_ = x[ERR_WRONG_SLOT-2]
+	_ = x[ERR_NOT_OWNED-3]
+}
+
+const _EquipRejectedReason_name = "ERR_ERRORERR_UNKNOWN_ITEMERR_WRONG_SLOTERR_NOT_OWNED"
+
+var _EquipRejectedReason_index = [...]uint8{0, 9, 25, 39, 52}
+
+func (i EquipRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_EquipRejectedReason_index)-1 {
+		return "EquipRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _EquipRejectedReason_name[_EquipRejectedReason_index[idx]:_EquipRejectedReason_index[idx+1]]
+}
@@ -0,0 +1,346 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// How an actor's standing with the power it
+// serves changes when it kills another actor.
+const (
+	standingPerEnemyKill = 10
+	standingPerAllyKill  = -50
+	standingPerNpcKill   = 1
+)
+
+const guildNameMaxLen = 24
+
+// The guilds of every faction. Is shared by every world
+// of the shard so guilds survive the world being reset.
+type factionTable struct {
+	mu sync.Mutex
+
+	// Indexed by the lower case name of the guild
+	guilds map[string]datastore.Guild
+
+	saves factionSaves
+
+	logger *Logger
+}
+
+// The guilds and members waiting to be saved. Only the
+// latest value of each is kept if the store falls behind.
+type factionSaves struct {
+	mu sync.Mutex
+
+	// Indexed by the lower case name of the guild or actor
+	guilds  map[string]datastore.Guild
+	members map[string]datastore.FactionMember
+
+	// Signaled when a value is waiting to be saved
+	pending chan struct{}
+}
+
+func newFactionTable(guilds []datastore.Guild) *factionTable {
+	t := &factionTable{
+		guilds: make(map[string]datastore.Guild, len(guilds)),
+		saves: factionSaves{
+			guilds:  make(map[string]datastore.Guild),
+			members: make(map[string]datastore.FactionMember),
+			pending: make(chan struct{}, 1),
+		},
+	}
+
+	for _, g := range guilds {
+		t.guilds[strings.ToLower(g.Name)] = g
+	}
+
+	return t
+}
+
+// Is safe to call on a nil table. The value is saved
+// on another go routine so the simulation is never
+// blocked by the store. If the store has fallen
+// behind the value replaces the guild or member's
+// value that is waiting to be saved.
+func (t *factionTable) save(v interface{}) {
+	if t == nil {
+		return
+	}
+
+	s := &t.saves
+	s.mu.Lock()
+	switch v := v.(type) {
+	case datastore.Guild:
+		s.guilds[strings.ToLower(v.Name)] = v
+	case datastore.FactionMember:
+		s.members[strings.ToLower(v.Actor)] = v
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Returns the guilds and members waiting
+// to be saved and forgets them.
+func (s *factionSaves) take() ([]datastore.Guild, []datastore.FactionMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guilds := make([]datastore.Guild, 0, len(s.guilds))
+	for name, g := range s.guilds {
+		guilds = append(guilds, g)
+		delete(s.guilds, name)
+	}
+
+	members := make([]datastore.FactionMember, 0, len(s.members))
+	for name, m := range s.members {
+		members = append(members, m)
+		delete(s.members, name)
+	}
+
+	return guilds, members
+}
+
+// Saves guilds and members to the store. Runs on its
+// own go routine for as long as the shard exists.
+func (t *factionTable) saveTo(store datastore.FactionStore) {
+	for range t.saves.pending {
+		guilds, members := t.saves.take()
+
+		// Guilds are saved first so members never
+		// belong to a guild that hasn't been saved.
+		for _, g := range guilds {
+			if err := store.SaveGuild(g); err != nil {
+				t.logger.Error("error saving guild", "guild", g.Name, "err", err)
+			}
+		}
+
+		for _, m := range members {
+			if err := store.SaveFactionMember(m); err != nil {
+				t.logger.Error("error saving faction member", "actor", m.Actor, "err", err)
+			}
+		}
+	}
+}
+
+func (t *factionTable) guild(name string) (datastore.Guild, bool) {
+	if t == nil {
+		return datastore.Guild{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	g, exists := t.guilds[strings.ToLower(name)]
+	return g, exists
+}
+
+// Returns false if a guild with the same name exists.
+func (t *factionTable) found(g datastore.Guild) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := strings.ToLower(g.Name)
+	if _, exists := t.guilds[name]; exists {
+		return false
+	}
+
+	t.guilds[name] = g
+	t.save(g)
+	return true
+}
+
+// Sent to an actor when its allegiance,
+// guild or standing has changed.
+type FactionMsg struct {
+	Time       stime.Time           `json:"time"`
+	Allegiance datastore.Allegiance `json:"allegiance"`
+	Guild      string               `json:"guild"`
+	Standing   int                  `json:"standing"`
+}
+
+// Is called with the actor's saved membership before it connects.
+func (a *actor) joinFaction(m datastore.FactionMember) {
+	a.allegiance = m.Allegiance
+	a.guild = m.Guild
+	a.standing = m.Standing
+}
+
+func (a *actor) factionMember() datastore.FactionMember {
+	return datastore.FactionMember{
+		Actor:      a.name,
+		Allegiance: a.allegiance,
+		Guild:      a.guild,
+		Standing:   a.standing,
+	}
+}
+
+func (a *actor) sendFaction(now stime.Time) {
+	a.queueMsg(FactionMsg{now, a.allegiance, a.guild, a.standing})
+}
+
+// Saves the actor's membership and sends it the change.
+func (a *actor) factionChanged(now stime.Time) {
+	a.factions.save(a.factionMember())
+	a.sendFaction(now)
+}
+
+// Returns true if both actors serve the same power.
+func (e actorEntity) isAllyOf(other actorEntity) bool {
+	return e.allegiance != datastore.AL_NONE && e.allegiance == other.allegiance
+}
+
+// Returns the change in standing for killing the victim.
+func (a *actor) standingForKill(victim *actor) int {
+	switch {
+	case a.allegiance == datastore.AL_NONE:
+		return 0
+	case !victim.hasLegend():
+		return standingPerNpcKill
+	case a.isAllyOf(victim.actorEntity):
+		return standingPerAllyKill
+	case victim.allegiance != datastore.AL_NONE:
+		return standingPerEnemyKill
+	}
+
+	return 0
+}
+
+func (a *actor) changeStanding(delta int, now stime.Time) {
+	if delta == 0 || !a.hasLegend() {
+		return
+	}
+
+	a.standing += delta
+	a.factionChanged(now)
+}
+
+// Returns the actors serving the same power as the actor
+// as of the allegiance they last published.
+func (c *chatChannels) alliesOf(a *actor) []*actor {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var actors []*actor
+	for _, other := range c.actors {
+		allegiance := c.presence[other].allegiance
+		if other == a || a.allegiance != datastore.AL_NONE && allegiance == a.allegiance {
+			actors = append(actors, other)
+		}
+	}
+	return actors
+}
+
+// Returns the actors in the same guild as the
+// actor as of the guild they last published.
+func (c *chatChannels) guildOf(a *actor) []*actor {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var actors []*actor
+	for _, other := range c.actors {
+		if other == a || strings.EqualFold(c.presence[other].guild, a.guild) {
+			actors = append(actors, other)
+		}
+	}
+	return actors
+}
+
+func parseAllegiance(s string) (datastore.Allegiance, error) {
+	switch strings.ToLower(s) {
+	case "good":
+		return datastore.AL_GOOD, nil
+	case "bad":
+		return datastore.AL_BAD, nil
+	}
+
+	return datastore.AL_NONE, errors.New("you can only serve the good or the bad")
+}
+
+func (a *actor) pledge(allegiance datastore.Allegiance, now stime.Time) error {
+	if a.allegiance != datastore.AL_NONE {
+		return fmt.Errorf("you already serve the %s, /renounce your allegiance first", a.allegiance)
+	}
+
+	a.allegiance = allegiance
+	a.factionChanged(now)
+	return nil
+}
+
+// The actor leaves its guild and loses its standing.
+func (a *actor) renounce(now stime.Time) error {
+	if a.allegiance == datastore.AL_NONE {
+		return errors.New("you don't serve anyone")
+	}
+
+	a.allegiance = datastore.AL_NONE
+	a.guild = ""
+	a.standing = 0
+	a.factionChanged(now)
+	return nil
+}
+
+func (a *actor) foundGuild(name string, now stime.Time) error {
+	switch {
+	case a.allegiance == datastore.AL_NONE:
+		return errors.New("you must /pledge your allegiance before founding a guild")
+	case a.guild != "":
+		return errors.New("you must leave your guild before founding another")
+	case len(name) > guildNameMaxLen:
+		return fmt.Errorf("guild names can't be longer than %d characters", guildNameMaxLen)
+	}
+
+	if !a.factions.found(datastore.Guild{Name: name, Allegiance: a.allegiance, Founder: a.name}) {
+		return fmt.Errorf("the guild %s already exists", name)
+	}
+
+	a.guild = name
+	a.factionChanged(now)
+	return nil
+}
+
+func (a *actor) joinGuild(name string, now stime.Time) error {
+	g, exists := a.factions.guild(name)
+	switch {
+	case !exists:
+		return fmt.Errorf("the guild %s doesn't exist", name)
+	case a.guild != "":
+		return errors.New("you must leave your guild before joining another")
+	case g.Allegiance != a.allegiance:
+		return fmt.Errorf("the guild %s only accepts those who serve the %s", g.Name, g.Allegiance)
+	}
+
+	a.guild = g.Name
+	a.factionChanged(now)
+	return nil
+}
+
+func (a *actor) leaveGuild(now stime.Time) error {
+	if a.guild == "" {
+		return errors.New("you aren't in a guild")
+	}
+
+	a.guild = ""
+	a.factionChanged(now)
+	return nil
+}
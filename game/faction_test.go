@@ -0,0 +1,142 @@
+package game
+
+import (
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/quad"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeFactions(c gospec.Context) {
+	c.Specify("an actor", func() {
+		chat := newChatChannels(nil)
+		factions := newFactionTable([]datastore.Guild{
+			{Name: "Dawn", Allegiance: datastore.AL_GOOD, Founder: "Carol"},
+		})
+
+		alice := newPartyTestActor(chat, nil, 1, "Alice", coord.Cell{0, 0})
+		bob := newPartyTestActor(chat, nil, 2, "Bob", coord.Cell{1, 0})
+		alice.factions = factions
+		bob.factions = factions
+
+		c.Specify("can pledge its allegiance", func() {
+			msgs := alice.issueCommand("/pledge good", 1)
+			c.Expect(alice.allegiance, Equals, datastore.AL_GOOD)
+			c.Expect(msgs, Contains, ActorMsg(FactionMsg{1, datastore.AL_GOOD, "", 0}))
+
+			c.Specify("which is saved", func() {
+				_, members := factions.saves.take()
+				c.Expect(members, ContainsExactly, []datastore.FactionMember{{
+					Actor:      "Alice",
+					Allegiance: datastore.AL_GOOD,
+				}})
+
+				c.Specify("once with the latest value if the store has fallen behind", func() {
+					alice.issueCommand("/renounce", 2)
+					alice.issueCommand("/pledge bad", 3)
+
+					_, members := factions.saves.take()
+					c.Expect(members, ContainsExactly, []datastore.FactionMember{{
+						Actor:      "Alice",
+						Allegiance: datastore.AL_BAD,
+					}})
+				})
+			})
+
+			c.Specify("which is included in its state", func() {
+				state := alice.ToState().(ActorEntityState)
+				c.Expect(state.Allegiance, Equals, datastore.AL_GOOD)
+			})
+
+			c.Specify("only once", func() {
+				reply := alice.issueCommand("/pledge bad", 1)[0].(CommandReplyMsg)
+				c.Expect(reply.Failed, IsTrue)
+				c.Expect(alice.allegiance, Equals, datastore.AL_GOOD)
+			})
+
+			c.Specify("and join a guild of its allegiance", func() {
+				alice.issueCommand("/guild join dawn", 1)
+				c.Expect(alice.guild, Equals, "Dawn")
+			})
+
+			c.Specify("and found a guild", func() {
+				alice.issueCommand("/guild found Dusk Watch", 1)
+				c.Expect(alice.guild, Equals, "Dusk Watch")
+
+				g, exists := factions.guild("dusk watch")
+				c.Assume(exists, IsTrue)
+				c.Expect(g.Founder, Equals, "Alice")
+
+				c.Specify("that can't be founded again", func() {
+					bob.issueCommand("/pledge good", 1)
+					reply := bob.issueCommand("/guild found dusk watch", 1)[0].(CommandReplyMsg)
+					c.Expect(reply.Failed, IsTrue)
+				})
+			})
+
+			c.Specify("and lose its standing when it renounces", func() {
+				alice.standing = 20
+				alice.issueCommand("/renounce", 1)
+				c.Expect(alice.allegiance, Equals, datastore.AL_NONE)
+				c.Expect(alice.standing, Equals, 0)
+			})
+		})
+
+		c.Specify("can't join a guild of another allegiance", func() {
+			alice.issueCommand("/pledge bad", 1)
+			reply := alice.issueCommand("/guild join dawn", 1)[0].(CommandReplyMsg)
+			c.Expect(reply.Failed, IsTrue)
+			c.Expect(alice.guild, Equals, "")
+		})
+
+		c.Specify("that serves the same power", func() {
+			alice.allegiance = datastore.AL_GOOD
+			bob.allegiance = datastore.AL_GOOD
+
+			c.Specify("is friendly", func() {
+				phase := &narrowPhase{actorIndex: ActorIndex{alice.id: alice, bob.id: bob}}
+				c.Expect(phase.isFriendly(alice.id, bob), IsTrue)
+
+				assail := assailEntity{spawnedBy: 100, spawnedByActor: alice.id, cell: bob.Cell(), damage: 25}
+				phase.solveActorAssail(bob, assail, quad.Collision{}, 1)
+				c.Expect(bob.hp, Equals, baseHpMax)
+			})
+
+			c.Specify("can be sent faction messages once it has published its allegiance", func() {
+				inputPhase{}.processChatCmd(alice.withChatCmd(&chatCmd{CR_FACTION, 1, "hi", "", 0}), 1)
+				c.Expect(len(bob.deliveredMsgs()), Equals, 0)
+
+				chat.publish(bob)
+				inputPhase{}.processChatCmd(alice.withChatCmd(&chatCmd{CR_FACTION, 2, "hi", "", 0}), 2)
+				c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatMsg{2, CR_FACTION, "Alice", "", "hi"},
+				})
+			})
+
+			c.Specify("loses standing for killing an ally", func() {
+				c.Expect(alice.standingForKill(bob), Equals, standingPerAllyKill)
+			})
+		})
+
+		c.Specify("gains standing for killing an enemy", func() {
+			alice.allegiance = datastore.AL_GOOD
+			bob.allegiance = datastore.AL_BAD
+
+			phase := &narrowPhase{actorIndex: ActorIndex{alice.id: alice, bob.id: bob}}
+			bob.takeDamage(bob.hp)
+			phase.recordKill(alice.id, bob, 1)
+
+			c.Expect(alice.standing, Equals, standingPerEnemyKill)
+			c.Expect(alice.deliveredMsgs(), Contains, ActorMsg(FactionMsg{1, datastore.AL_GOOD, "", standingPerEnemyKill}))
+		})
+
+		c.Specify("without an allegiance can't send faction messages", func() {
+			inputPhase{}.processChatCmd(alice.withChatCmd(&chatCmd{CR_FACTION, 1, "hi", "", 0}), 1)
+			c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+				ChatRejectedMsg{1, CR_FACTION, "", CHRR_NO_FACTION, 0},
+			})
+		})
+	})
+}
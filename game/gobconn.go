@@ -5,8 +5,10 @@ import (
 	"context"
 	"encoding/gob"
 	"io"
-	"log"
+	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/ghthor/aodd/game/datastore"
 	"github.com/ghthor/filu/rpg2d"
@@ -54,12 +56,43 @@ func init() {
 	// Other entity states
 	gob.Register(SayEntityState{})
 	gob.Register(AssailEntityState{})
+	gob.Register(AoeEntityState{})
 	gob.Register(WallEntityState{})
+	gob.Register(StructureEntityState{})
+	gob.Register(ResourceNodeEntityState{})
 
 	// Cmd Requests. They have no responses.
 	gob.Register(MoveRequest{})
 	gob.Register(UseRequest{})
 	gob.Register(ChatRequest{})
+	gob.Register(BuildRequest{})
+	gob.Register(CraftRequest{})
+	gob.Register(EquipRequest{})
+	gob.Register(EmoteRequest{})
+	gob.Register(PartyRequest{})
+	gob.Register(TradeRequest{})
+
+	// Messages sent to a single actor
+	gob.Register(ActorMsgs{})
+	gob.Register(CooldownsMsg{})
+	gob.Register(UseRejectedMsg{})
+	gob.Register(ResourcesMsg{})
+	gob.Register(BuildRejectedMsg{})
+	gob.Register(CraftMsg{})
+	gob.Register(CraftRejectedMsg{})
+	gob.Register(EquipRejectedMsg{})
+	gob.Register(WorldEndedMsg{})
+	gob.Register(KickedMsg{})
+	gob.Register(ChatMsg{})
+	gob.Register(ChatRejectedMsg{})
+	gob.Register(CommandReplyMsg{})
+	gob.Register(PartyMsg{})
+	gob.Register(PartyInviteMsg{})
+	gob.Register(PartyRejectedMsg{})
+	gob.Register(FactionMsg{})
+	gob.Register(TradeMsg{})
+	gob.Register(TradeInviteMsg{})
+	gob.Register(TradeRejectedMsg{})
 }
 
 type gobConn struct {
@@ -101,15 +134,99 @@ func NewGobConn(rw io.ReadWriter) Conn {
 	}
 }
 
+// How long a client has to read what it's sent
+// before its connection is closed.
+const slowClientTimeout = 5 * time.Second
+
+// Counts the bytes written to a writer.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += n
+	return n, err
+}
+
+// A connection to a client of the shard. Records the
+// messages and bytes sent to the client and closes the
+// connection if the client doesn't read them in time.
+type shardConn struct {
+	Conn
+	written *countingWriter
+
+	setWriteDeadline func(time.Time) error
+	timeout          time.Duration
+
+	// Ends the connection. Reads and writes that
+	// are blocked return an error.
+	close func()
+
+	logger *Logger
+}
+
+func newShardConn(conn net.Conn, close func(), logger *Logger) Conn {
+	written := &countingWriter{w: conn}
+	rw := struct {
+		io.Reader
+		io.Writer
+	}{conn, written}
+
+	return shardConn{
+		Conn:    NewGobConn(rw),
+		written: written,
+
+		setWriteDeadline: conn.SetWriteDeadline,
+		timeout:          slowClientTimeout,
+
+		close: close,
+
+		logger: logger,
+	}
+}
+
+func (c shardConn) Close() error {
+	c.close()
+	return nil
+}
+
+func (c shardConn) EncodeAndSend(t EncodedType, v interface{}) error {
+	deadline := time.Now().Add(c.timeout)
+	c.setWriteDeadline(deadline)
+
+	before := c.written.n
+	err := c.Conn.EncodeAndSend(t, v)
+	c.setWriteDeadline(time.Time{})
+
+	metrics.sent(t, v, c.written.n-before, err)
+
+	if err != nil && !time.Now().Before(deadline) {
+		metrics.evicted()
+		c.logger.Warn("evicted a slow client", "type", t, "err", err)
+	}
+
+	return err
+}
+
+// Every connection is given an id that's
+// unique for as long as the process runs.
+var lastConnId uint64
+
+// Each line logged by a connection is tagged with its id.
 func newGobWebsocketHandler(
 	ds datastore.Datastore,
-	actorConnector ActorConnector) http.HandlerFunc {
+	actorConnector ActorConnector,
+	logger *Logger) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.With("conn", atomic.AddUint64(&lastConnId, 1))
+
 		ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 			OriginPatterns: []string{"localhost"},
 		})
 		if err != nil {
-			log.Println(err)
+			logger.Warn("error accepting websocket", "remote", r.RemoteAddr, "err", err)
 			return
 		}
 		defer ws.Close(websocket.StatusInternalError, "its all coming down")
@@ -118,12 +235,11 @@ func newGobWebsocketHandler(
 		defer cancel()
 
 		conn := websocket.NetConn(ctx, ws, websocket.MessageBinary)
-		c := NewPreLoginConn(NewGobConn(conn), ds)
+		c := NewPreLoginConn(newShardConn(conn, cancel, logger), ds, logger)
+		logger.Info("connection opened", "remote", r.RemoteAddr)
 
 		// Blocks until the connection has disconnected
 		err = LoginAndConnectActor(c, actorConnector)
-		if err != nil {
-			log.Printf("packet handler terminated: %v", err)
-		}
+		logger.Info("connection closed", "err", err)
 	})
 }
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
 	"github.com/ghthor/filu/rpg2d/coord"
 	"github.com/ghthor/filu/rpg2d/entity"
 	"github.com/ghthor/filu/sim/stime"
@@ -13,6 +16,16 @@ import (
 
 type updatePhaseLocker struct {
 	*ActorIndexLocker
+
+	// The zone being simulated, nil if the
+	// world isn't divided into zones.
+	zone *localZone
+
+	// Records a snapshot of the zone's entities
+	// when one has been requested.
+	snapshotOf *localZone
+
+	metrics *tickMetrics
 }
 
 type updatePhase struct {
@@ -21,17 +34,31 @@ type updatePhase struct {
 
 type inputPhaseLocker struct {
 	*ActorIndexLocker
-	nextId func() entity.Id
+	nextId  func() entity.Id
+	terrain *terrainIndex
+	edits   *worldEdits
+	nodes   resourceNodeIndex
+	metrics *tickMetrics
 }
 
 type inputPhase struct {
-	index  ActorIndex
-	nextId func() entity.Id
+	index   ActorIndex
+	nextId  func() entity.Id
+	terrain *terrainIndex
+	edits   *worldEdits
+	nodes   resourceNodeIndex
 }
 
 func (phase updatePhaseLocker) Update(e entity.Entity, now stime.Time) entity.Entity {
+	defer phase.metrics.timePhase(TP_UPDATE, now, time.Now())
 	defer phase.ActorIndexLocker.RUnlock()
-	return updatePhase{phase.ActorIndexLocker.RLock()}.Update(e, now)
+	index := phase.ActorIndexLocker.RLock()
+
+	phase.metrics.countEntity(e, index, now)
+	e = updatePhase{index}.Update(e, now)
+	phase.zone.observe(e, index, now)
+	phase.snapshotOf.recordSnapshot(e, now)
+	return e
 }
 
 func (phase updatePhase) Update(e entity.Entity, now stime.Time) entity.Entity {
@@ -56,15 +83,25 @@ func (phase updatePhase) Update(e entity.Entity, now stime.Time) entity.Entity {
 
 		// Reset speed after a charge
 		if actor.lastStartedCharge+chargeDuration <= now {
-			actor.speed = baseSpeed
+			actor.speed = actor.baseSpeed()
 		}
 
+		actor.updateAnimation(now)
+		actor.chat.publish(actor)
+		actor.parties.publish(actor)
+		actor.sendPartyStatus(now)
+		actor.updateTrade(phase.index, now)
+
 		return actor.Entity()
 
 	case assailEntity:
 		// Remove all assail entities
 		return entity.Removed{e, now}
 
+	case aoeEntity:
+		// Remove all area of effect entities
+		return entity.Removed{e, now}
+
 	case sayEntity:
 		// TODO parametize server fps
 		if e.saidAt+(sayEntityDuration*40) <= now {
@@ -79,6 +116,15 @@ func (phase updatePhase) Update(e entity.Entity, now stime.Time) entity.Entity {
 		e.flags = e.flags &^ entity.FlagNew
 		return e
 
+	case structureEntity:
+		e.flags = e.flags &^ entity.FlagNew
+		return e
+
+	case resourceNodeEntity:
+		e.flags = e.flags &^ entity.FlagNew
+		e.respawn(now)
+		return e
+
 	case entity.Removed:
 		// 3 Secs * Sim FPS
 		if e.RemovedAt+(3*40) <= now {
@@ -92,8 +138,9 @@ func (phase updatePhase) Update(e entity.Entity, now stime.Time) entity.Entity {
 }
 
 func (phase inputPhaseLocker) ApplyInputsTo(e entity.Entity, now stime.Time) []entity.Entity {
+	defer phase.metrics.timePhase(TP_INPUT, now, time.Now())
 	defer phase.ActorIndexLocker.RUnlock()
-	return inputPhase{phase.RLock(), phase.nextId}.ApplyInputsTo(e, now)
+	return inputPhase{phase.RLock(), phase.nextId, phase.terrain, phase.edits, phase.nodes}.ApplyInputsTo(e, now)
 }
 
 func (phase inputPhase) ApplyInputsTo(e entity.Entity, now stime.Time) []entity.Entity {
@@ -112,6 +159,16 @@ func (phase inputPhase) ApplyInputsTo(e entity.Entity, now stime.Time) []entity.
 			phase.processChatCmd(actor, now)...,
 		)
 
+		entities = append(entities,
+			phase.processBuildCmd(actor, now)...,
+		)
+
+		phase.processCraftCmd(actor, now)
+		phase.processEquipCmd(actor, now)
+		phase.processEmoteCmd(actor, now)
+		phase.processPartyCmd(actor, now)
+		phase.processTradeCmd(actor, now)
+
 		return append(entities, actor.Entity())
 
 	case sayEntity:
@@ -120,6 +177,16 @@ func (phase inputPhase) ApplyInputsTo(e entity.Entity, now stime.Time) []entity.
 	case wallEntity:
 		return []entity.Entity{e}
 
+	case structureEntity:
+		if phase.edits.takeDemolished(e.id) {
+			return []entity.Entity{entity.Removed{e, now}}
+		}
+
+		return []entity.Entity{e}
+
+	case resourceNodeEntity:
+		return []entity.Entity{e}
+
 	case entity.Removed:
 		return []entity.Entity{e}
 
@@ -135,6 +202,7 @@ const (
 	MR_ERROR MoveRequestType = iota
 	MR_MOVE
 	MR_MOVE_CANCEL
+	MR_MOVE_TO
 	MR_SIZE
 )
 
@@ -142,11 +210,18 @@ type MoveRequest struct {
 	MoveRequestType
 	stime.Time
 	coord.Direction
+
+	// Cell an MR_MOVE_TO request will path to
+	Dest coord.Cell
 }
 
 type moveCmd struct {
 	stime.Time
 	coord.Direction
+
+	// Non-nil if the actor is moving to a cell
+	// instead of moving in the direction.
+	dest *coord.Cell
 }
 
 type UseRequestType int
@@ -176,6 +251,27 @@ type ChatRequestType int
 const (
 	CR_ERROR ChatRequestType = iota
 	CR_SAY
+	CR_WHISPER
+	CR_SHOUT
+	CR_PARTY
+	CR_GLOBAL
+
+	// Sent to the actors that serve the same
+	// power or are in the same guild.
+	CR_FACTION
+	CR_GUILD
+
+	CR_EMOTE
+
+	// Sent to every actor by an administrator.
+	// Can only be sent with the /announce command.
+	CR_ANNOUNCE
+
+	// Stops or resumes delivering the messages
+	// sent by the actor named by To.
+	CR_IGNORE
+	CR_UNIGNORE
+
 	CR_SIZE
 )
 
@@ -183,12 +279,20 @@ type ChatRequest struct {
 	ChatRequestType
 	stime.Time
 	Msg string
+
+	// The name of the actor a whisper is sent to
+	To string
 }
 
 type chatCmd struct {
 	ChatRequestType
 	stime.Time
 	msg string
+	to  string
+
+	// Number of chat requests that were replaced by this
+	// request before the simulation could read them.
+	dropped int
 }
 
 func newMoveRequest(t MoveRequestType, timeIssued stime.Time, params string) (MoveRequest, error) {
@@ -198,9 +302,23 @@ func newMoveRequest(t MoveRequestType, timeIssued stime.Time, params string) (Mo
 	}
 
 	return MoveRequest{
-		t,
-		timeIssued,
-		d,
+		MoveRequestType: t,
+		Time:            timeIssued,
+		Direction:       d,
+	}, nil
+}
+
+func newMoveToRequest(timeIssued stime.Time, params string) (MoveRequest, error) {
+	var dest coord.Cell
+	_, err := fmt.Sscanf(params, "%d,%d", &dest.X, &dest.Y)
+	if err != nil {
+		return MoveRequest{}, err
+	}
+
+	return MoveRequest{
+		MoveRequestType: MR_MOVE_TO,
+		Time:            timeIssued,
+		Dest:            dest,
 	}, nil
 }
 
@@ -210,7 +328,17 @@ func newUseRequest(t UseRequestType, timeIssued stime.Time, params string) (UseR
 		return UseRequest{t, timeIssued, params}, nil
 	case "charge":
 		return UseRequest{t, timeIssued, params}, nil
+	case "gather":
+		return UseRequest{t, timeIssued, params}, nil
 	default:
+		if _, exists := aoeSkills[params]; exists {
+			return UseRequest{t, timeIssued, params}, nil
+		}
+
+		if _, exists := channelSkills[params]; exists {
+			return UseRequest{t, timeIssued, params}, nil
+		}
+
 		return UseRequest{}, fmt.Errorf("unknown skill: %s", params)
 	}
 }
@@ -220,10 +348,31 @@ func newChatRequest(t ChatRequestType, timeIssued stime.Time, params string) (Ch
 		return ChatRequest{}, errors.New("chat message exceeded 120 char limit")
 	}
 
+	var to string
+
+	switch t {
+	case CR_IGNORE, CR_UNIGNORE:
+		if params == "" {
+			return ChatRequest{}, errors.New("must name the actor to ignore")
+		}
+
+		to, params = params, ""
+
+	// Whispers are prefixed with the name of the recipient
+	case CR_WHISPER:
+		parts := strings.SplitN(params, " ", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return ChatRequest{}, errors.New("whisper must begin with the name of the recipient")
+		}
+
+		to, params = parts[0], parts[1]
+	}
+
 	return ChatRequest{
 		ChatRequestType: t,
 		Time:            timeIssued,
 		Msg:             params,
+		To:              to,
 	}, nil
 }
 
@@ -260,6 +409,14 @@ func (c actorConn) SubmitCmd(cmd, params string) error {
 
 		c.submitMoveRequest <- r
 
+	case "moveTo":
+		r, err := newMoveToRequest(stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitMoveRequest <- r
+
 	case "use":
 		r, err := newUseRequest(UR_USE, stime.Time(timeIssued), params)
 		if err != nil {
@@ -284,6 +441,190 @@ func (c actorConn) SubmitCmd(cmd, params string) error {
 
 		c.submitChatRequest <- r
 
+	case "whisper":
+		r, err := newChatRequest(CR_WHISPER, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "shout":
+		r, err := newChatRequest(CR_SHOUT, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "party":
+		r, err := newChatRequest(CR_PARTY, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "global":
+		r, err := newChatRequest(CR_GLOBAL, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "faction":
+		r, err := newChatRequest(CR_FACTION, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "guild":
+		r, err := newChatRequest(CR_GUILD, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "ignore":
+		r, err := newChatRequest(CR_IGNORE, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "unignore":
+		r, err := newChatRequest(CR_UNIGNORE, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitChatRequest <- r
+
+	case "sculpt":
+		r, err := newBuildRequest(BR_SCULPT, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitBuildRequest <- r
+
+	case "build":
+		r, err := newBuildRequest(BR_BUILD, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitBuildRequest <- r
+
+	case "demolish":
+		r, err := newBuildRequest(BR_DEMOLISH, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitBuildRequest <- r
+
+	case "craft":
+		r, err := newCraftRequest(stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitCraftRequest <- r
+
+	case "equip":
+		r, err := newEquipRequest(stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitEquipRequest <- r
+
+	case "emote":
+		r, err := newEmoteRequest(stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitEmoteRequest <- r
+
+	case "invite":
+		r, err := newPartyRequest(PR_INVITE, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitPartyRequest <- r
+
+	case "accept":
+		r, err := newPartyRequest(PR_ACCEPT, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitPartyRequest <- r
+
+	case "leave":
+		r, err := newPartyRequest(PR_LEAVE, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitPartyRequest <- r
+
+	case "kick":
+		r, err := newPartyRequest(PR_KICK, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitPartyRequest <- r
+
+	case "trade":
+		r, err := newTradeRequest(TR_REQUEST, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitTradeRequest <- r
+
+	case "offer":
+		r, err := newTradeRequest(TR_OFFER, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitTradeRequest <- r
+
+	case "lock":
+		r, err := newTradeRequest(TR_LOCK, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitTradeRequest <- r
+
+	case "confirm":
+		r, err := newTradeRequest(TR_CONFIRM, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitTradeRequest <- r
+
+	case "canceltrade":
+		r, err := newTradeRequest(TR_CANCEL, stime.Time(timeIssued), params)
+		if err != nil {
+			return err
+		}
+
+		c.submitTradeRequest <- r
+
 	default:
 		return fmt.Errorf("unknown command: %s", parts[0])
 	}
@@ -312,6 +653,48 @@ func (c actorConn) SubmitChatRequest(r ChatRequest) {
 	}
 }
 
+func (c actorConn) SubmitBuildRequest(r BuildRequest) {
+	select {
+	case c.submitBuildRequest <- r:
+	default:
+	}
+}
+
+func (c actorConn) SubmitCraftRequest(r CraftRequest) {
+	select {
+	case c.submitCraftRequest <- r:
+	default:
+	}
+}
+
+func (c actorConn) SubmitEquipRequest(r EquipRequest) {
+	select {
+	case c.submitEquipRequest <- r:
+	default:
+	}
+}
+
+func (c actorConn) SubmitEmoteRequest(r EmoteRequest) {
+	select {
+	case c.submitEmoteRequest <- r:
+	default:
+	}
+}
+
+func (c actorConn) SubmitPartyRequest(r PartyRequest) {
+	select {
+	case c.submitPartyRequest <- r:
+	default:
+	}
+}
+
+func (c actorConn) SubmitTradeRequest(r TradeRequest) {
+	select {
+	case c.submitTradeRequest <- r:
+	default:
+	}
+}
+
 func (c actorConn) ReadMoveCmd() *moveCmd {
 	return <-c.readMoveCmd
 }
@@ -320,16 +703,25 @@ func (a *actor) applyPathAction(pa *coord.PathAction) {
 	prevPathAction := a.pathAction
 	prevFacing := a.facing
 	prevFlags := a.flags
+	prevCast := a.cast
 
 	a.undoLastMoveAction = func() {
 		a.pathAction = prevPathAction
 		a.facing = prevFacing
 		a.flags = prevFlags
+		a.cast = prevCast
 		a.undoLastMoveAction = nil
+
+		// The step was blocked so the path to the
+		// cell will be planned again around it.
+		a.path = nil
 	}
 
 	a.pathAction = pa
 	a.facing = pa.Direction()
+
+	// Moving interrupts casting
+	a.interruptCast()
 }
 
 func (a *actor) applyTurnAction(ta coord.TurnAction) {
@@ -367,23 +759,42 @@ func (phase inputPhase) processMoveCmd(a *actor, now stime.Time) {
 		return
 	}
 
+	if cmd.dest != nil {
+		phase.processMoveToCmd(a, cmd, now)
+		return
+	}
+
+	a.step(cmd.Direction, phase.terrain, now)
+}
+
+// Moves the actor a single cell in the direction or turns
+// the actor to face the direction if it can't move yet.
+// The terrain of the destination must be walkable and
+// changes how long it takes to move into it. A destination
+// in a neighbouring zone must not be blocked by its entities.
+// Returns true if the actor started moving.
+func (a *actor) step(d coord.Direction, terrain *terrainIndex, now stime.Time) bool {
+	dest := a.Cell().Neighbor(d)
+	props := terrain.propertiesAt(dest)
+
 	// Actor may be able to move
 	pathAction := &coord.PathAction{
-		Span: stime.NewSpan(now, now+stime.Time(a.speed)),
+		Span: stime.NewSpan(now, now+props.moveDuration(a.speed)),
 		Orig: a.Cell(),
-		Dest: a.Cell().Neighbor(cmd.Direction),
+		Dest: dest,
 	}
 
-	if pathAction.CanHappenAfter(a.lastMoveAction) {
+	if props.walkable && pathAction.CanHappenAfter(a.lastMoveAction) &&
+		!a.zone.blocksCrossing(dest, a.actorEntity.id) {
 		a.applyPathAction(pathAction)
-		return
+		return true
 	}
 
 	// Actor must change facing
-	if a.facing != cmd.Direction {
+	if a.facing != d {
 		turnAction := coord.TurnAction{
 			From: a.facing,
-			To:   cmd.Direction,
+			To:   d,
 			Time: now,
 		}
 
@@ -391,6 +802,54 @@ func (phase inputPhase) processMoveCmd(a *actor, now stime.Time) {
 			a.applyTurnAction(turnAction)
 		}
 	}
+
+	return false
+}
+
+// The steps planned to move an actor to a cell.
+type movePath struct {
+	cmd   *moveCmd
+	steps []coord.Direction
+}
+
+// The move to command is repeated every tick until it is
+// canceled or replaced. A path is planned the first time
+// it is seen and the actor takes a step along it every
+// time it can move. If a step is reverted by the collision
+// solver the path is planned again from where the actor is.
+func (phase inputPhase) processMoveToCmd(a *actor, cmd *moveCmd, now stime.Time) {
+	// The actor has already arrived or couldn't
+	// find a path for this command.
+	if a.finishedMoveCmd == cmd {
+		return
+	}
+
+	if a.path == nil || a.path.cmd != cmd {
+		blocked := blockedCells(a.lastWorldState(), a.actorEntity.id)
+		steps, found := findPath(a.Cell(), *cmd.dest, ActorCullBounds(a.Cell()),
+			func(c coord.Cell) bool {
+				return blocked[c] || !phase.terrain.propertiesAt(c).walkable
+			})
+
+		if !found {
+			a.path = nil
+			a.finishedMoveCmd = cmd
+			return
+		}
+
+		a.path = &movePath{cmd, steps}
+	}
+
+	if len(a.path.steps) == 0 {
+		a.path = nil
+		a.finishedMoveCmd = cmd
+		return
+	}
+
+	path := a.path
+	if a.step(path.steps[0], phase.terrain, now) {
+		path.steps = path.steps[1:]
+	}
 }
 
 // 1s
@@ -400,8 +859,9 @@ const assailCooldown = 40
 type assailEntity struct {
 	id entity.Id
 
-	spawnedBy entity.Id
-	spawnedAt stime.Time
+	spawnedBy      entity.Id
+	spawnedByActor rpg2d.ActorId
+	spawnedAt      stime.Time
 
 	cell  coord.Cell
 	flags entity.Flag
@@ -455,43 +915,84 @@ func (c actorConn) ReadUseCmd() *useCmd {
 
 func (phase inputPhase) processUseCmd(a *actor, now stime.Time) []entity.Entity {
 	cmd := a.ReadUseCmd()
+
+	if a.cast != nil {
+		return phase.processCast(a, cmd, now)
+	}
+
 	if cmd == nil {
 		return nil
 	}
 
+	if cooldownOf(cmd.skill) == 0 {
+		a.rejectUseCmd(cmd, URR_UNKNOWN_SKILL, now)
+		return nil
+	}
+
+	if !a.cooldowns.isReady(cmd.skill, now) {
+		a.rejectUseCmd(cmd, URR_COOLDOWN, now)
+		return nil
+	}
+
 	// TODO Only allow when stationary
-	// TODO Trigger a cooldown
 	switch cmd.skill {
 	case "assail":
-		// Implement a cooldown
-		if a.lastAssail.spawnedAt+assailCooldown > now {
-			return nil
-		}
-
 		e := assailEntity{
 			id: phase.nextId(),
 
-			spawnedBy: a.actorEntity.Id(),
-			spawnedAt: now,
+			spawnedBy:      a.actorEntity.Id(),
+			spawnedByActor: a.Id(),
+			spawnedAt:      now,
 
 			cell:  a.Cell().Neighbor(a.facing),
 			flags: entity.FlagNew,
 
-			damage: 25,
+			damage: a.assailDamage(),
 		}
 
-		a.lastAssail = e
+		a.startCooldown(cmd.skill, now)
 
 		return []entity.Entity{e}
 
 	case "charge":
-		// Implement a cooldown
-		if a.lastStartedCharge+chargeCooldown <= now {
-			a.speed = chargeSpeed
-			a.lastStartedCharge = now
+		a.speed = chargeSpeed
+		a.lastStartedCharge = now
+		a.startCooldown(cmd.skill, now)
+		return nil
+
+	case "gather":
+		phase.startGather(a, cmd, now)
+		return nil
+	}
+
+	if skill, exists := channelSkills[cmd.skill]; exists {
+		// Channels can only be started while stationary
+		if a.pathAction != nil {
+			a.rejectUseCmd(cmd, URR_MOVING, now)
+			return nil
 		}
+
+		a.startCooldown(cmd.skill, now)
+		a.startCast(cmd.skill, skill.duration, true, now)
+		return nil
 	}
-	return nil
+
+	skill := aoeSkills[cmd.skill]
+
+	if skill.castTime > 0 {
+		// Casts can only be started while stationary
+		if a.pathAction != nil {
+			a.rejectUseCmd(cmd, URR_MOVING, now)
+			return nil
+		}
+
+		a.startCast(cmd.skill, skill.castTime, false, now)
+		return nil
+	}
+
+	a.startCooldown(cmd.skill, now)
+
+	return []entity.Entity{newAoeEntity(phase.nextId(), cmd.skill, a, now)}
 }
 
 // In seconds
@@ -572,6 +1073,30 @@ func (phase inputPhase) processChatCmd(a *actor, now stime.Time) []entity.Entity
 		return nil
 	}
 
+	a.reportDroppedChatCmds(cmd, now)
+
+	switch cmd.ChatRequestType {
+	case CR_IGNORE:
+		a.chatMod.ignore(cmd.to)
+		return nil
+
+	case CR_UNIGNORE:
+		a.chatMod.unignore(cmd.to)
+		return nil
+
+	case CR_WHISPER:
+		// A whisper may begin with a slash
+
+	default:
+		if a.runSlashCommand(cmd, now) {
+			return nil
+		}
+	}
+
+	if !a.moderateChatCmd(cmd, now) {
+		return nil
+	}
+
 	switch cmd.ChatRequestType {
 	case CR_SAY:
 		return []entity.Entity{sayEntity{
@@ -585,6 +1110,43 @@ func (phase inputPhase) processChatCmd(a *actor, now stime.Time) []entity.Entity
 
 			msg: cmd.msg,
 		}}
+
+	case CR_WHISPER:
+		a.whisper(cmd, now)
+
+	case CR_SHOUT:
+		a.sendChat(cmd, a.chat.actorsNear(a.Cell(), shoutRadius), now)
+
+	case CR_PARTY:
+		members := a.partyMembers()
+		if len(members) == 0 {
+			a.rejectChatCmd(cmd, CHRR_NO_PARTY, now)
+			return nil
+		}
+
+		a.sendChat(cmd, members, now)
+
+	case CR_GLOBAL:
+		a.sendChat(cmd, a.chat.all(), now)
+
+	case CR_FACTION:
+		if a.allegiance == datastore.AL_NONE {
+			a.rejectChatCmd(cmd, CHRR_NO_FACTION, now)
+			return nil
+		}
+
+		a.sendChat(cmd, a.chat.alliesOf(a), now)
+
+	case CR_GUILD:
+		if a.guild == "" {
+			a.rejectChatCmd(cmd, CHRR_NO_GUILD, now)
+			return nil
+		}
+
+		a.sendChat(cmd, a.chat.guildOf(a), now)
+
+	case CR_EMOTE:
+		a.sendChat(cmd, a.chat.actorsNear(a.Cell(), emoteRadius), now)
 	}
 
 	return nil
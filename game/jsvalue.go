@@ -20,6 +20,36 @@ func (e ActorEntityState) JSValue() js.Value {
 		v.Set("PathAction", js.Null())
 	}
 
+	if e.Cast != nil {
+		cast := js.Global().Get("Object").New()
+		cast.Set("Skill", e.Cast.Skill)
+		cast.Set("Start", int64(e.Cast.Start))
+		cast.Set("End", int64(e.Cast.End))
+		cast.Set("Channeled", e.Cast.Channeled)
+		v.Set("Cast", cast)
+	} else {
+		v.Set("Cast", js.Null())
+	}
+
+	if e.Animation != nil {
+		animation := js.Global().Get("Object").New()
+		animation.Set("Name", e.Animation.Name)
+		animation.Set("Start", int64(e.Animation.Start))
+		animation.Set("End", int64(e.Animation.End))
+		v.Set("Animation", animation)
+	} else {
+		v.Set("Animation", js.Null())
+	}
+
+	equipment := js.Global().Get("Array").New(len(e.Equipment))
+	for i, item := range e.Equipment {
+		equipment.SetIndex(i, item)
+	}
+	v.Set("Equipment", equipment)
+
+	v.Set("Allegiance", string(e.Allegiance))
+	v.Set("Guild", e.Guild)
+
 	// Health and Mana
 	v.Set("Hp", e.Hp)
 	v.Set("HpMax", e.HpMax)
@@ -37,6 +67,25 @@ func (e WallEntityState) JSValue() js.Value {
 	return v
 }
 
+func (e StructureEntityState) JSValue() js.Value {
+	v := js.Global().Get("Object").New()
+	v.Set("Type", e.Type)
+	v.Set("Id", int64(e.Id))
+	v.Set("Cell", e.Cell)
+	v.Set("BuiltBy", e.BuiltBy)
+	return v
+}
+
+func (e ResourceNodeEntityState) JSValue() js.Value {
+	v := js.Global().Get("Object").New()
+	v.Set("Type", e.Type)
+	v.Set("Id", int64(e.Id))
+	v.Set("Cell", e.Cell)
+	v.Set("Kind", e.Kind)
+	v.Set("Amount", e.Amount)
+	return v
+}
+
 func (e AssailEntityState) JSValue() js.Value {
 	v := js.Global().Get("Object").New()
 	v.Set("Type", e.Type)
@@ -50,6 +99,29 @@ func (e AssailEntityState) JSValue() js.Value {
 	return v
 }
 
+func (e AoeEntityState) JSValue() js.Value {
+	v := js.Global().Get("Object").New()
+	v.Set("Type", e.Type)
+
+	v.Set("Id", int64(e.Id))
+
+	v.Set("Skill", e.Skill)
+	v.Set("Shape", e.Shape.String())
+
+	v.Set("SpawnedBy", int64(e.SpawnedBy))
+	v.Set("SpawnedAt", int64(e.SpawnedAt))
+
+	cells := js.Global().Get("Array").New(len(e.Cells))
+	for i, c := range e.Cells {
+		cell := js.Global().Get("Object").New()
+		cell.Set("Cell", c.Cell)
+		cell.Set("Damage", c.Damage)
+		cells.SetIndex(i, cell)
+	}
+	v.Set("Cells", cells)
+	return v
+}
+
 func (e SayEntityState) JSValue() js.Value {
 	v := js.Global().Get("Object").New()
 	v.Set("Type", e.Type)
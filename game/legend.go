@@ -0,0 +1,161 @@
+package game
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Records events into the legends of characters. Events
+// are appended to the store on another go routine so
+// the simulation is never blocked by the store.
+type legendRecorder struct {
+	// The world the events are recorded in
+	epoch int
+
+	mu sync.Mutex
+
+	// Events waiting to be appended to the store.
+	// Grows without a bound if the store falls behind.
+	events []datastore.LegendEvent
+
+	// Signaled when an event is waiting to be appended
+	pending chan struct{}
+
+	logger *Logger
+}
+
+func newLegendRecorder(epoch int) *legendRecorder {
+	return &legendRecorder{
+		epoch:   epoch,
+		pending: make(chan struct{}, 1),
+	}
+}
+
+// Is safe to call on a nil recorder. Events aren't
+// dropped if the store has fallen behind.
+func (l *legendRecorder) record(e datastore.LegendEvent) {
+	if l == nil {
+		return
+	}
+
+	e.Epoch = l.epoch
+
+	l.mu.Lock()
+	l.events = append(l.events, e)
+	l.mu.Unlock()
+
+	select {
+	case l.pending <- struct{}{}:
+	default:
+	}
+}
+
+// Returns the events waiting to be appended and forgets them.
+func (l *legendRecorder) take() []datastore.LegendEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	events := l.events
+	l.events = nil
+	return events
+}
+
+// Stops saveTo once the events that have been recorded
+// are appended. No events can be recorded afterwards.
+func (l *legendRecorder) stop() {
+	close(l.pending)
+}
+
+// Appends recorded events to the store until the recorder is stopped.
+func (l *legendRecorder) saveTo(store datastore.LegendStore) {
+	for range l.pending {
+		for _, e := range l.take() {
+			err := store.AppendLegendEvent(e)
+			if err != nil {
+				l.logger.Error("error saving legend event", "actor", e.Actor, "err", err)
+			}
+		}
+	}
+}
+
+// Npcs don't have legends.
+func (a *actor) hasLegend() bool {
+	return a.id >= 0
+}
+
+func (a *actor) recordLegend(t datastore.LegendEventType, subject string) {
+	if !a.hasLegend() {
+		return
+	}
+
+	a.legend.record(datastore.LegendEvent{
+		Actor:   a.name,
+		Type:    t,
+		At:      time.Now(),
+		Subject: subject,
+	})
+}
+
+// Records the time the actor has played since it connected.
+func (a *actor) recordTimePlayed() {
+	if !a.hasLegend() || a.connectedAt.IsZero() {
+		return
+	}
+
+	now := time.Now()
+	a.legend.record(datastore.LegendEvent{
+		Actor:    a.name,
+		Type:     datastore.LE_TIME_PLAYED,
+		At:       now,
+		Duration: now.Sub(a.connectedAt),
+	})
+}
+
+// Records the death of the actor in its legend and the
+// kill in the legend of the actor that killed it. The
+// killer's standing with the power it serves changes.
+func (phase *narrowPhase) recordKill(killedBy rpg2d.ActorId, a *actor, now stime.Time) {
+	killer, exists := phase.actorIndex[killedBy]
+	if !exists {
+		a.recordLegend(datastore.LE_DEATH, "")
+		return
+	}
+
+	a.recordLegend(datastore.LE_DEATH, killer.name)
+	killer.recordLegend(datastore.LE_KILL, a.name)
+	killer.changeStanding(killer.standingForKill(a), now)
+}
+
+// Serves the legend of the character named by the last
+// element of the request's path as a json array.
+type legendHandler struct {
+	store  datastore.LegendStore
+	logger *Logger
+}
+
+func (h legendHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	if name == "" {
+		http.Error(w, "character name is required", http.StatusBadRequest)
+		return
+	}
+
+	legend, err := h.store.Legend(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(legend)
+	if err != nil {
+		h.logger.Warn("error writing legend", "name", name, "err", err)
+	}
+}
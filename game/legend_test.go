@@ -0,0 +1,125 @@
+package game
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeLegend(c gospec.Context) {
+	store := datastore.NewMemLegendStore()
+	legend := newLegendRecorder(0)
+
+	// Appends all the recorded events to the store
+	flush := func() {
+		legend.stop()
+		legend.saveTo(store)
+	}
+
+	newLegendActor := func(id int, name string) *actor {
+		a := &actor{legend: legend}
+		a.id = rpg2d.ActorId(id)
+		a.name = name
+		a.hp, a.hpMax = 10, 10
+		return a
+	}
+
+	c.Specify("events recorded faster than they're saved", func() {
+		a := newLegendActor(0, "crafter")
+		for i := 0; i < 1000; i++ {
+			a.recordLegend(datastore.LE_ITEM_CRAFTED, "torch")
+		}
+		flush()
+
+		c.Specify("are all saved", func() {
+			l, err := store.Legend("crafter")
+			c.Assume(err, IsNil)
+			c.Expect(len(l), Equals, 1000)
+		})
+	})
+
+	c.Specify("an actor that is killed", func() {
+		killer := newLegendActor(0, "killer")
+		victim := newLegendActor(1, "victim")
+
+		phase := &narrowPhase{actorIndex: ActorIndex{killer.id: killer, victim.id: victim}}
+
+		died := victim.takeDamage(10)
+		c.Assume(died, IsTrue)
+		phase.recordKill(killer.id, victim, 1)
+		flush()
+
+		c.Specify("has the death in its legend", func() {
+			l, err := store.Legend("victim")
+			c.Assume(err, IsNil)
+			c.Assume(len(l), Equals, 1)
+			c.Expect(l[0].Type, Equals, datastore.LE_DEATH)
+			c.Expect(l[0].Subject, Equals, "killer")
+		})
+
+		c.Specify("has the kill in the killer's legend", func() {
+			l, err := store.Legend("killer")
+			c.Assume(err, IsNil)
+			c.Assume(len(l), Equals, 1)
+			c.Expect(l[0].Type, Equals, datastore.LE_KILL)
+			c.Expect(l[0].Subject, Equals, "victim")
+		})
+	})
+
+	c.Specify("an npc doesn't have a legend", func() {
+		npc := newLegendActor(-1, "npc")
+		npc.recordLegend(datastore.LE_ITEM_CRAFTED, "sword")
+		flush()
+
+		l, err := store.Legend("npc")
+		c.Assume(err, IsNil)
+		c.Expect(len(l), Equals, 0)
+	})
+
+	c.Specify("an actor that completes a craft has the item in its legend", func() {
+		a := newLegendActor(0, "crafter")
+		a.resources = Resources{}
+		a.craft = &craftAction{recipe: "torch", start: 0, end: 1}
+
+		cmds := make(chan *craftCmd, 1)
+		cmds <- nil
+		a.readCraftCmd = cmds
+
+		inputPhase{}.processCraftCmd(a, 1)
+		flush()
+
+		l, err := store.Legend("crafter")
+		c.Assume(err, IsNil)
+		c.Assume(len(l), Equals, 1)
+		c.Expect(l[0].Type, Equals, datastore.LE_ITEM_CRAFTED)
+		c.Expect(l[0].Subject, Equals, "torch")
+	})
+
+	c.Specify("a legend is served as json", func() {
+		store.AppendLegendEvent(datastore.LegendEvent{
+			Actor: "actor",
+			Type:  datastore.LE_STRUCTURE_BUILT,
+		})
+
+		w := httptest.NewRecorder()
+		legendHandler{store, nil}.ServeHTTP(w, httptest.NewRequest("GET", "/legend/actor", nil))
+		c.Assume(w.Code, Equals, http.StatusOK)
+
+		var l []datastore.LegendEvent
+		c.Assume(json.NewDecoder(w.Body).Decode(&l), IsNil)
+		c.Assume(len(l), Equals, 1)
+		c.Expect(l[0].Type, Equals, datastore.LE_STRUCTURE_BUILT)
+
+		c.Specify("and requires a character", func() {
+			w := httptest.NewRecorder()
+			legendHandler{store, nil}.ServeHTTP(w, httptest.NewRequest("GET", "/legend/", nil))
+			c.Expect(w.Code, Equals, http.StatusBadRequest)
+		})
+	})
+}
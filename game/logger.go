@@ -0,0 +1,129 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type LogLevel int
+
+const (
+	LL_DEBUG LogLevel = iota
+	LL_INFO
+	LL_WARN
+	LL_ERROR
+)
+
+var logLevelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l LogLevel) String() string {
+	if l < LL_DEBUG || l > LL_ERROR {
+		return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+	}
+	return logLevelNames[l]
+}
+
+// Returns the level named debug, info, warn or error.
+func ParseLogLevel(name string) (LogLevel, error) {
+	for l, n := range logLevelNames {
+		if strings.EqualFold(name, n) {
+			return LogLevel(l), nil
+		}
+	}
+	return LL_INFO, fmt.Errorf("unknown log level: %s", name)
+}
+
+// Lines are written by loggers without a level or fields.
+type logOutput struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+// Writes each line as the time, the level and the message
+// followed by key=value fields. Loggers created by With
+// share the output of the logger they were created from.
+// A nil logger writes to stderr at the info level.
+type Logger struct {
+	out    *logOutput
+	fields string
+}
+
+var defaultLogger = NewLogger(os.Stderr, LL_INFO)
+
+// Lines below the level are discarded.
+func NewLogger(w io.Writer, level LogLevel) *Logger {
+	return &Logger{out: &logOutput{w: w, level: level}}
+}
+
+// Returns a logger that adds the key value
+// pairs to every line it writes.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	if l == nil {
+		l = defaultLogger
+	}
+
+	var b strings.Builder
+	b.WriteString(l.fields)
+	writeLogFields(&b, kv)
+
+	return &Logger{out: l.out, fields: b.String()}
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.log(LL_DEBUG, msg, kv) }
+func (l *Logger) Info(msg string, kv ...interface{})  { l.log(LL_INFO, msg, kv) }
+func (l *Logger) Warn(msg string, kv ...interface{})  { l.log(LL_WARN, msg, kv) }
+func (l *Logger) Error(msg string, kv ...interface{}) { l.log(LL_ERROR, msg, kv) }
+
+func (l *Logger) log(level LogLevel, msg string, kv []interface{}) {
+	if l == nil {
+		l = defaultLogger
+	}
+
+	if level < l.out.level {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(time.Now().Format("2006/01/02 15:04:05"))
+	b.WriteString(" level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	b.WriteString(logValue(msg))
+	b.WriteString(l.fields)
+	writeLogFields(&b, kv)
+	b.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	io.WriteString(l.out.w, b.String())
+}
+
+func writeLogFields(b *strings.Builder, kv []interface{}) {
+	for i := 0; i < len(kv); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(kv[i]))
+		b.WriteByte('=')
+
+		if i+1 < len(kv) {
+			b.WriteString(logValue(kv[i+1]))
+		} else {
+			b.WriteString(`""`)
+		}
+	}
+}
+
+// Values are quoted if they're empty or contain
+// spaces, quotes or equal signs.
+func logValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
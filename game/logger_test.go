@@ -0,0 +1,79 @@
+package game
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+// Returns the lines written without the time.
+func loggedLines(buf *bytes.Buffer) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+
+		// The date and time are the first 2 fields
+		lines = append(lines, strings.SplitN(line, " ", 3)[2])
+	}
+	return lines
+}
+
+func DescribeLogger(c gospec.Context) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LL_INFO)
+
+	c.Specify("a logger", func() {
+		c.Specify("writes the level, message and fields", func() {
+			logger.Info("actor kicked", "tick", 120, "reason", "spamming chat")
+			c.Expect(loggedLines(&buf), ContainsExactly, []string{
+				`level=info msg="actor kicked" tick=120 reason="spamming chat"`,
+			})
+		})
+
+		c.Specify("discards lines below its level", func() {
+			logger.Debug("hidden")
+			logger.Warn("shown", "err", errors.New("failed"))
+			c.Expect(loggedLines(&buf), ContainsExactly, []string{
+				`level=warn msg=shown err=failed`,
+			})
+		})
+
+		c.Specify("tags every line with the fields it was created with", func() {
+			conn := logger.With("conn", 3)
+			actor := conn.With("actor", "Alice", "id", 5)
+
+			conn.Info("connection opened")
+			actor.Error("error sculpting terrain", "tick", 7)
+
+			c.Expect(loggedLines(&buf), ContainsExactly, []string{
+				`level=info msg="connection opened" conn=3`,
+				`level=error msg="error sculpting terrain" conn=3 actor=Alice id=5 tick=7`,
+			})
+		})
+
+		c.Specify("quotes values that are empty or contain equal signs", func() {
+			logger.Info("values", "a", "", "b", "x=y")
+			c.Expect(loggedLines(&buf), ContainsExactly, []string{
+				`level=info msg=values a="" b="x=y"`,
+			})
+		})
+	})
+
+	c.Specify("a log level", func() {
+		c.Specify("can be parsed from its name", func() {
+			level, err := ParseLogLevel("WARN")
+			c.Assume(err, IsNil)
+			c.Expect(level, Equals, LL_WARN)
+		})
+
+		c.Specify("is invalid if the name is unknown", func() {
+			_, err := ParseLogLevel("verbose")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+}
@@ -0,0 +1,361 @@
+package game
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// The phases of a tick that are timed.
+type tickPhase int
+
+const (
+	TP_INPUT tickPhase = iota
+	TP_UPDATE
+	TP_NARROW
+	TP_SIZE
+)
+
+var tickPhaseNames = [TP_SIZE]string{"input", "update", "narrow"}
+
+// The stats of a single tick of a zone.
+type ZoneStats struct {
+	Bounds coord.Bounds
+	Tick   stime.Time
+
+	// Time spent in the handlers of each phase. Handlers
+	// may run concurrently so this can be longer than
+	// the tick itself.
+	Phases [TP_SIZE]time.Duration
+
+	// The entities in the zone's quad tree by kind
+	Entities map[string]int
+}
+
+// Returns the number of entities in the zone's quad tree.
+func (s ZoneStats) QuadTreeSize() int {
+	var size int
+	for _, n := range s.Entities {
+		size += n
+	}
+	return size
+}
+
+// Records the stats of a zone during each tick. The stats
+// of a tick are complete once the next tick has begun.
+type tickMetrics struct {
+	mu   sync.Mutex
+	tick ZoneStats
+	last ZoneStats
+}
+
+// Must be called with the lock held.
+func (m *tickMetrics) beginTick(now stime.Time) {
+	if m.tick.Entities != nil && m.tick.Tick == now {
+		return
+	}
+
+	if m.tick.Entities != nil {
+		m.last = m.tick
+		metrics.observeTick(m.last)
+	}
+
+	m.tick = ZoneStats{Tick: now, Entities: make(map[string]int)}
+}
+
+// Adds the time since the handler of the phase
+// began to the time spent in the phase.
+func (m *tickMetrics) timePhase(p tickPhase, now stime.Time, began time.Time) {
+	if m == nil {
+		return
+	}
+
+	d := time.Since(began)
+
+	m.mu.Lock()
+	m.beginTick(now)
+	m.tick.Phases[p] += d
+	m.mu.Unlock()
+}
+
+// Is called by the update phase with
+// every entity in the quad tree.
+func (m *tickMetrics) countEntity(e entity.Entity, index ActorIndex, now stime.Time) {
+	if m == nil {
+		return
+	}
+
+	kind := entityKind(e, index)
+
+	m.mu.Lock()
+	m.beginTick(now)
+	m.tick.Entities[kind]++
+	m.mu.Unlock()
+}
+
+// Returns the stats of the last complete tick.
+func (m *tickMetrics) stats() ZoneStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.last
+	s.Entities = make(map[string]int, len(m.last.Entities))
+	for kind, n := range m.last.Entities {
+		s.Entities[kind] = n
+	}
+	return s
+}
+
+func entityKind(e entity.Entity, index ActorIndex) string {
+	switch e := e.(type) {
+	case actorEntity:
+		if a := index[e.ActorId()]; a != nil && !a.hasLegend() {
+			return "npc"
+		}
+		return "actor"
+	case assailEntity:
+		return "assail"
+	case aoeEntity:
+		return "aoe"
+	case sayEntity:
+		return "say"
+	case wallEntity:
+		return "wall"
+	case structureEntity:
+		return "structure"
+	case resourceNodeEntity:
+		return "resource_node"
+	case entity.Removed:
+		return "removed"
+	default:
+		return "other"
+	}
+}
+
+// A histogram with fixed upper bounds for its buckets.
+type histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+
+	// The last count is of the values
+	// greater than every bucket.
+	counts []uint64
+	sum    float64
+}
+
+func newHistogram(buckets ...float64) *histogram {
+	return &histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.mu.Unlock()
+}
+
+// Labels must be empty or end with a comma.
+func (h *histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var count uint64
+	for i, le := range h.buckets {
+		count += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, formatMetric(le), count)
+	}
+	count += h.counts[len(h.buckets)]
+
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, metricLabels(labels), formatMetric(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, metricLabels(labels), count)
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func metricLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + strings.TrimSuffix(labels, ",") + "}"
+}
+
+func writeMetricHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// The metrics recorded by every shard in the process.
+var metrics = newShardMetrics()
+
+type shardMetrics struct {
+	phaseSeconds [TP_SIZE]*histogram
+
+	diffBytes    *histogram
+	diffEntities *histogram
+
+	mu        sync.Mutex
+	sentBytes map[EncodedType]uint64
+	sentMsgs  map[EncodedType]uint64
+
+	evictions uint64
+}
+
+func newShardMetrics() *shardMetrics {
+	m := &shardMetrics{
+		diffBytes:    newHistogram(64, 256, 1024, 4096, 16384, 65536),
+		diffEntities: newHistogram(1, 2, 5, 10, 25, 50, 100, 250),
+
+		sentBytes: make(map[EncodedType]uint64),
+		sentMsgs:  make(map[EncodedType]uint64),
+	}
+
+	// A tick must be simulated in 25ms at 40fps
+	for i := range m.phaseSeconds {
+		m.phaseSeconds[i] = newHistogram(.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05)
+	}
+
+	return m
+}
+
+func (m *shardMetrics) observeTick(s ZoneStats) {
+	for p, d := range s.Phases {
+		m.phaseSeconds[p].observe(d.Seconds())
+	}
+}
+
+// Records a message that was sent to a client and the bytes
+// written. The bytes are recorded even if sending failed.
+func (m *shardMetrics) sent(t EncodedType, v interface{}, n int, err error) {
+	m.mu.Lock()
+	m.sentBytes[t] += uint64(n)
+	if err == nil {
+		m.sentMsgs[t]++
+	}
+	m.mu.Unlock()
+
+	if diff, ok := v.(rpg2d.WorldStateDiff); ok && err == nil {
+		m.diffBytes.observe(float64(n))
+		m.diffEntities.observe(float64(len(diff.Entities) + len(diff.Removed)))
+	}
+}
+
+func (m *shardMetrics) evicted() {
+	atomic.AddUint64(&m.evictions, 1)
+}
+
+func (m *shardMetrics) write(w io.Writer) {
+	writeMetricHeader(w, "aodd_tick_phase_seconds", "histogram",
+		"Time spent in the handlers of a phase of the simulation during a tick of a zone.")
+	for p, h := range m.phaseSeconds {
+		h.write(w, "aodd_tick_phase_seconds", fmt.Sprintf("phase=%q,", tickPhaseNames[p]))
+	}
+
+	m.mu.Lock()
+	types := make([]EncodedType, 0, len(m.sentBytes))
+	for t := range m.sentBytes {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	writeMetricHeader(w, "aodd_sent_bytes_total", "counter",
+		"Bytes sent to clients by the type of message.")
+	for _, t := range types {
+		fmt.Fprintf(w, "aodd_sent_bytes_total{type=%q} %d\n", t.String(), m.sentBytes[t])
+	}
+
+	writeMetricHeader(w, "aodd_sent_messages_total", "counter",
+		"Messages sent to clients by the type of message.")
+	for _, t := range types {
+		fmt.Fprintf(w, "aodd_sent_messages_total{type=%q} %d\n", t.String(), m.sentMsgs[t])
+	}
+	m.mu.Unlock()
+
+	writeMetricHeader(w, "aodd_world_state_diff_bytes", "histogram",
+		"Size of the world state diffs sent to clients.")
+	m.diffBytes.write(w, "aodd_world_state_diff_bytes", "")
+
+	writeMetricHeader(w, "aodd_world_state_diff_entities", "histogram",
+		"Entities changed or removed by the world state diffs sent to clients.")
+	m.diffEntities.write(w, "aodd_world_state_diff_entities", "")
+
+	writeMetricHeader(w, "aodd_slow_client_evictions_total", "counter",
+		"Clients disconnected because they didn't read what they were sent in time.")
+	fmt.Fprintf(w, "aodd_slow_client_evictions_total %d\n", atomic.LoadUint64(&m.evictions))
+}
+
+// Serves the metrics of the process and the shard's
+// current world in the Prometheus text format.
+type metricsHandler struct {
+	shard *shard
+}
+
+func (h metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.shard.mu.RLock()
+	world := h.shard.world
+	h.shard.mu.RUnlock()
+
+	zones := world.zones.stats()
+
+	var connected int
+	for _, a := range world.zones.allActors() {
+		if a.hasLegend() {
+			connected++
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	buf := bufio.NewWriter(w)
+	defer buf.Flush()
+
+	metrics.write(buf)
+
+	writeMetricHeader(buf, "aodd_connected_actors", "gauge",
+		"Actors controlled by a connection that are being simulated.")
+	fmt.Fprintf(buf, "aodd_connected_actors %d\n", connected)
+
+	entities := make(map[string]int)
+	for _, s := range zones {
+		for kind, n := range s.Entities {
+			entities[kind] += n
+		}
+	}
+
+	kinds := make([]string, 0, len(entities))
+	for kind := range entities {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	writeMetricHeader(buf, "aodd_entities", "gauge",
+		"Entities in the world by kind as of the last tick of each zone.")
+	for _, kind := range kinds {
+		fmt.Fprintf(buf, "aodd_entities{kind=%q} %d\n", kind, entities[kind])
+	}
+
+	writeMetricHeader(buf, "aodd_quad_tree_entities", "gauge",
+		"Entities in the quad tree of each zone as of its last tick.")
+	for _, s := range zones {
+		b := s.Bounds
+		fmt.Fprintf(buf, "aodd_quad_tree_entities{zone=\"%d,%d %d,%d\"} %d\n",
+			b.TopL.X, b.TopL.Y, b.BotR.X, b.BotR.Y, s.QuadTreeSize())
+	}
+}
@@ -0,0 +1,120 @@
+package game
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeMetrics(c gospec.Context) {
+	c.Specify("a histogram", func() {
+		h := newHistogram(1, 5, 10)
+		for _, v := range []float64{0.5, 1, 3, 7, 20} {
+			h.observe(v)
+		}
+
+		var buf bytes.Buffer
+		h.write(&buf, "test", `a="b",`)
+
+		c.Specify("writes the cumulative count of each bucket", func() {
+			c.Expect(buf.String(), Equals, strings.Join([]string{
+				`test_bucket{a="b",le="1"} 2`,
+				`test_bucket{a="b",le="5"} 3`,
+				`test_bucket{a="b",le="10"} 4`,
+				`test_bucket{a="b",le="+Inf"} 5`,
+				`test_sum{a="b"} 31.5`,
+				`test_count{a="b"} 5`,
+				``,
+			}, "\n"))
+		})
+	})
+
+	c.Specify("the stats of a zone's tick", func() {
+		m := &tickMetrics{}
+
+		npc := &actor{}
+		npc.id = -1
+		npc.actorEntity.actorId = 1
+		index := ActorIndex{1: npc}
+
+		m.countEntity(wallEntity{id: 1}, index, 1)
+		m.countEntity(wallEntity{id: 2}, index, 1)
+		m.countEntity(npc.actorEntity, index, 1)
+		m.timePhase(TP_INPUT, 1, time.Now().Add(-time.Millisecond))
+
+		c.Specify("are incomplete until the next tick has begun", func() {
+			c.Expect(m.stats().QuadTreeSize(), Equals, 0)
+		})
+
+		c.Specify("are complete once the next tick has begun", func() {
+			m.countEntity(wallEntity{id: 1}, index, 2)
+
+			s := m.stats()
+			c.Expect(s.Tick, Equals, stime.Time(1))
+			c.Expect(s.QuadTreeSize(), Equals, 3)
+			c.Expect(s.Entities["wall"], Equals, 2)
+			c.Expect(s.Entities["npc"], Equals, 1)
+			c.Expect(s.Phases[TP_INPUT] >= time.Millisecond, IsTrue)
+		})
+	})
+
+	c.Specify("a connection to a client", func() {
+		server, client := net.Pipe()
+		defer server.Close()
+		defer client.Close()
+
+		conn := newShardConn(server, func() { server.Close() }, nil).(shardConn)
+		conn.timeout = 10 * time.Millisecond
+
+		sentMsgs := func(t EncodedType) uint64 {
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			return metrics.sentMsgs[t]
+		}
+
+		c.Specify("records the messages and bytes sent", func() {
+			read := make(chan int)
+			go func() {
+				buf := make([]byte, 4096)
+				n, _ := client.Read(buf)
+				read <- n
+			}()
+
+			before := sentMsgs(ET_CONNECTED)
+			c.Assume(conn.EncodeAndSend(ET_CONNECTED, "hi"), IsNil)
+
+			c.Expect(sentMsgs(ET_CONNECTED), Equals, before+1)
+			c.Expect(conn.written.n, Equals, <-read)
+		})
+
+		c.Specify("is evicted if the client doesn't read in time", func() {
+			evictions := atomic.LoadUint64(&metrics.evictions)
+
+			c.Expect(conn.EncodeAndSend(ET_CONNECTED, "hi"), Not(IsNil))
+			c.Expect(atomic.LoadUint64(&metrics.evictions), Equals, evictions+1)
+		})
+	})
+
+	c.Specify("the metrics of a zone", func() {
+		z, _ := newTestZone(coord.Bounds{coord.Cell{1, -1}, coord.Cell{64, -64}})
+
+		var e entity.Entity = wallEntity{id: 1}
+		z.metrics.countEntity(e, nil, 1)
+		z.metrics.countEntity(e, nil, 2)
+
+		c.Specify("include its bounds", func() {
+			s := z.Stats()
+			c.Expect(s.Bounds, Equals, z.bounds)
+			c.Expect(s.Entities["wall"], Equals, 1)
+		})
+	})
+}
@@ -11,16 +11,18 @@ func _() {
 	_ = x[MR_ERROR-0]
 	_ = x[MR_MOVE-1]
 	_ = x[MR_MOVE_CANCEL-2]
-	_ = x[MR_SIZE-3]
+	_ = x[MR_MOVE_TO-3]
+	_ = x[MR_SIZE-4]
 }
 
-const _MoveRequestType_name = "MR_ERRORMR_MOVEMR_MOVE_CANCELMR_SIZE"
+const _MoveRequestType_name = "MR_ERRORMR_MOVEMR_MOVE_CANCELMR_MOVE_TOMR_SIZE"
 
-var _MoveRequestType_index = [...]uint8{0, 8, 15, 29, 36}
+var _MoveRequestType_index = [...]uint8{0, 8, 15, 29, 39, 46}
 
 func (i MoveRequestType) String() string {
-	if i < 0 || i >= MoveRequestType(len(_MoveRequestType_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_MoveRequestType_index)-1 {
 		return "MoveRequestType(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _MoveRequestType_name[_MoveRequestType_index[i]:_MoveRequestType_index[i+1]]
+	return _MoveRequestType_name[_MoveRequestType_index[idx]:_MoveRequestType_index[idx+1]]
 }
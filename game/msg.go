@@ -0,0 +1,69 @@
+package game
+
+import "sync"
+
+// A message that is sent privately to the connection
+// that controls an actor. Unlike entities, messages are
+// not part of the world state and are never culled by
+// an actor's viewport.
+type ActorMsg interface{}
+
+// The messages queued for an actor during a single
+// tick of the simulation.
+type ActorMsgs struct {
+	Msgs []ActorMsg
+}
+
+// Queues a message that will be sent to the actor's
+// connection before the diff of the tick it was queued during.
+func (a *actorConn) queueMsg(msg ActorMsg) {
+	a.msgs = append(a.msgs, msg)
+}
+
+// Messages delivered to an actor by other actors. Other
+// actors may be simulated on other go routines so the
+// messages are held until the actor's next state is written.
+type msgInbox struct {
+	mu   sync.Mutex
+	msgs []ActorMsg
+}
+
+// Delivers a message that will be sent to the actor's
+// connection after its next world state has been written.
+// Is safe to call from any go routine.
+func (a *actorConn) deliverMsg(msg ActorMsg) {
+	if a.inbox == nil {
+		return
+	}
+
+	a.inbox.mu.Lock()
+	a.inbox.msgs = append(a.inbox.msgs, msg)
+	a.inbox.mu.Unlock()
+}
+
+// Sends the messages that were delivered since the actor's
+// last state was written, such as the reason it was kicked.
+// Must only be called once the actor has been removed from
+// the simulation and before its IO is stopped.
+func (a *actorConn) sendDeliveredMsgs() {
+	a.queueDeliveredMsgs()
+
+	// Actors that haven't received the initial world
+	// state have no connection to send messages to.
+	if len(a.msgs) > 0 && a.initialState != nil {
+		a.sendMsgs <- a.msgs
+	}
+	a.msgs = nil
+}
+
+// Moves the delivered messages into the queue.
+func (a *actorConn) queueDeliveredMsgs() {
+	if a.inbox == nil {
+		return
+	}
+
+	a.inbox.mu.Lock()
+	a.msgs = append(a.msgs, a.inbox.msgs...)
+	a.inbox.msgs = nil
+	a.inbox.mu.Unlock()
+}
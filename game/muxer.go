@@ -1,6 +1,8 @@
 package game
 
 import (
+	"io"
+
 	"github.com/ghthor/filu/rpg2d"
 	"github.com/ghthor/filu/rpg2d/coord"
 	"github.com/ghthor/filu/rpg2d/entity"
@@ -12,21 +14,35 @@ type InitialStateWriter interface {
 
 type DiffWriter interface {
 	WriteWorldStateDiff(rpg2d.WorldStateDiff)
+	WriteActorMsgs(ActorMsgs)
 }
 
 type actorConn struct {
 	// Comm interface to muxer used by SubmitCmd() method
-	submitMoveRequest chan<- MoveRequest
-	submitUseRequest  chan<- UseRequest
-	submitChatRequest chan<- ChatRequest
-
-	readMoveCmd <-chan *moveCmd
-	readUseCmd  <-chan *useCmd
-	readChatCmd <-chan *chatCmd
+	submitMoveRequest  chan<- MoveRequest
+	submitUseRequest   chan<- UseRequest
+	submitChatRequest  chan<- ChatRequest
+	submitBuildRequest chan<- BuildRequest
+	submitCraftRequest chan<- CraftRequest
+	submitEquipRequest chan<- EquipRequest
+	submitEmoteRequest chan<- EmoteRequest
+	submitPartyRequest chan<- PartyRequest
+	submitTradeRequest chan<- TradeRequest
+
+	readMoveCmd  <-chan *moveCmd
+	readUseCmd   <-chan *useCmd
+	readChatCmd  <-chan *chatCmd
+	readBuildCmd <-chan *buildCmd
+	readCraftCmd <-chan *craftCmd
+	readEquipCmd <-chan *equipCmd
+	readEmoteCmd <-chan *emoteCmd
+	readPartyCmd <-chan *partyCmd
+	readTradeCmd <-chan *tradeCmd
 
 	// Comm interface to muxer used to send world states
 	sendState chan<- *rpg2d.WorldState
 	sendDiff  chan<- *rpg2d.WorldStateDiff
+	sendMsgs  chan<- []ActorMsg
 
 	// Comm interface to muxer used by stopIO() method
 	stop chan<- chan<- struct{}
@@ -34,16 +50,32 @@ type actorConn struct {
 	// External connection used to publish the initial world state
 	conn InitialStateWriter
 
+	// Ends the external connection. Is nil
+	// if the connection can't be closed.
+	closer io.Closer
+
 	initialState *rpg2d.WorldState
 	prevState    rpg2d.WorldState
 	nextState    rpg2d.WorldState
 
 	diff rpg2d.WorldStateDiff
+
+	// Messages queued during the current tick
+	msgs []ActorMsg
+
+	// Messages delivered by other actors
+	inbox *msgInbox
+
+	// Changes players have made to the world
+	edits *worldEdits
 }
 
 func newActorConn(conn InitialStateWriter) actorConn {
+	closer, _ := conn.(io.Closer)
+
 	return actorConn{
-		conn: conn,
+		conn:   conn,
+		closer: closer,
 		prevState: rpg2d.WorldState{
 			Entities: make(entity.StateSlice, 0, 1),
 		},
@@ -58,64 +90,124 @@ func newActorConn(conn InitialStateWriter) actorConn {
 }
 
 func (a *actorConn) startIO() {
+	a.inbox = &msgInbox{}
+
 	// Setup communication channels
 	moveReqCh := make(chan MoveRequest, 2)
 	useReqCh := make(chan UseRequest, 2)
 	chatReqCh := make(chan ChatRequest, 2)
+	buildReqCh := make(chan BuildRequest, 2)
+	craftReqCh := make(chan CraftRequest, 2)
+	equipReqCh := make(chan EquipRequest, 2)
+	emoteReqCh := make(chan EmoteRequest, 2)
+	partyReqCh := make(chan PartyRequest, 2)
+	tradeReqCh := make(chan TradeRequest, 2)
 
 	moveCmdCh := make(chan *moveCmd)
 	useCmdCh := make(chan *useCmd)
 	chatCmdCh := make(chan *chatCmd)
+	buildCmdCh := make(chan *buildCmd)
+	craftCmdCh := make(chan *craftCmd)
+	equipCmdCh := make(chan *equipCmd)
+	emoteCmdCh := make(chan *emoteCmd)
+	partyCmdCh := make(chan *partyCmd)
+	tradeCmdCh := make(chan *tradeCmd)
 
 	stateOutputCh := make(chan *rpg2d.WorldState)
 	diffOutputCh := make(chan *rpg2d.WorldStateDiff)
+	msgsOutputCh := make(chan []ActorMsg)
 	stopCh := make(chan chan<- struct{})
 
 	// Set the channels accessible to the outside world
 	a.submitMoveRequest = moveReqCh
 	a.submitUseRequest = useReqCh
 	a.submitChatRequest = chatReqCh
+	a.submitBuildRequest = buildReqCh
+	a.submitCraftRequest = craftReqCh
+	a.submitEquipRequest = equipReqCh
+	a.submitEmoteRequest = emoteReqCh
+	a.submitPartyRequest = partyReqCh
+	a.submitTradeRequest = tradeReqCh
 
 	a.readMoveCmd = moveCmdCh
 	a.readUseCmd = useCmdCh
 	a.readChatCmd = chatCmdCh
+	a.readBuildCmd = buildCmdCh
+	a.readCraftCmd = craftCmdCh
+	a.readEquipCmd = equipCmdCh
+	a.readEmoteCmd = emoteCmdCh
+	a.readPartyCmd = partyCmdCh
+	a.readTradeCmd = tradeCmdCh
 
 	a.sendState = stateOutputCh
 	a.sendDiff = diffOutputCh
+	a.sendMsgs = msgsOutputCh
 	a.stop = stopCh
 
 	// Establish the channel endpoints used inside the go routine
 	var newMoveRequest <-chan MoveRequest
 	var newUseRequest <-chan UseRequest
 	var newChatRequest <-chan ChatRequest
+	var newBuildRequest <-chan BuildRequest
+	var newCraftRequest <-chan CraftRequest
+	var newEquipRequest <-chan EquipRequest
+	var newEmoteRequest <-chan EmoteRequest
+	var newPartyRequest <-chan PartyRequest
+	var newTradeRequest <-chan TradeRequest
 
 	var sendMoveCmd chan<- *moveCmd
 	var sendUseCmd chan<- *useCmd
 	var sendChatCmd chan<- *chatCmd
+	var sendBuildCmd chan<- *buildCmd
+	var sendCraftCmd chan<- *craftCmd
+	var sendEquipCmd chan<- *equipCmd
+	var sendEmoteCmd chan<- *emoteCmd
+	var sendPartyCmd chan<- *partyCmd
+	var sendTradeCmd chan<- *tradeCmd
 
 	var newState <-chan *rpg2d.WorldState
 	var newDiff <-chan *rpg2d.WorldStateDiff
+	var newMsgs <-chan []ActorMsg
 	var stopReq <-chan chan<- struct{}
 
 	newMoveRequest = moveReqCh
 	newUseRequest = useReqCh
 	newChatRequest = chatReqCh
+	newBuildRequest = buildReqCh
+	newCraftRequest = craftReqCh
+	newEquipRequest = equipReqCh
+	newEmoteRequest = emoteReqCh
+	newPartyRequest = partyReqCh
+	newTradeRequest = tradeReqCh
 
 	sendMoveCmd = moveCmdCh
 	sendUseCmd = useCmdCh
 	sendChatCmd = chatCmdCh
+	sendBuildCmd = buildCmdCh
+	sendCraftCmd = craftCmdCh
+	sendEquipCmd = equipCmdCh
+	sendEmoteCmd = emoteCmdCh
+	sendPartyCmd = partyCmdCh
+	sendTradeCmd = tradeCmdCh
 
 	newState = stateOutputCh
 	newDiff = diffOutputCh
+	newMsgs = msgsOutputCh
 	stopReq = stopCh
 
 	go func() {
 		var hasStopped chan<- struct{}
 
 		cmd := struct {
-			moveCmd *moveCmd
-			useCmd  *useCmd
-			chatCmd *chatCmd
+			moveCmd  *moveCmd
+			useCmd   *useCmd
+			chatCmd  *chatCmd
+			buildCmd *buildCmd
+			craftCmd *craftCmd
+			equipCmd *equipCmd
+			emoteCmd *emoteCmd
+			partyCmd *partyCmd
+			tradeCmd *tradeCmd
 		}{}
 
 		updateMoveCmdWith := func(r MoveRequest) {
@@ -127,10 +219,17 @@ func (a *actorConn) startIO() {
 				}
 			case MR_MOVE_CANCEL:
 				if cmd.moveCmd != nil {
-					if cmd.moveCmd.Direction == r.Direction {
+					// Any cancel will stop an actor moving to a cell
+					if cmd.moveCmd.dest != nil || cmd.moveCmd.Direction == r.Direction {
 						cmd.moveCmd = nil
 					}
 				}
+			case MR_MOVE_TO:
+				dest := r.Dest
+				cmd.moveCmd = &moveCmd{
+					Time: r.Time,
+					dest: &dest,
+				}
 			}
 		}
 
@@ -156,10 +255,66 @@ func (a *actorConn) startIO() {
 				ChatRequestType: r.ChatRequestType,
 				Time:            r.Time,
 				msg:             r.Msg,
+				to:              r.To,
 			}
+
+			// The sender is told about the requests that
+			// were replaced before they could be sent.
+			if cmd.chatCmd != nil {
+				chatCmd.dropped = cmd.chatCmd.dropped + 1
+			}
+
 			cmd.chatCmd = &chatCmd
 		}
 
+		updateBuildCmdWith := func(r BuildRequest) {
+			cmd.buildCmd = &buildCmd{
+				BuildRequestType: r.BuildRequestType,
+				Time:             r.Time,
+				cell:             r.Cell,
+				terrain:          r.Terrain,
+			}
+		}
+
+		updateCraftCmdWith := func(r CraftRequest) {
+			cmd.craftCmd = &craftCmd{
+				Time:   r.Time,
+				recipe: r.Recipe,
+			}
+		}
+
+		updateEquipCmdWith := func(r EquipRequest) {
+			cmd.equipCmd = &equipCmd{
+				Time: r.Time,
+				slot: r.Slot,
+				item: r.Item,
+			}
+		}
+
+		updateEmoteCmdWith := func(r EmoteRequest) {
+			cmd.emoteCmd = &emoteCmd{
+				Time:  r.Time,
+				emote: r.Emote,
+			}
+		}
+
+		updatePartyCmdWith := func(r PartyRequest) {
+			cmd.partyCmd = &partyCmd{
+				PartyRequestType: r.PartyRequestType,
+				Time:             r.Time,
+				name:             r.Name,
+			}
+		}
+
+		updateTradeCmdWith := func(r TradeRequest) {
+			cmd.tradeCmd = &tradeCmd{
+				TradeRequestType: r.TradeRequestType,
+				Time:             r.Time,
+				name:             r.Name,
+				items:            r.Items,
+			}
+		}
+
 		var diffWriter DiffWriter
 
 		// Wait for the initial world state
@@ -170,6 +325,18 @@ func (a *actorConn) startIO() {
 			case sendUseCmd <- cmd.useCmd:
 			case sendChatCmd <- cmd.chatCmd:
 				cmd.chatCmd = nil
+			case sendBuildCmd <- cmd.buildCmd:
+				cmd.buildCmd = nil
+			case sendCraftCmd <- cmd.craftCmd:
+				cmd.craftCmd = nil
+			case sendEquipCmd <- cmd.equipCmd:
+				cmd.equipCmd = nil
+			case sendEmoteCmd <- cmd.emoteCmd:
+				cmd.emoteCmd = nil
+			case sendPartyCmd <- cmd.partyCmd:
+				cmd.partyCmd = nil
+			case sendTradeCmd <- cmd.tradeCmd:
+				cmd.tradeCmd = nil
 			case state := <-newState:
 				if state != nil {
 					diffWriter = a.conn.WriteWorldState(*state)
@@ -190,7 +357,13 @@ func (a *actorConn) startIO() {
 		// 1. ReadMoveCmd() method requests the actor's movement cmd
 		// 2. ReadUseCmd() method requests the actor's use cmd
 		// 3. ReadChatCmd() method requests the actor's chat cmd
-		// 4. stopIO() method has been called
+		// 4. ReadBuildCmd() method requests the actor's build cmd
+		// 5. ReadCraftCmd() method requests the actor's craft cmd
+		// 6. ReadEquipCmd() method requests the actor's equip cmd
+		// 7. ReadEmoteCmd() method requests the actor's emote cmd
+		// 8. ReadPartyCmd() method requests the actor's party cmd
+		// 9. ReadTradeCmd() method requests the actor's trade cmd
+		// 10. stopIO() method has been called
 		select {
 		case sendMoveCmd <- cmd.moveCmd:
 			goto locked
@@ -199,6 +372,24 @@ func (a *actorConn) startIO() {
 		case sendChatCmd <- cmd.chatCmd:
 			cmd.chatCmd = nil
 			goto locked
+		case sendBuildCmd <- cmd.buildCmd:
+			cmd.buildCmd = nil
+			goto locked
+		case sendCraftCmd <- cmd.craftCmd:
+			cmd.craftCmd = nil
+			goto locked
+		case sendEquipCmd <- cmd.equipCmd:
+			cmd.equipCmd = nil
+			goto locked
+		case sendEmoteCmd <- cmd.emoteCmd:
+			cmd.emoteCmd = nil
+			goto locked
+		case sendPartyCmd <- cmd.partyCmd:
+			cmd.partyCmd = nil
+			goto locked
+		case sendTradeCmd <- cmd.tradeCmd:
+			cmd.tradeCmd = nil
+			goto locked
 
 		case hasStopped = <-stopReq:
 			goto exit
@@ -206,11 +397,17 @@ func (a *actorConn) startIO() {
 		}
 
 		// ## 3 potential events to respond to
-		// 1. SubmitCmd() method has been called with a new move/use/chat request
+		// 1. SubmitCmd() method has been called with a new move/use/chat/build/craft/equip/emote/party/trade request
 		// 2. ReadMoveCmd() method requests the actor's movement cmd
 		// 3. ReadUseCmd() method requests the actor's use cmd
 		// 4. ReadChatCmd() method requests the actor's chat cmd
-		// 5. stopIO() method has been called
+		// 5. ReadBuildCmd() method requests the actor's build cmd
+		// 6. ReadCraftCmd() method requests the actor's craft cmd
+		// 7. ReadEquipCmd() method requests the actor's equip cmd
+		// 8. ReadEmoteCmd() method requests the actor's emote cmd
+		// 9. ReadPartyCmd() method requests the actor's party cmd
+		// 10. ReadTradeCmd() method requests the actor's trade cmd
+		// 11. stopIO() method has been called
 		select {
 		case r := <-newMoveRequest:
 			updateMoveCmdWith(r)
@@ -221,6 +418,24 @@ func (a *actorConn) startIO() {
 		case r := <-newChatRequest:
 			updateChatCmdWith(r)
 			goto unlocked
+		case r := <-newBuildRequest:
+			updateBuildCmdWith(r)
+			goto unlocked
+		case r := <-newCraftRequest:
+			updateCraftCmdWith(r)
+			goto unlocked
+		case r := <-newEquipRequest:
+			updateEquipCmdWith(r)
+			goto unlocked
+		case r := <-newEmoteRequest:
+			updateEmoteCmdWith(r)
+			goto unlocked
+		case r := <-newPartyRequest:
+			updatePartyCmdWith(r)
+			goto unlocked
+		case r := <-newTradeRequest:
+			updateTradeCmdWith(r)
+			goto unlocked
 
 		case diff := <-newDiff:
 			if diff != nil {
@@ -229,6 +444,10 @@ func (a *actorConn) startIO() {
 
 			goto unlocked
 
+		case msgs := <-newMsgs:
+			diffWriter.WriteActorMsgs(ActorMsgs{msgs})
+			goto unlocked
+
 		case sendMoveCmd <- cmd.moveCmd:
 			goto locked
 		case sendUseCmd <- cmd.useCmd:
@@ -236,6 +455,24 @@ func (a *actorConn) startIO() {
 		case sendChatCmd <- cmd.chatCmd:
 			cmd.chatCmd = nil
 			goto locked
+		case sendBuildCmd <- cmd.buildCmd:
+			cmd.buildCmd = nil
+			goto locked
+		case sendCraftCmd <- cmd.craftCmd:
+			cmd.craftCmd = nil
+			goto locked
+		case sendEquipCmd <- cmd.equipCmd:
+			cmd.equipCmd = nil
+			goto locked
+		case sendEmoteCmd <- cmd.emoteCmd:
+			cmd.emoteCmd = nil
+			goto locked
+		case sendPartyCmd <- cmd.partyCmd:
+			cmd.partyCmd = nil
+			goto locked
+		case sendTradeCmd <- cmd.tradeCmd:
+			cmd.tradeCmd = nil
+			goto locked
 
 		case hasStopped = <-stopReq:
 			goto exit
@@ -248,10 +485,17 @@ func (a *actorConn) startIO() {
 
 		// ## 3 potential events to respond to
 		// 1. WriteState() method has been called with a new world state
-		// 2. ReadMoveCmd() method requests the actor's move command
-		// 3. ReadUseCmd() method requests the actor's use command
-		// 4. ReadChatCmd() method requests the actor's chat command
-		// 5. stopIO() method has been called
+		// 2. WriteState() method has been called with queued messages
+		// 3. ReadMoveCmd() method requests the actor's move command
+		// 4. ReadUseCmd() method requests the actor's use command
+		// 5. ReadChatCmd() method requests the actor's chat command
+		// 6. ReadBuildCmd() method requests the actor's build command
+		// 7. ReadCraftCmd() method requests the actor's craft command
+		// 8. ReadEquipCmd() method requests the actor's equip command
+		// 9. ReadEmoteCmd() method requests the actor's emote command
+		// 10. ReadPartyCmd() method requests the actor's party command
+		// 11. ReadTradeCmd() method requests the actor's trade command
+		// 12. stopIO() method has been called
 		select {
 		case diff := <-newDiff:
 			if diff != nil {
@@ -260,6 +504,10 @@ func (a *actorConn) startIO() {
 
 			goto unlocked
 
+		case msgs := <-newMsgs:
+			diffWriter.WriteActorMsgs(ActorMsgs{msgs})
+			goto locked
+
 		case sendMoveCmd <- cmd.moveCmd:
 			goto locked
 		case sendUseCmd <- cmd.useCmd:
@@ -267,6 +515,24 @@ func (a *actorConn) startIO() {
 		case sendChatCmd <- cmd.chatCmd:
 			cmd.chatCmd = nil
 			goto locked
+		case sendBuildCmd <- cmd.buildCmd:
+			cmd.buildCmd = nil
+			goto locked
+		case sendCraftCmd <- cmd.craftCmd:
+			cmd.craftCmd = nil
+			goto locked
+		case sendEquipCmd <- cmd.equipCmd:
+			cmd.equipCmd = nil
+			goto locked
+		case sendEmoteCmd <- cmd.emoteCmd:
+			cmd.emoteCmd = nil
+			goto locked
+		case sendPartyCmd <- cmd.partyCmd:
+			cmd.partyCmd = nil
+			goto locked
+		case sendTradeCmd <- cmd.tradeCmd:
+			cmd.tradeCmd = nil
+			goto locked
 
 		case hasStopped = <-stopReq:
 			goto exit
@@ -286,6 +552,20 @@ func (a actorConn) stopIO() {
 	<-hasStopped
 }
 
+// Disconnects the client once the IO has been stopped
+// so every message sent to it has been written. Does
+// nothing if the external connection can't be closed.
+func (a actorConn) closeConn() {
+	if a.closer != nil {
+		a.closer.Close()
+	}
+}
+
+// Returns the last world state written to the actor.
+func (a *actorConn) lastWorldState() rpg2d.WorldState {
+	return a.prevState
+}
+
 func ActorCullBounds(center coord.Cell) coord.Bounds {
 	return coord.Bounds{
 		center.Add(-26, 26),
@@ -296,17 +576,46 @@ func ActorCullBounds(center coord.Cell) coord.Bounds {
 // This is the first stage in writing out the state where we cull the
 // state down by the viewport bounds of an actor.
 func (a *actor) WriteState(state rpg2d.WorldState) {
+	bounds := ActorCullBounds(a.Cell())
+
+	// The terrain is sliced from the world's chunks instead of
+	// the simulation's terrain map so only the chunks that are
+	// near actors need to be in memory.
+	if a.edits != nil && a.edits.terrain != nil {
+		state.TerrainMap = a.edits.terrain.slice(bounds)
+	}
+
 	if a.initialState == nil {
 		// This is a hack that should be removed once the WorldState has been
 		// simplified and it doesn't contain so many entity duplications
-		a.actorConn.WriteState(state.CullForInitialState(ActorCullBounds(a.Cell())))
+		a.actorConn.WriteState(a.withoutIgnoredSays(state.CullForInitialState(bounds)))
 	} else {
-		a.actorConn.WriteState(
-			state.CullInto(a.actorConn.nextState, ActorCullBounds(a.Cell())),
-		)
+		a.actorConn.WriteState(a.withoutIgnoredSays(
+			a.withNeighborEntities(state.CullInto(a.actorConn.nextState, bounds), bounds),
+		))
 	}
 }
 
+// Adds the entities of neighbouring zones that
+// are within the bounds to the culled state.
+func (a *actor) withNeighborEntities(state rpg2d.WorldState, bounds coord.Bounds) rpg2d.WorldState {
+	if a.zone == nil {
+		return state
+	}
+
+	neighbors := a.zone.neighborEntities(bounds)
+	if len(neighbors) == 0 {
+		return state
+	}
+
+	// The culled entities may share a backing array
+	// with a previous state and must be copied.
+	entities := make(entity.StateSlice, 0, len(state.Entities)+len(neighbors))
+	entities = append(entities, state.Entities...)
+	state.Entities = append(entities, neighbors...)
+	return state
+}
+
 func (a *actorConn) WriteState(state rpg2d.WorldState) {
 	if a.initialState == nil {
 		a.initialState = &state
@@ -319,6 +628,22 @@ func (a *actorConn) WriteState(state rpg2d.WorldState) {
 		a.nextState = state
 		a.diff.Between(a.prevState, a.nextState)
 
+		// Changes players made to the terrain that the
+		// actor could already see aren't part of the diff.
+		if a.prevState.Bounds.Overlaps(a.nextState.Bounds) {
+			a.diff.TerrainMapSlices = append(a.diff.TerrainMapSlices,
+				a.edits.terrainBetween(a.prevState.Time, a.nextState.Time, a.nextState.Bounds)...,
+			)
+		}
+
+		// Messages are sent before the diff of the
+		// tick they were queued during.
+		a.queueDeliveredMsgs()
+		if len(a.msgs) > 0 {
+			a.sendMsgs <- a.msgs
+			a.msgs = nil
+		}
+
 		if len(a.diff.Entities) > 0 || len(a.diff.Removed) > 0 || a.diff.TerrainMapSlices != nil {
 			a.sendDiff <- &a.diff
 		} else {
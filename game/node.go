@@ -0,0 +1,237 @@
+package game
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// 0.25s
+// In frames
+const gatherCooldown = 10
+
+// The definition of a type of resource node.
+type resourceNodeKind struct {
+	// Name of the resource gathered from the node
+	resource string
+
+	// Resources the node holds when it has spawned
+	// and how many are yielded by each gather.
+	amount, yield int
+
+	// In frames
+	gatherTime, respawnDelay stime.Time
+}
+
+var resourceNodeKinds = map[string]resourceNodeKind{
+	"tree": {
+		resource: "wood",
+		amount:   5,
+		yield:    1,
+
+		// 2s
+		gatherTime: 40 * 2,
+		// 30s
+		respawnDelay: 40 * 30,
+	},
+
+	"ore": {
+		resource: "stone",
+		amount:   5,
+		yield:    1,
+
+		// 3s
+		gatherTime: 40 * 3,
+		// 60s
+		respawnDelay: 40 * 60,
+	},
+}
+
+// Describes a resource node that is placed
+// into a world when the simulation begins.
+type ResourceNodeSpawn struct {
+	Kind string
+	Cell coord.Cell
+}
+
+// The state of a resource node that is shared by
+// the entity in the quad tree and the node index
+// so actors can gather from it during the input phase.
+type resourceNode struct {
+	mu sync.Mutex
+
+	kind   string
+	amount int
+
+	depletedAt stime.Time
+}
+
+type resourceNodeEntity struct {
+	id    entity.Id
+	cell  coord.Cell
+	flags entity.Flag
+
+	*resourceNode
+}
+
+type ResourceNodeEntityState struct {
+	Type string `json:"type"`
+
+	Id   entity.Id  `json:"id"`
+	Cell coord.Cell `json:"cell"`
+
+	Kind   string `json:"kind"`
+	Amount int    `json:"amount"`
+}
+
+func (e resourceNodeEntity) Id() entity.Id        { return e.id }
+func (e resourceNodeEntity) Cell() coord.Cell     { return e.cell }
+func (e resourceNodeEntity) Bounds() coord.Bounds { return coord.Bounds{e.cell, e.cell} }
+func (e resourceNodeEntity) Flags() entity.Flag   { return e.flags }
+
+func (e resourceNodeEntity) ToState() entity.State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return ResourceNodeEntityState{
+		Type: "resourceNode",
+
+		Id:   e.id,
+		Cell: e.cell,
+
+		Kind:   e.kind,
+		Amount: e.amount,
+	}
+}
+
+func (e ResourceNodeEntityState) EntityId() entity.Id  { return e.Id }
+func (e ResourceNodeEntityState) Bounds() coord.Bounds { return coord.Bounds{e.Cell, e.Cell} }
+func (e ResourceNodeEntityState) IsDifferentFrom(other entity.State) bool {
+	switch other := other.(type) {
+	case ResourceNodeEntityState:
+		return e.Amount != other.Amount
+	}
+
+	return true
+}
+
+func (n *resourceNode) isDepleted() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.amount <= 0
+}
+
+// Removes up to a yield of resources from the node.
+// Returns the number of resources that were removed.
+func (n *resourceNode) gather(now stime.Time) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	yield := resourceNodeKinds[n.kind].yield
+	if yield > n.amount {
+		yield = n.amount
+	}
+
+	n.amount -= yield
+	if yield > 0 && n.amount <= 0 {
+		n.depletedAt = now
+	}
+
+	return yield
+}
+
+// Refills a depleted node once its respawn delay has passed.
+func (n *resourceNode) respawn(now stime.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	kind := resourceNodeKinds[n.kind]
+	if n.amount <= 0 && n.depletedAt+kind.respawnDelay <= now {
+		n.amount = kind.amount
+	}
+}
+
+// Resource nodes indexed by the cell they occupy. Nodes
+// are only placed when the simulation begins so the index
+// is never modified once the simulation is running.
+type resourceNodeIndex map[coord.Cell]resourceNodeEntity
+
+// Inserts the nodes into the quad tree and returns an index of them.
+func addResourceNodes(quad quad.Quad, spawns []ResourceNodeSpawn, nextId func() entity.Id) (quad.Quad, resourceNodeIndex, error) {
+	index := make(resourceNodeIndex, len(spawns))
+
+	for _, s := range spawns {
+		kind, exists := resourceNodeKinds[s.Kind]
+		if !exists {
+			return quad, nil, fmt.Errorf("unknown resource node: %s", s.Kind)
+		}
+
+		e := resourceNodeEntity{
+			id:   nextId(),
+			cell: s.Cell,
+
+			resourceNode: &resourceNode{
+				kind:   s.Kind,
+				amount: kind.amount,
+			},
+		}
+
+		quad = quad.Insert(e)
+		index[e.cell] = e
+	}
+
+	return quad, index, nil
+}
+
+// Returns the node the actor is facing.
+func (phase inputPhase) facingNode(a *actor) (resourceNodeEntity, bool) {
+	e, exists := phase.nodes[a.Cell().Neighbor(a.facing)]
+	return e, exists
+}
+
+// Starts gathering from the node the actor is facing.
+func (phase inputPhase) startGather(a *actor, cmd *useCmd, now stime.Time) {
+	node, exists := phase.facingNode(a)
+	if !exists {
+		a.rejectUseCmd(cmd, URR_NO_NODE, now)
+		return
+	}
+
+	if node.isDepleted() {
+		a.rejectUseCmd(cmd, URR_DEPLETED, now)
+		return
+	}
+
+	// Gathering can only be started while stationary
+	if a.pathAction != nil {
+		a.rejectUseCmd(cmd, URR_MOVING, now)
+		return
+	}
+
+	a.startCast(cmd.skill, resourceNodeKinds[node.kind].gatherTime, false, now)
+}
+
+// Called when an actor has finished gathering. The resources
+// yielded by the node are added to the actor's inventory.
+func (phase inputPhase) finishGather(a *actor, cmd *useCmd, now stime.Time) {
+	a.startCooldown(cmd.skill, now)
+
+	node, exists := phase.facingNode(a)
+	if !exists {
+		a.rejectUseCmd(cmd, URR_NO_NODE, now)
+		return
+	}
+
+	n := node.gather(now)
+	if n == 0 {
+		a.rejectUseCmd(cmd, URR_DEPLETED, now)
+		return
+	}
+
+	a.resources.add(Resources{resourceNodeKinds[node.kind].resource: n})
+	a.sendResources(now)
+}
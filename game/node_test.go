@@ -0,0 +1,157 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeResourceNodes(c gospec.Context) {
+	c.Specify("resource nodes", func() {
+		quadTree, err := quad.New(coord.Bounds{cell(-5, 5), cell(5, -5)}, quadMaxSize, nil)
+		c.Assume(err, IsNil)
+
+		c.Specify("are placed from a world definition", func() {
+			_, nodes, err := addResourceNodes(quadTree, []ResourceNodeSpawn{
+				{"tree", cell(0, 1)},
+				{"ore", cell(1, 0)},
+			}, entity.NewIdGenerator())
+			c.Assume(err, IsNil)
+			c.Assume(len(nodes), Equals, 2)
+
+			c.Expect(nodes[cell(0, 1)].kind, Equals, "tree")
+			c.Expect(nodes[cell(0, 1)].amount, Equals, resourceNodeKinds["tree"].amount)
+			c.Expect(nodes[cell(1, 0)].kind, Equals, "ore")
+		})
+
+		c.Specify("can't be placed if the kind doesn't exist", func() {
+			_, _, err := addResourceNodes(quadTree, []ResourceNodeSpawn{
+				{"cake", cell(0, 1)},
+			}, entity.NewIdGenerator())
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("an actor gathering", func() {
+		quadTree, err := quad.New(coord.Bounds{cell(-5, 5), cell(5, -5)}, quadMaxSize, nil)
+		c.Assume(err, IsNil)
+
+		_, nodes, err := addResourceNodes(quadTree, []ResourceNodeSpawn{
+			{"tree", cell(0, 1)},
+		}, entity.NewIdGenerator())
+		c.Assume(err, IsNil)
+
+		tree := nodes[cell(0, 1)]
+		kind := resourceNodeKinds["tree"]
+
+		cmds := make(chan *useCmd, 1)
+		a := &actor{
+			actorEntity: actorEntity{
+				cell:   cell(0, 0),
+				facing: coord.North,
+			},
+			cooldowns: make(cooldownTable),
+			resources: Resources{},
+		}
+		a.readUseCmd = cmds
+
+		phase := inputPhase{ActorIndex{0: a}, entity.NewIdGenerator(), nil, nil, nodes}
+
+		cmd := &useCmd{skill: "gather"}
+		process := func(now stime.Time) {
+			cmds <- cmd
+			phase.processUseCmd(a, now)
+		}
+
+		rejectedWith := func() UseRejectedReason {
+			for _, msg := range a.msgs {
+				if msg, isRejected := msg.(UseRejectedMsg); isRejected {
+					return msg.Reason
+				}
+			}
+			return URR_ERROR
+		}
+
+		c.Specify("takes time", func() {
+			process(1)
+			c.Assume(a.cast, Not(IsNil))
+			c.Expect(a.cast.end, Equals, 1+kind.gatherTime)
+
+			process(kind.gatherTime)
+			c.Expect(a.resources["wood"], Equals, 0)
+		})
+
+		c.Specify("yields resources into the actor's inventory", func() {
+			process(1)
+			process(1 + kind.gatherTime)
+
+			c.Expect(a.cast, IsNil)
+			c.Expect(a.resources["wood"], Equals, kind.yield)
+			c.Expect(tree.amount, Equals, kind.amount-kind.yield)
+
+			c.Specify("and sends the actor its resources", func() {
+				var resources ResourcesMsg
+				for _, msg := range a.msgs {
+					if msg, isResources := msg.(ResourcesMsg); isResources {
+						resources = msg
+					}
+				}
+				c.Expect(resources.Resources["wood"], Equals, kind.yield)
+			})
+		})
+
+		c.Specify("is interrupted if the actor moves", func() {
+			process(1)
+			path := pa(2, 10, cell(0, 0), cell(-1, 0))
+			a.applyPathAction(&path)
+			c.Expect(a.cast, IsNil)
+		})
+
+		c.Specify("can't gather", func() {
+			c.Specify("without facing a node", func() {
+				a.facing = coord.South
+				process(1)
+				c.Expect(a.cast, IsNil)
+				c.Expect(rejectedWith(), Equals, URR_NO_NODE)
+			})
+
+			c.Specify("from a depleted node", func() {
+				tree.amount = 0
+				process(1)
+				c.Expect(a.cast, IsNil)
+				c.Expect(rejectedWith(), Equals, URR_DEPLETED)
+			})
+
+			c.Specify("while moving", func() {
+				path := pa(0, 10, cell(0, 0), cell(-1, 0))
+				a.pathAction = &path
+				process(1)
+				c.Expect(a.cast, IsNil)
+				c.Expect(rejectedWith(), Equals, URR_MOVING)
+			})
+		})
+
+		c.Specify("until the node is depleted", func() {
+			for i := 0; i < kind.amount; i++ {
+				tree.gather(100)
+			}
+
+			c.Expect(tree.isDepleted(), IsTrue)
+			c.Expect(tree.ToState().(ResourceNodeEntityState).Amount, Equals, 0)
+
+			c.Specify("which respawns after a delay", func() {
+				update := updatePhase{ActorIndex{}}
+
+				update.Update(tree, 100+kind.respawnDelay-1)
+				c.Expect(tree.isDepleted(), IsTrue)
+
+				update.Update(tree, 100+kind.respawnDelay)
+				c.Expect(tree.amount, Equals, kind.amount)
+			})
+		})
+	})
+}
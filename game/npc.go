@@ -0,0 +1,383 @@
+package game
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ghthor/aodd/game/datastore"
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// How often an npc will decide what input to submit.
+const npcThinkPeriod = 250 * time.Millisecond
+
+// The world as an npc sees it when deciding
+// what input it will submit.
+type NpcView struct {
+	Now stime.Time
+
+	// The npc's own state
+	Self ActorEntityState
+
+	// Where the npc was spawned
+	Spawn coord.Cell
+
+	// The direction the npc is currently trying
+	// to move in. Nil if it is standing still.
+	Moving *coord.Direction
+
+	// Actors controlled by players that are
+	// within the npc's viewport.
+	Actors []ActorEntityState
+
+	Rand *rand.Rand
+}
+
+// The input an npc will submit.
+type NpcDecision struct {
+	// Direction to move in. Nil to stand still.
+	Move *coord.Direction
+
+	// Cell to path to. Used instead of Move if non-nil.
+	MoveTo *coord.Cell
+
+	// Skill to use. Empty to use none.
+	Use string
+}
+
+// An AI that controls an npc.
+type NpcBehavior interface {
+	Decide(NpcView) NpcDecision
+}
+
+// Describes an npc that will be spawned into a world.
+type NpcSpawn struct {
+	Name string
+
+	Cell   coord.Cell
+	Facing coord.Direction
+
+	Behavior NpcBehavior
+
+	// Time between an npc dying and being respawned.
+	// If negative the npc will never be respawned.
+	RespawnDelay time.Duration
+
+	// The world will end when the npc dies.
+	EndsWorld bool
+}
+
+// An actor controlled by an in-process AI instead of a
+// client connection. The npc replaces the connection by
+// implementing the InitialStateWriter and DiffWriter
+// interfaces and submits requests through its actor
+// like a connection does through an InputReceiver.
+type npc struct {
+	*actor
+
+	spawn NpcSpawn
+	isNpc func(entity.Id) bool
+	rand  *rand.Rand
+
+	// The world state as seen by the npc
+	mu       sync.RWMutex
+	state    rpg2d.WorldState
+	hasState bool
+
+	// Last input submitted
+	moving   *coord.Direction
+	movingTo *coord.Cell
+	using    string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newNpc(entityId entity.Id, actorId rpg2d.ActorId, spawn NpcSpawn, isNpc func(entity.Id) bool) *npc {
+	n := &npc{
+		spawn: spawn,
+		isNpc: isNpc,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		stop:  make(chan struct{}),
+	}
+
+	n.actor = NewActor(entityId, datastore.Actor{
+		Id:     actorId,
+		Name:   spawn.Name,
+		Loc:    spawn.Cell,
+		Facing: spawn.Facing,
+	}, n)
+
+	n.actor.cell = spawn.Cell
+
+	return n
+}
+
+func (n *npc) WriteWorldState(state rpg2d.WorldState) DiffWriter {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.state = state.Clone()
+	n.hasState = true
+	return n
+}
+
+func (n *npc) WriteWorldStateDiff(diff rpg2d.WorldStateDiff) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.state.Apply(diff)
+}
+
+// Npcs don't react to rejected skills or cooldowns.
+// They will keep trying to use a skill until their
+// behavior decides to stop.
+func (n *npc) WriteActorMsgs(ActorMsgs) {}
+
+// Stops the npc from thinking.
+func (n *npc) Close() {
+	n.stopOnce.Do(func() { close(n.stop) })
+}
+
+// Returns the npc's view of the world. Returns
+// false if the initial world state hasn't been
+// received.
+func (n *npc) view() (NpcView, bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if !n.hasState {
+		return NpcView{}, false
+	}
+
+	view := NpcView{
+		Now:    n.state.Time,
+		Spawn:  n.spawn.Cell,
+		Moving: n.moving,
+		Rand:   n.rand,
+	}
+
+	for _, e := range n.state.Entities {
+		e, isActor := e.(ActorEntityState)
+		if !isActor {
+			continue
+		}
+
+		switch {
+		case e.Id == n.actor.actorEntity.id:
+			view.Self = e
+		case !n.isNpc(e.Id):
+			view.Actors = append(view.Actors, e)
+		}
+	}
+
+	return view, true
+}
+
+// Submits the requests needed to change the
+// npc's input to match the decision.
+func (n *npc) act(d NpcDecision, now stime.Time) {
+	if d.MoveTo != nil {
+		d.Move = nil
+	}
+
+	if !sameDirection(n.moving, d.Move) || !sameCell(n.movingTo, d.MoveTo) {
+		switch {
+		case n.moving != nil:
+			n.SubmitMoveRequest(MoveRequest{
+				MoveRequestType: MR_MOVE_CANCEL,
+				Time:            now,
+				Direction:       *n.moving,
+			})
+		case n.movingTo != nil:
+			n.SubmitMoveRequest(MoveRequest{
+				MoveRequestType: MR_MOVE_CANCEL,
+				Time:            now,
+			})
+		}
+
+		switch {
+		case d.MoveTo != nil:
+			n.SubmitMoveRequest(MoveRequest{
+				MoveRequestType: MR_MOVE_TO,
+				Time:            now,
+				Dest:            *d.MoveTo,
+			})
+		case d.Move != nil:
+			n.SubmitMoveRequest(MoveRequest{
+				MoveRequestType: MR_MOVE,
+				Time:            now,
+				Direction:       *d.Move,
+			})
+		}
+
+		n.moving, n.movingTo = d.Move, d.MoveTo
+	}
+
+	if n.using != d.Use {
+		if n.using != "" {
+			n.SubmitUseRequest(UseRequest{UR_USE_CANCEL, now, n.using})
+		}
+
+		if d.Use != "" {
+			n.SubmitUseRequest(UseRequest{UR_USE, now, d.Use})
+		}
+
+		n.using = d.Use
+	}
+}
+
+func (n *npc) think() {
+	ticker := time.NewTicker(npcThinkPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			return
+
+		case <-ticker.C:
+			view, ok := n.view()
+			if !ok {
+				continue
+			}
+
+			n.act(n.spawn.Behavior.Decide(view), view.Now)
+		}
+	}
+}
+
+func sameDirection(a, b *coord.Direction) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameCell(a, b *coord.Cell) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// Spawns the npcs of a world into its simulation
+// and respawns them after they have died.
+type npcSpawner struct {
+	sim    actorSimulation
+	nextId func() entity.Id
+
+	// Called when an npc that ends the world has died
+	endWorld func(reason string)
+
+	mu      sync.RWMutex
+	npcs    map[entity.Id]*npc
+	stopped bool
+}
+
+// The actors of a simulation can be connected and removed.
+type actorSimulation interface {
+	ConnectActor(rpg2d.Actor)
+	RemoveActor(rpg2d.Actor)
+}
+
+func newNpcSpawner(sim actorSimulation, nextId func() entity.Id) *npcSpawner {
+	return &npcSpawner{
+		sim:    sim,
+		nextId: nextId,
+		npcs:   make(map[entity.Id]*npc),
+	}
+}
+
+func (s *npcSpawner) isNpc(id entity.Id) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.npcs[id]
+	return exists
+}
+
+// Npcs are given negative actor ids so they
+// never collide with the ids of the datastore.
+func (s *npcSpawner) spawnAll(spawns []NpcSpawn) {
+	for i, spawn := range spawns {
+		s.spawn(rpg2d.ActorId(-(i + 1)), spawn)
+	}
+}
+
+func (s *npcSpawner) spawn(id rpg2d.ActorId, spawn NpcSpawn) {
+	n := newNpc(s.nextId(), id, spawn, s.isNpc)
+
+	var died sync.Once
+	n.actor.onDeath = func() {
+		// Called during the narrow phase while the actor
+		// index is locked so the npc must be removed from
+		// the simulation on another go routine.
+		died.Do(func() {
+			if spawn.EndsWorld && s.endWorld != nil {
+				go s.endWorld(spawn.Name + " has been slain")
+				return
+			}
+
+			go s.respawn(id, n)
+		})
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.npcs[n.actor.actorEntity.id] = n
+	s.mu.Unlock()
+
+	s.sim.ConnectActor(n.actor)
+	go n.think()
+}
+
+func (s *npcSpawner) respawn(id rpg2d.ActorId, n *npc) {
+	s.mu.RLock()
+	stopped := s.stopped
+	s.mu.RUnlock()
+
+	// The npc was closed when the spawner was stopped
+	if stopped {
+		return
+	}
+
+	n.Close()
+	s.sim.RemoveActor(n.actor)
+
+	s.mu.Lock()
+	delete(s.npcs, n.actor.actorEntity.id)
+	s.mu.Unlock()
+
+	if n.spawn.RespawnDelay < 0 {
+		return
+	}
+
+	time.AfterFunc(n.spawn.RespawnDelay, func() {
+		s.spawn(id, n.spawn)
+	})
+}
+
+// Closes every npc and prevents any more from being
+// spawned. The npcs aren't removed from the simulation
+// so it must be halted by the caller.
+func (s *npcSpawner) stopAll() {
+	s.mu.Lock()
+	s.stopped = true
+	npcs := make([]*npc, 0, len(s.npcs))
+	for _, n := range s.npcs {
+		npcs = append(npcs, n)
+	}
+	s.mu.Unlock()
+
+	for _, n := range npcs {
+		n.Close()
+	}
+}
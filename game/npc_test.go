@@ -0,0 +1,162 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeNpcs(c gospec.Context) {
+	player := func(id entity.Id, c coord.Cell) ActorEntityState {
+		return ActorEntityState{Id: id, Cell: c}
+	}
+
+	c.Specify("an npc that guards", func() {
+		b := NpcGuard{Radius: 3}
+		v := NpcView{
+			Self:  player(0, cell(1, 0)),
+			Spawn: cell(0, 0),
+		}
+
+		c.Specify("returns to its spawn", func() {
+			d := b.Decide(v)
+			c.Assume(d.MoveTo, Not(IsNil))
+			c.Expect(*d.MoveTo, Equals, cell(0, 0))
+
+			v.Self.Cell = cell(0, 0)
+			c.Expect(b.Decide(v), Equals, NpcDecision{})
+		})
+
+		c.Specify("ignores actors outside the radius", func() {
+			v.Actors = []ActorEntityState{player(1, cell(0, 4))}
+			c.Expect(*b.Decide(v).MoveTo, Equals, cell(0, 0))
+		})
+
+		c.Specify("attacks actors inside the radius", func() {
+			v.Actors = []ActorEntityState{player(1, cell(1, 1))}
+			d := b.Decide(v)
+			c.Expect(*d.Move, Equals, coord.North)
+			c.Expect(d.Use, Equals, "assail")
+		})
+	})
+
+	c.Specify("an npc that chases", func() {
+		b := NpcChase{Sight: 5}
+		v := NpcView{Self: player(0, cell(0, 0))}
+
+		c.Specify("stands still if it can't see an actor", func() {
+			c.Expect(b.Decide(v), Equals, NpcDecision{})
+		})
+
+		c.Specify("moves toward the nearest actor", func() {
+			v.Actors = []ActorEntityState{
+				player(1, cell(0, -4)),
+				player(2, cell(3, 0)),
+			}
+
+			d := b.Decide(v)
+			c.Assume(d.MoveTo, Not(IsNil))
+			c.Expect(*d.MoveTo, Equals, cell(3, 0))
+			c.Expect(d.Use, Equals, "")
+		})
+	})
+
+	c.Specify("an npc that flees", func() {
+		b := NpcFlee{Sight: 5}
+		v := NpcView{
+			Self:   player(0, cell(0, 0)),
+			Actors: []ActorEntityState{player(1, cell(-2, 1))},
+		}
+
+		c.Specify("moves away from the nearest actor", func() {
+			c.Expect(*b.Decide(v).Move, Equals, coord.East)
+		})
+	})
+
+	c.Specify("an npc that wanders", func() {
+		b := NpcWander{Radius: 2}
+		v := NpcView{
+			Self:  player(0, cell(0, 0)),
+			Spawn: cell(0, 0),
+			Rand:  rand.New(rand.NewSource(1)),
+		}
+
+		c.Specify("stays within the radius of its spawn", func() {
+			v.Self.Cell = cell(0, -3)
+			c.Expect(*b.Decide(v).MoveTo, Equals, cell(0, 0))
+		})
+
+		c.Specify("never uses a skill", func() {
+			for i := 0; i < 20; i++ {
+				c.Expect(b.Decide(v).Use, Equals, "")
+			}
+		})
+	})
+
+	c.Specify("an npc", func() {
+		spawner := newNpcSpawner(nil, entity.NewIdGenerator())
+		n := newNpc(1, -1, NpcSpawn{
+			Name: "npc",
+			Cell: cell(5, 5),
+		}, spawner.isNpc)
+		spawner.npcs[1] = n
+
+		c.Specify("is spawned at its cell", func() {
+			c.Expect(n.actor.Cell(), Equals, cell(5, 5))
+		})
+
+		c.Specify("can't see until it has received the world state", func() {
+			_, ok := n.view()
+			c.Expect(ok, Not(IsTrue))
+		})
+
+		c.Specify("only sees actors that aren't npcs", func() {
+			other := newNpc(2, -2, NpcSpawn{Cell: cell(6, 5)}, spawner.isNpc)
+			spawner.npcs[2] = other
+
+			n.WriteWorldState(rpg2d.WorldState{
+				Time: 3,
+				Entities: entity.StateSlice{
+					n.actor.ToState(),
+					other.actor.ToState(),
+					player(3, cell(4, 5)),
+				},
+			})
+
+			v, ok := n.view()
+			c.Assume(ok, IsTrue)
+			c.Expect(v.Now, Equals, stime.Time(3))
+			c.Expect(v.Self.Id, Equals, entity.Id(1))
+			c.Assume(len(v.Actors), Equals, 1)
+			c.Expect(v.Actors[0].Id, Equals, entity.Id(3))
+		})
+
+		c.Specify("submits requests when its decision changes", func() {
+			moveReqs := make(chan MoveRequest, 4)
+			useReqs := make(chan UseRequest, 4)
+			n.actor.submitMoveRequest = moveReqs
+			n.actor.submitUseRequest = useReqs
+
+			north, east := coord.North, coord.East
+
+			n.act(NpcDecision{Move: &north, Use: "assail"}, 1)
+			n.act(NpcDecision{Move: &north, Use: "assail"}, 2)
+			n.act(NpcDecision{Move: &east}, 3)
+
+			c.Expect(len(moveReqs), Equals, 3)
+			c.Expect(<-moveReqs, Equals, MoveRequest{MoveRequestType: MR_MOVE, Time: 1, Direction: coord.North})
+			c.Expect(<-moveReqs, Equals, MoveRequest{MoveRequestType: MR_MOVE_CANCEL, Time: 3, Direction: coord.North})
+			c.Expect(<-moveReqs, Equals, MoveRequest{MoveRequestType: MR_MOVE, Time: 3, Direction: coord.East})
+
+			c.Expect(len(useReqs), Equals, 2)
+			c.Expect(<-useReqs, Equals, UseRequest{UR_USE, 1, "assail"})
+			c.Expect(<-useReqs, Equals, UseRequest{UR_USE_CANCEL, 3, "assail"})
+		})
+	})
+}
@@ -0,0 +1,463 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// The most actors a party can contain.
+const partyMaxSize = 5
+
+// Changes to the positions and health of an actor's party
+// members are sent to the actor at most once every period.
+const partyStatusPeriod = 10 // 250ms in frames
+
+type PartyRequestType int
+
+//go:generate stringer -type=PartyRequestType
+const (
+	PR_ERROR PartyRequestType = iota
+	PR_INVITE
+	PR_ACCEPT
+	PR_LEAVE
+	PR_KICK
+	PR_SIZE
+)
+
+type PartyRequest struct {
+	PartyRequestType
+	stime.Time
+
+	// The actor being invited or kicked, or the actor
+	// whose invite is being accepted. Unused by leave.
+	Name string
+}
+
+// Params are the name of the actor the request is for.
+func newPartyRequest(t PartyRequestType, timeIssued stime.Time, params string) (PartyRequest, error) {
+	name := strings.TrimSpace(params)
+
+	switch t {
+	case PR_INVITE, PR_KICK:
+		if name == "" {
+			return PartyRequest{}, fmt.Errorf("%v request without a name", t)
+		}
+
+	case PR_ACCEPT, PR_LEAVE:
+	default:
+		return PartyRequest{}, fmt.Errorf("unknown party request: %v", t)
+	}
+
+	return PartyRequest{t, timeIssued, name}, nil
+}
+
+type partyCmd struct {
+	PartyRequestType
+	stime.Time
+	name string
+}
+
+type PartyRejectedReason int
+
+//go:generate stringer -type=PartyRejectedReason
+const (
+	PRR_ERROR PartyRejectedReason = iota
+	PRR_NO_ACTOR
+	PRR_NOT_LEADER
+	PRR_IN_PARTY
+	PRR_NOT_IN_PARTY
+	PRR_NOT_INVITED
+	PRR_NOT_MEMBER
+	PRR_FULL
+)
+
+// Sent to an actor's connection when a
+// party request could not be performed.
+type PartyRejectedMsg struct {
+	Time    stime.Time          `json:"time"`
+	Request PartyRequestType    `json:"request"`
+	Name    string              `json:"name"`
+	Reason  PartyRejectedReason `json:"reason"`
+}
+
+// Sent to an actor that has been invited to join a party.
+type PartyInviteMsg struct {
+	Time stime.Time `json:"time"`
+	From string     `json:"from"`
+}
+
+type PartyMemberState struct {
+	Name  string     `json:"name"`
+	Cell  coord.Cell `json:"cell"`
+	Hp    int        `json:"hp"`
+	HpMax int        `json:"hpMax"`
+}
+
+// Sent to an actor when its party has changed. Party
+// members are included even if they're too far away
+// to be in the world state the actor is sent.
+type PartyMsg struct {
+	Time   stime.Time `json:"time"`
+	Leader string     `json:"leader"`
+
+	// Empty if the actor isn't in a party
+	Members []PartyMemberState `json:"members"`
+}
+
+func (m PartyMsg) hasSameMembersAs(other PartyMsg) bool {
+	if m.Leader != other.Leader || len(m.Members) != len(other.Members) {
+		return false
+	}
+
+	for i := range m.Members {
+		if m.Members[i].Name != other.Members[i].Name {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m PartyMsg) isSameAs(other PartyMsg) bool {
+	if !m.hasSameMembersAs(other) {
+		return false
+	}
+
+	for i := range m.Members {
+		if m.Members[i] != other.Members[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+type party struct {
+	leader  *actor
+	members []*actor
+}
+
+// The parties of every actor in the world. Is shared by
+// the zones of the world so members can be in any zone.
+type partyTable struct {
+	mu sync.Mutex
+
+	ofActor map[*actor]*party
+
+	// Indexed by the actor that was invited
+	invites map[*actor]*actor
+
+	// The state of each actor as of its last update phase.
+	// Members in other zones are only read through this copy.
+	status map[*actor]PartyMemberState
+}
+
+func newPartyTable() *partyTable {
+	return &partyTable{
+		ofActor: make(map[*actor]*party),
+		invites: make(map[*actor]*actor),
+		status:  make(map[*actor]PartyMemberState),
+	}
+}
+
+// Is called by the actor's update phase and
+// before the actor is being simulated.
+func (t *partyTable) publish(a *actor) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	t.status[a] = PartyMemberState{
+		Name:  a.name,
+		Cell:  a.Cell(),
+		Hp:    a.hp,
+		HpMax: a.hpMax,
+	}
+	t.mu.Unlock()
+}
+
+// Invites the actor to join the party led by the inviter. The
+// inviter becomes the leader of a new party when the invite
+// is accepted if the inviter isn't in a party.
+func (t *partyTable) invite(from, to *actor) (PartyRejectedReason, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[from]
+	switch {
+	case p != nil && p.leader != from:
+		return PRR_NOT_LEADER, false
+	case t.ofActor[to] != nil:
+		return PRR_IN_PARTY, false
+	case p != nil && len(p.members) >= partyMaxSize:
+		return PRR_FULL, false
+	}
+
+	t.invites[to] = from
+	return PRR_ERROR, true
+}
+
+// Adds the actor to the party of the actor that invited it.
+// If the name is empty the most recent invite is accepted.
+func (t *partyTable) accept(a *actor, name string) (PartyRejectedReason, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	from := t.invites[a]
+	switch {
+	case from == nil, name != "" && !strings.EqualFold(from.name, name):
+		return PRR_NOT_INVITED, false
+	case t.ofActor[a] != nil:
+		return PRR_IN_PARTY, false
+	}
+
+	p := t.ofActor[from]
+	if p == nil {
+		p = &party{leader: from, members: []*actor{from}}
+		t.ofActor[from] = p
+	}
+
+	if len(p.members) >= partyMaxSize {
+		return PRR_FULL, false
+	}
+
+	delete(t.invites, a)
+	p.members = append(p.members, a)
+	t.ofActor[a] = p
+	return PRR_ERROR, true
+}
+
+// Removes the actor from its party. The member that joined
+// after the leader becomes the leader if the leader leaves.
+// A party is disbanded when only 1 member remains.
+func (t *partyTable) leave(a *actor) (PartyRejectedReason, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[a]
+	if p == nil {
+		return PRR_NOT_IN_PARTY, false
+	}
+
+	t.removeMember(p, a)
+	return PRR_ERROR, true
+}
+
+// Removes the named actor from the leader's party.
+func (t *partyTable) kick(leader *actor, name string) (PartyRejectedReason, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[leader]
+	switch {
+	case p == nil:
+		return PRR_NOT_IN_PARTY, false
+	case p.leader != leader:
+		return PRR_NOT_LEADER, false
+	}
+
+	for _, m := range p.members {
+		if m != leader && strings.EqualFold(m.name, name) {
+			t.removeMember(p, m)
+			return PRR_ERROR, true
+		}
+	}
+
+	return PRR_NOT_MEMBER, false
+}
+
+func (t *partyTable) removeMember(p *party, a *actor) {
+	delete(t.ofActor, a)
+
+	for i, m := range p.members {
+		if m == a {
+			p.members = append(p.members[:i:i], p.members[i+1:]...)
+			break
+		}
+	}
+
+	if p.leader == a && len(p.members) > 0 {
+		p.leader = p.members[0]
+	}
+
+	if len(p.members) == 1 {
+		delete(t.ofActor, p.members[0])
+		p.members = nil
+	}
+}
+
+// Removes an actor that has disconnected from its party
+// and the invites it has sent and received.
+func (t *partyTable) remove(a *actor) {
+	if t == nil {
+		return
+	}
+
+	t.leave(a)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.status, a)
+	delete(t.invites, a)
+	for to, from := range t.invites {
+		if from == a {
+			delete(t.invites, to)
+		}
+	}
+}
+
+// Returns the members of the actor's party,
+// including the actor. Returns nil if the
+// actor isn't in a party.
+func (t *partyTable) membersOf(a *actor) []*actor {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[a]
+	if p == nil {
+		return nil
+	}
+
+	return append([]*actor(nil), p.members...)
+}
+
+// Returns true if the actor with the id
+// is in the same party as the actor.
+func (t *partyTable) inSameParty(a *actor, id rpg2d.ActorId) bool {
+	if t == nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[a]
+	if p == nil {
+		return false
+	}
+
+	for _, m := range p.members {
+		if m.Id() == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the state of the actor's party.
+func (t *partyTable) statusOf(a *actor, now stime.Time) PartyMsg {
+	msg := PartyMsg{Time: now}
+	if t == nil {
+		return msg
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := t.ofActor[a]
+	if p == nil {
+		return msg
+	}
+
+	msg.Leader = p.leader.name
+	msg.Members = make([]PartyMemberState, 0, len(p.members))
+	for _, m := range p.members {
+		msg.Members = append(msg.Members, t.status[m])
+	}
+
+	return msg
+}
+
+// Returns the actors in the same party as the actor,
+// including the actor.
+func (a *actor) partyMembers() []*actor {
+	return a.parties.membersOf(a)
+}
+
+func (a *actor) ReadPartyCmd() *partyCmd {
+	return <-a.readPartyCmd
+}
+
+func (a *actor) rejectPartyCmd(cmd *partyCmd, reason PartyRejectedReason, now stime.Time) {
+	a.queueMsg(PartyRejectedMsg{now, cmd.PartyRequestType, cmd.name, reason})
+}
+
+func (phase inputPhase) processPartyCmd(a *actor, now stime.Time) {
+	cmd := a.ReadPartyCmd()
+	if cmd == nil {
+		return
+	}
+
+	a.runPartyCmd(cmd, now)
+}
+
+// Changes the actor's party. Members are sent the
+// change with the next status of their party.
+func (a *actor) runPartyCmd(cmd *partyCmd, now stime.Time) {
+	if a.parties == nil {
+		a.rejectPartyCmd(cmd, PRR_ERROR, now)
+		return
+	}
+
+	var reason PartyRejectedReason
+	var ok bool
+
+	switch cmd.PartyRequestType {
+	case PR_INVITE:
+		to := a.chat.actorNamed(cmd.name)
+		if to == nil || to == a {
+			a.rejectPartyCmd(cmd, PRR_NO_ACTOR, now)
+			return
+		}
+
+		reason, ok = a.parties.invite(a, to)
+		if ok {
+			to.deliverMsg(PartyInviteMsg{now, a.name})
+		}
+
+	case PR_ACCEPT:
+		reason, ok = a.parties.accept(a, cmd.name)
+
+	case PR_LEAVE:
+		reason, ok = a.parties.leave(a)
+
+	case PR_KICK:
+		reason, ok = a.parties.kick(a, cmd.name)
+	}
+
+	if !ok {
+		a.rejectPartyCmd(cmd, reason, now)
+	}
+}
+
+// Sends the actor the status of its party if it has changed.
+func (a *actor) sendPartyStatus(now stime.Time) {
+	if a.parties == nil {
+		return
+	}
+
+	msg := a.parties.statusOf(a, now)
+	last := a.lastPartyStatus
+
+	switch {
+	case msg.isSameAs(last):
+		return
+	case msg.hasSameMembersAs(last) && now < last.Time+partyStatusPeriod:
+		return
+	}
+
+	a.lastPartyStatus = msg
+	a.queueMsg(msg)
+}
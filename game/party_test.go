@@ -0,0 +1,191 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/quad"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func newPartyTestActor(chat *chatChannels, parties *partyTable, id int, name string, cell coord.Cell) *actor {
+	a := newChatTestActor(chat, name, cell)
+	a.id = rpg2d.ActorId(id)
+	a.actorEntity.actorId = a.id
+	a.hp, a.hpMax = baseHpMax, baseHpMax
+	a.parties = parties
+	parties.publish(a)
+	return a
+}
+
+func DescribeParties(c gospec.Context) {
+	c.Specify("a party request", func() {
+		c.Specify("includes the name of the actor", func() {
+			r, err := newPartyRequest(PR_INVITE, 1, " bob ")
+			c.Assume(err, IsNil)
+			c.Expect(r, Equals, PartyRequest{PR_INVITE, 1, "bob"})
+		})
+
+		c.Specify("is invalid if an invite has no name", func() {
+			_, err := newPartyRequest(PR_INVITE, 1, "")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("a party", func() {
+		chat := newChatChannels(nil)
+		parties := newPartyTable()
+
+		alice := newPartyTestActor(chat, parties, 1, "Alice", coord.Cell{0, 0})
+		bob := newPartyTestActor(chat, parties, 2, "Bob", coord.Cell{1, 0})
+		carol := newPartyTestActor(chat, parties, 3, "Carol", coord.Cell{200, 0})
+
+		run := func(a *actor, t PartyRequestType, name string) []ActorMsg {
+			a.runPartyCmd(&partyCmd{t, 1, name}, 1)
+			return a.deliveredMsgs()
+		}
+
+		c.Specify("is formed when an invite is accepted", func() {
+			c.Expect(len(run(alice, PR_INVITE, "bob")), Equals, 0)
+			c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{PartyInviteMsg{1, "Alice"}})
+
+			c.Expect(len(run(bob, PR_ACCEPT, "")), Equals, 0)
+			c.Expect(alice.partyMembers(), ContainsExactly, []*actor{alice, bob})
+
+			c.Specify("and is led by the actor that invited", func() {
+				c.Expect(run(bob, PR_INVITE, "carol"), ContainsExactly, []ActorMsg{
+					PartyRejectedMsg{1, PR_INVITE, "carol", PRR_NOT_LEADER},
+				})
+			})
+
+			c.Specify("and can chat", func() {
+				inputPhase{}.processChatCmd(bob.withChatCmd(&chatCmd{CR_PARTY, 1, "hi", "", 0}), 1)
+				c.Expect(alice.deliveredMsgs(), ContainsExactly, []ActorMsg{
+					ChatMsg{1, CR_PARTY, "Bob", "", "hi"},
+				})
+			})
+
+			c.Specify("sends the members their status", func() {
+				alice.sendPartyStatus(1)
+				msgs := alice.deliveredMsgs()
+				c.Assume(len(msgs), Equals, 1)
+
+				status := msgs[0].(PartyMsg)
+				c.Expect(status.Leader, Equals, "Alice")
+				c.Expect(status.Members, ContainsExactly, []PartyMemberState{
+					{"Alice", coord.Cell{0, 0}, baseHpMax, baseHpMax},
+					{"Bob", coord.Cell{1, 0}, baseHpMax, baseHpMax},
+				})
+
+				c.Specify("only when it has changed", func() {
+					alice.sendPartyStatus(2)
+					c.Expect(len(alice.deliveredMsgs()), Equals, 0)
+				})
+
+				c.Specify("as of the last state each member published", func() {
+					bob.actorEntity.cell = coord.Cell{2, 0}
+					alice.sendPartyStatus(1 + partyStatusPeriod)
+					c.Expect(len(alice.deliveredMsgs()), Equals, 0)
+
+					parties.publish(bob)
+					alice.sendPartyStatus(1 + partyStatusPeriod)
+					msgs := alice.deliveredMsgs()
+					c.Assume(len(msgs), Equals, 1)
+					c.Expect(msgs[0].(PartyMsg).Members[1].Cell, Equals, coord.Cell{2, 0})
+				})
+
+				c.Specify("at most once a period if only their health has changed", func() {
+					bob.hp -= 10
+					parties.publish(bob)
+					alice.sendPartyStatus(2)
+					c.Expect(len(alice.deliveredMsgs()), Equals, 0)
+
+					alice.sendPartyStatus(1 + partyStatusPeriod)
+					c.Expect(len(alice.deliveredMsgs()), Equals, 1)
+				})
+
+				c.Specify("immediately when a member has left", func() {
+					run(bob, PR_LEAVE, "")
+					alice.sendPartyStatus(2)
+					msgs := alice.deliveredMsgs()
+					c.Assume(len(msgs), Equals, 1)
+					c.Expect(len(msgs[0].(PartyMsg).Members), Equals, 0)
+				})
+			})
+
+			c.Specify("that is full can't be joined", func() {
+				for i := 0; i < partyMaxSize-2; i++ {
+					a := newPartyTestActor(chat, parties, 10+i, string(rune('a'+i)), coord.Cell{0, 0})
+					run(alice, PR_INVITE, a.name)
+					run(a, PR_ACCEPT, "")
+				}
+
+				c.Expect(run(alice, PR_INVITE, "carol"), ContainsExactly, []ActorMsg{
+					PartyRejectedMsg{1, PR_INVITE, "carol", PRR_FULL},
+				})
+			})
+
+			c.Specify("is disbanded when only the leader remains", func() {
+				run(alice, PR_KICK, "bob")
+				c.Expect(len(alice.partyMembers()), Equals, 0)
+				c.Expect(len(bob.partyMembers()), Equals, 0)
+			})
+
+			c.Specify("is given a new leader when its leader leaves", func() {
+				run(alice, PR_INVITE, "carol")
+				run(carol, PR_ACCEPT, "alice")
+
+				parties.remove(alice)
+				c.Expect(bob.partyMembers(), ContainsExactly, []*actor{bob, carol})
+				c.Expect(len(run(bob, PR_KICK, "carol")), Equals, 0)
+			})
+
+			c.Specify("can only be kicked from by the leader", func() {
+				c.Expect(run(bob, PR_KICK, "alice"), ContainsExactly, []ActorMsg{
+					PartyRejectedMsg{1, PR_KICK, "alice", PRR_NOT_LEADER},
+				})
+			})
+
+			c.Specify("makes its members friendly", func() {
+				phase := &narrowPhase{actorIndex: ActorIndex{alice.id: alice, bob.id: bob}}
+				c.Expect(phase.isFriendly(alice.id, bob), IsTrue)
+				c.Expect(phase.isFriendly(carol.id, bob), IsFalse)
+
+				assail := assailEntity{spawnedBy: 100, spawnedByActor: alice.id, cell: bob.Cell(), damage: 25}
+
+				c.Specify("so they can't damage each other", func() {
+					phase.solveActorAssail(bob, assail, quad.Collision{}, 1)
+					c.Expect(bob.hp, Equals, baseHpMax)
+				})
+
+				c.Specify("unless friendly fire is allowed", func() {
+					phase.friendlyFire = true
+					phase.solveActorAssail(bob, assail, quad.Collision{}, 1)
+					c.Expect(bob.hp, Equals, baseHpMax-25)
+				})
+			})
+		})
+
+		c.Specify("can't be joined without an invite", func() {
+			c.Expect(run(bob, PR_ACCEPT, ""), ContainsExactly, []ActorMsg{
+				PartyRejectedMsg{1, PR_ACCEPT, "", PRR_NOT_INVITED},
+			})
+		})
+
+		c.Specify("can't be joined after the inviter has disconnected", func() {
+			run(alice, PR_INVITE, "bob")
+			bob.deliveredMsgs()
+			parties.remove(alice)
+			c.Expect(run(bob, PR_ACCEPT, ""), ContainsExactly, []ActorMsg{
+				PartyRejectedMsg{1, PR_ACCEPT, "", PRR_NOT_INVITED},
+			})
+		})
+
+		c.Specify("can't invite an actor that isn't connected", func() {
+			c.Expect(run(alice, PR_INVITE, "dave"), ContainsExactly, []ActorMsg{
+				PartyRejectedMsg{1, PR_INVITE, "dave", PRR_NO_ACTOR},
+			})
+		})
+	})
+}
@@ -0,0 +1,31 @@
+// Code generated by "stringer -type=PartyRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PRR_ERROR-0]
+	_ = x[PRR_NO_ACTOR-1]
+	_ = x[PRR_NOT_LEADER-2]
+	_ = x[PRR_IN_PARTY-3]
+	_ = x[PRR_NOT_IN_PARTY-4]
+	_ = x[PRR_NOT_INVITED-5]
+	_ = x[PRR_NOT_MEMBER-6]
+	_ = x[PRR_FULL-7]
+}
+
+const _PartyRejectedReason_name = "PRR_ERRORPRR_NO_ACTORPRR_NOT_LEADERPRR_IN_PARTYPRR_NOT_IN_PARTYPRR_NOT_INVITEDPRR_NOT_MEMBERPRR_FULL"
+
+var _PartyRejectedReason_index = [...]uint8{0, 9, 21, 35, 47, 63, 78, 92, 100}
+
+func (i PartyRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_PartyRejectedReason_index)-1 {
+		return "PartyRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PartyRejectedReason_name[_PartyRejectedReason_index[idx]:_PartyRejectedReason_index[idx+1]]
+}
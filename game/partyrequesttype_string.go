@@ -0,0 +1,29 @@
+// Code generated by "stringer -type=PartyRequestType"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PR_ERROR-0]
+	_ = x[PR_INVITE-1]
+	_ = x[PR_ACCEPT-2]
+	_ = x[PR_LEAVE-3]
+	_ = x[PR_KICK-4]
+	_ = x[PR_SIZE-5]
+}
+
+const _PartyRequestType_name = "PR_ERRORPR_INVITEPR_ACCEPTPR_LEAVEPR_KICKPR_SIZE"
+
+var _PartyRequestType_index = [...]uint8{0, 8, 17, 26, 34, 41, 48}
+
+func (i PartyRequestType) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_PartyRequestType_index)-1 {
+		return "PartyRequestType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _PartyRequestType_name[_PartyRequestType_index[idx]:_PartyRequestType_index[idx+1]]
+}
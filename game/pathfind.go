@@ -0,0 +1,198 @@
+package game
+
+import (
+	"container/heap"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+)
+
+// A node of the A* search frontier.
+type pathNode struct {
+	cell coord.Cell
+
+	// Cost from the start and the estimated
+	// cost from the start to the goal.
+	cost, estimate int
+
+	index int
+}
+
+type pathFrontier []*pathNode
+
+func (f pathFrontier) Len() int           { return len(f) }
+func (f pathFrontier) Less(i, j int) bool { return f[i].estimate < f[j].estimate }
+func (f pathFrontier) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+	f[i].index = i
+	f[j].index = j
+}
+
+func (f *pathFrontier) Push(x interface{}) {
+	n := x.(*pathNode)
+	n.index = len(*f)
+	*f = append(*f, n)
+}
+
+func (f *pathFrontier) Pop() interface{} {
+	old := *f
+	n := old[len(old)-1]
+	*f = old[:len(old)-1]
+	return n
+}
+
+var pathDirections = [...]coord.Direction{
+	coord.North,
+	coord.East,
+	coord.South,
+	coord.West,
+}
+
+// Finds the shortest path of steps from one cell to another
+// that stays inside the bounds and avoids the blocked cells.
+// If the goal is blocked the path will end in a cell adjacent
+// to it. Returns false if there is no path.
+func findPath(from, to coord.Cell, bounds coord.Bounds, isBlocked func(coord.Cell) bool) ([]coord.Direction, bool) {
+	if from == to {
+		return nil, true
+	}
+
+	if !bounds.Contains(to) {
+		return nil, false
+	}
+
+	goalBlocked := isBlocked(to)
+	isGoal := func(c coord.Cell) bool {
+		if goalBlocked {
+			return distance(c, to) == 1
+		}
+		return c == to
+	}
+
+	cameFrom := make(map[coord.Cell]coord.Direction)
+	costs := map[coord.Cell]int{from: 0}
+
+	frontier := &pathFrontier{}
+	heap.Push(frontier, &pathNode{cell: from, estimate: distance(from, to)})
+
+	for frontier.Len() > 0 {
+		n := heap.Pop(frontier).(*pathNode)
+
+		if isGoal(n.cell) {
+			return walkBack(from, n.cell, cameFrom), true
+		}
+
+		// A cheaper path to the cell has been found
+		// since this node was added to the frontier.
+		if n.cost > costs[n.cell] {
+			continue
+		}
+
+		for _, d := range pathDirections {
+			next := n.cell.Neighbor(d)
+			if !bounds.Contains(next) || isBlocked(next) {
+				continue
+			}
+
+			cost := n.cost + 1
+			if prev, seen := costs[next]; seen && prev <= cost {
+				continue
+			}
+
+			costs[next] = cost
+			cameFrom[next] = d
+			heap.Push(frontier, &pathNode{
+				cell:     next,
+				cost:     cost,
+				estimate: cost + distance(next, to),
+			})
+		}
+	}
+
+	return nil, false
+}
+
+// Rebuilds the steps from the start to the end by
+// following the directions that reached each cell.
+func walkBack(from, to coord.Cell, cameFrom map[coord.Cell]coord.Direction) []coord.Direction {
+	var steps []coord.Direction
+	for c := to; c != from; {
+		d := cameFrom[c]
+		steps = append(steps, d)
+		c = c.Neighbor(opposite(d))
+	}
+
+	// Reverse into the order they must be taken
+	for i, j := 0, len(steps)-1; i < j; i, j = i+1, j-1 {
+		steps[i], steps[j] = steps[j], steps[i]
+	}
+
+	return steps
+}
+
+func opposite(d coord.Direction) coord.Direction {
+	switch d {
+	case coord.North:
+		return coord.South
+	case coord.East:
+		return coord.West
+	case coord.South:
+		return coord.North
+	default:
+		return coord.East
+	}
+}
+
+// Returns the cells that are blocked by walls, structures
+// and other actors in a world state as seen by an actor.
+func blockedCells(state rpg2d.WorldState, self entity.Id) map[coord.Cell]bool {
+	blocked := make(map[coord.Cell]bool)
+
+	block := func(b coord.Bounds) {
+		for x := b.TopL.X; x <= b.BotR.X; x++ {
+			for y := b.BotR.Y; y <= b.TopL.Y; y++ {
+				blocked[coord.Cell{x, y}] = true
+			}
+		}
+	}
+
+	for _, e := range state.Entities {
+		switch e := e.(type) {
+		case WallEntityState:
+			block(e.Bounds())
+		case StructureEntityState:
+			block(e.Bounds())
+		case ResourceNodeEntityState:
+			block(e.Bounds())
+		case ActorEntityState:
+			if e.Id != self {
+				block(e.Bounds())
+			}
+		}
+	}
+
+	return blocked
+}
+
+// Returns the unblocked cell inside the bounds and within
+// the radius that is closest to the cell. Returns false if
+// every cell within the radius is blocked.
+func closestUnblockedCell(c coord.Cell, radius int, bounds coord.Bounds, isBlocked func(coord.Cell) bool) (coord.Cell, bool) {
+	for r := 0; r <= radius; r++ {
+		for x := -r; x <= r; x++ {
+			for y := -r; y <= r; y++ {
+				// Cells closer than r have already been checked
+				if abs(x) != r && abs(y) != r {
+					continue
+				}
+
+				if cell := c.Add(x, y); bounds.Contains(cell) && !isBlocked(cell) {
+					return cell, true
+				}
+			}
+		}
+	}
+
+	return coord.Cell{}, false
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribePathfinding(c gospec.Context) {
+	bounds := coord.Bounds{cell(-5, 5), cell(5, -5)}
+
+	blockedBy := func(cells ...coord.Cell) func(coord.Cell) bool {
+		return func(c coord.Cell) bool {
+			for _, blocked := range cells {
+				if c == blocked {
+					return true
+				}
+			}
+			return false
+		}
+	}
+
+	walk := func(from coord.Cell, steps []coord.Direction) coord.Cell {
+		for _, d := range steps {
+			from = from.Neighbor(d)
+		}
+		return from
+	}
+
+	c.Specify("a path", func() {
+		c.Specify("is empty if it starts at the goal", func() {
+			steps, found := findPath(cell(0, 0), cell(0, 0), bounds, blockedBy())
+			c.Expect(found, IsTrue)
+			c.Expect(len(steps), Equals, 0)
+		})
+
+		c.Specify("is a straight line if nothing is blocked", func() {
+			steps, found := findPath(cell(0, 0), cell(0, 3), bounds, blockedBy())
+			c.Assume(found, IsTrue)
+			c.Expect(steps, ContainsExactly, []coord.Direction{coord.North, coord.North, coord.North})
+		})
+
+		c.Specify("goes around blocked cells", func() {
+			wall := blockedBy(cell(-1, 1), cell(0, 1), cell(1, 1))
+			steps, found := findPath(cell(0, 0), cell(0, 2), bounds, wall)
+			c.Assume(found, IsTrue)
+
+			c.Expect(len(steps), Equals, 6)
+			c.Expect(walk(cell(0, 0), steps), Equals, cell(0, 2))
+
+			at := cell(0, 0)
+			for _, d := range steps {
+				at = at.Neighbor(d)
+				c.Expect(wall(at), Not(IsTrue))
+			}
+		})
+
+		c.Specify("ends next to a blocked goal", func() {
+			steps, found := findPath(cell(0, 0), cell(0, 3), bounds, blockedBy(cell(0, 3)))
+			c.Assume(found, IsTrue)
+			c.Expect(walk(cell(0, 0), steps), Equals, cell(0, 2))
+		})
+
+		c.Specify("can't be found", func() {
+			c.Specify("if the goal is outside the bounds", func() {
+				_, found := findPath(cell(0, 0), cell(0, 6), bounds, blockedBy())
+				c.Expect(found, Not(IsTrue))
+			})
+
+			c.Specify("if the goal is enclosed", func() {
+				enclosed := blockedBy(cell(2, 3), cell(4, 3), cell(3, 4), cell(3, 2))
+				_, found := findPath(cell(0, 0), cell(3, 3), bounds, enclosed)
+				c.Expect(found, Not(IsTrue))
+			})
+		})
+	})
+
+	c.Specify("the blocked cells of a world state", func() {
+		self := ActorEntityState{Id: 0, bounds: coord.Bounds{cell(0, 0), cell(0, 0)}}
+		other := ActorEntityState{Id: 1, bounds: coord.Bounds{cell(1, 0), cell(2, 0)}}
+
+		blocked := blockedCells(rpg2d.WorldState{
+			Entities: entity.StateSlice{
+				self,
+				other,
+				WallEntityState{Id: 2, Cell: cell(0, 1)},
+				AssailEntityState{Id: 3, Cell: cell(0, -1)},
+			},
+		}, 0)
+
+		c.Specify("include walls and the cells of other moving actors", func() {
+			c.Expect(blocked[cell(0, 1)], IsTrue)
+			c.Expect(blocked[cell(1, 0)], IsTrue)
+			c.Expect(blocked[cell(2, 0)], IsTrue)
+		})
+
+		c.Specify("don't include the actor itself or entities that don't collide", func() {
+			c.Expect(blocked[cell(0, 0)], Not(IsTrue))
+			c.Expect(blocked[cell(0, -1)], Not(IsTrue))
+		})
+	})
+
+	c.Specify("an actor moving to a cell", func() {
+		a := &actor{
+			actorEntity: actorEntity{
+				cell:   cell(0, 0),
+				facing: coord.North,
+			},
+		}
+
+		cmd := &moveCmd{dest: &coord.Cell{0, 3}}
+		a.path = &movePath{cmd, []coord.Direction{coord.North, coord.North, coord.North}}
+
+		c.Specify("plans again if a step is reverted", func() {
+			path := pa(0, 15, cell(0, 0), cell(0, 1))
+			a.applyPathAction(&path)
+			a.revertMoveAction()
+
+			c.Expect(a.path, IsNil)
+		})
+	})
+}
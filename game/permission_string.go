@@ -0,0 +1,25 @@
+// Code generated by "stringer -type=Permission"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[PERM_PLAYER-0]
+	_ = x[PERM_ADMIN-1]
+}
+
+const _Permission_name = "PERM_PLAYERPERM_ADMIN"
+
+var _Permission_index = [...]uint8{0, 11, 21}
+
+func (i Permission) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Permission_index)-1 {
+		return "Permission(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Permission_name[_Permission_index[idx]:_Permission_index[idx+1]]
+}
@@ -54,9 +54,15 @@ type mockActor struct {
 
 	entityState game.ActorEntityState
 
-	lastMoveRequest chan game.MoveRequest
-	lastUseRequest  chan game.UseRequest
-	lastChatRequest chan game.ChatRequest
+	lastMoveRequest  chan game.MoveRequest
+	lastUseRequest   chan game.UseRequest
+	lastChatRequest  chan game.ChatRequest
+	lastBuildRequest chan game.BuildRequest
+	lastCraftRequest chan game.CraftRequest
+	lastEquipRequest chan game.EquipRequest
+	lastEmoteRequest chan game.EmoteRequest
+	lastPartyRequest chan game.PartyRequest
+	lastTradeRequest chan game.TradeRequest
 
 	wasClosed bool
 }
@@ -70,6 +76,24 @@ func (a *mockActor) SubmitUseRequest(r game.UseRequest) {
 func (a *mockActor) SubmitChatRequest(r game.ChatRequest) {
 	a.lastChatRequest <- r
 }
+func (a *mockActor) SubmitBuildRequest(r game.BuildRequest) {
+	a.lastBuildRequest <- r
+}
+func (a *mockActor) SubmitCraftRequest(r game.CraftRequest) {
+	a.lastCraftRequest <- r
+}
+func (a *mockActor) SubmitEquipRequest(r game.EquipRequest) {
+	a.lastEquipRequest <- r
+}
+func (a *mockActor) SubmitEmoteRequest(r game.EmoteRequest) {
+	a.lastEmoteRequest <- r
+}
+func (a *mockActor) SubmitPartyRequest(r game.PartyRequest) {
+	a.lastPartyRequest <- r
+}
+func (a *mockActor) SubmitTradeRequest(r game.TradeRequest) {
+	a.lastTradeRequest <- r
+}
 
 func (a mockActor) Close() { a.wasClosed = true }
 
@@ -103,11 +127,11 @@ func DescribeActorGobConn(c gospec.Context) {
 	// so we can sync before the function scope
 	// is exitted.
 	func() {
-		loginConn := game.NewPreLoginConn(game.NewGobConn(conn.nextEndpoint()), ds)
+		loginConn := game.NewPreLoginConn(game.NewGobConn(conn.nextEndpoint()), ds, nil)
 
 		go func() {
 			exitWithError <- game.LoginAndConnectActor(loginConn,
-				func(dsactor datastore.Actor, stateWriter game.InitialStateWriter) (game.InputReceiver, entity.State) {
+				func(dsactor datastore.Actor, stateWriter game.InitialStateWriter, _ *game.Logger) (game.InputReceiver, entity.State) {
 					actor := &mockActor{
 						actor:       dsactor,
 						stateWriter: stateWriter,
@@ -116,9 +140,15 @@ func DescribeActorGobConn(c gospec.Context) {
 							Name: dsactor.Name,
 						},
 
-						lastMoveRequest: make(chan game.MoveRequest),
-						lastUseRequest:  make(chan game.UseRequest),
-						lastChatRequest: make(chan game.ChatRequest),
+						lastMoveRequest:  make(chan game.MoveRequest),
+						lastUseRequest:   make(chan game.UseRequest),
+						lastChatRequest:  make(chan game.ChatRequest),
+						lastBuildRequest: make(chan game.BuildRequest),
+						lastCraftRequest: make(chan game.CraftRequest),
+						lastEquipRequest: make(chan game.EquipRequest),
+						lastEmoteRequest: make(chan game.EmoteRequest),
+						lastPartyRequest: make(chan game.PartyRequest),
+						lastTradeRequest: make(chan game.TradeRequest),
 					}
 					actorConnected <- actor
 					return actor, actor.entityState
@@ -297,7 +327,7 @@ func DescribeActorGobConn(c gospec.Context) {
 				// so we can sync before the function scope
 				// is exitted.
 				func() {
-					loginConn := game.NewPreLoginConn(game.NewGobConn(conn.nextEndpoint()), ds)
+					loginConn := game.NewPreLoginConn(game.NewGobConn(conn.nextEndpoint()), ds, nil)
 
 					go func() {
 						exitWithError <- game.LoginAndConnectActor(loginConn, nil)
@@ -408,7 +438,31 @@ func DescribeActorGobConn(c gospec.Context) {
 
 					update, err := connectResp.NextUpdate()
 					c.Assume(err, IsNil)
-					c.Expect(update, rpg2dtest.StateEquals, diff)
+					c.Expect(update.WorldStateDiff, rpg2dtest.StateEquals, diff)
+				}))
+
+				c.Specify("followed by messages sent to the actor", withStopServer(func() {
+					msgs := game.ActorMsgs{[]game.ActorMsg{
+						game.CooldownsMsg{
+							Time:      2,
+							Cooldowns: []game.Cooldown{{"assail", 42}},
+						},
+						game.UseRejectedMsg{
+							Time:   2,
+							Skill:  "assail",
+							Reason: game.URR_COOLDOWN,
+						},
+					}}
+
+					go func() {
+						diffWriter.WriteActorMsgs(msgs)
+					}()
+
+					update, err := connectResp.NextUpdate()
+					c.Assume(err, IsNil)
+					c.Assume(len(update.Msgs), Equals, 2)
+					c.Expect(update.Msgs[0].(game.CooldownsMsg).Cooldowns, ContainsExactly, []game.Cooldown{{"assail", 42}})
+					c.Expect(update.Msgs[1], Equals, msgs.Msgs[1])
 				}))
 			}))
 
@@ -441,6 +495,69 @@ func DescribeActorGobConn(c gospec.Context) {
 				connectResp.InputConn.SendChatRequest(r)
 				c.Expect(<-actor.lastChatRequest, Equals, r)
 			}))
+
+			c.Specify("can submit a build request", withStopServer(func() {
+				r := game.BuildRequest{
+					BuildRequestType: game.BR_SCULPT,
+					Time:             2,
+					Cell:             coord.Cell{1, -1},
+					Terrain:          rpg2d.TT_ROCK,
+				}
+				connectResp.InputConn.SendBuildRequest(r)
+				c.Expect(<-actor.lastBuildRequest, Equals, r)
+			}))
+
+			c.Specify("can submit a craft request", withStopServer(func() {
+				r := game.CraftRequest{
+					Time:   2,
+					Recipe: "sword",
+				}
+				connectResp.InputConn.SendCraftRequest(r)
+				c.Expect(<-actor.lastCraftRequest, Equals, r)
+			}))
+
+			c.Specify("can submit an equip request", withStopServer(func() {
+				r := game.EquipRequest{
+					Time: 2,
+					Slot: game.ES_WEAPON,
+					Item: "sword",
+				}
+				connectResp.InputConn.SendEquipRequest(r)
+				c.Expect(<-actor.lastEquipRequest, Equals, r)
+			}))
+
+			c.Specify("can submit an emote request", withStopServer(func() {
+				r := game.EmoteRequest{
+					Time:  2,
+					Emote: "wave",
+				}
+				connectResp.InputConn.SendEmoteRequest(r)
+				c.Expect(<-actor.lastEmoteRequest, Equals, r)
+			}))
+
+			c.Specify("can submit a party request", withStopServer(func() {
+				r := game.PartyRequest{
+					PartyRequestType: game.PR_INVITE,
+					Time:             2,
+					Name:             "actor",
+				}
+				connectResp.InputConn.SendPartyRequest(r)
+				c.Expect(<-actor.lastPartyRequest, Equals, r)
+			}))
+
+			c.Specify("can submit a trade request", withStopServer(func() {
+				r := game.TradeRequest{
+					TradeRequestType: game.TR_OFFER,
+					Time:             2,
+					Items:            game.Resources{"wood": 5},
+				}
+				connectResp.InputConn.SendTradeRequest(r)
+
+				submitted := <-actor.lastTradeRequest
+				c.Expect(submitted.TradeRequestType, Equals, game.TR_OFFER)
+				c.Expect(submitted.Time, Equals, r.Time)
+				c.Expect(submitted.Items["wood"], Equals, 5)
+			}))
 		}))
 	}))
 }
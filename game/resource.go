@@ -0,0 +1,58 @@
+package game
+
+import (
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Quantities of resources indexed by name.
+type Resources map[string]int
+
+// The resources every actor begins with.
+var startingResources = Resources{
+	"stone": 20,
+	"wood":  20,
+}
+
+func (r Resources) clone() Resources {
+	c := make(Resources, len(r))
+	for name, n := range r {
+		c[name] = n
+	}
+	return c
+}
+
+// Returns true if there are enough resources to pay the cost.
+func (r Resources) has(cost Resources) bool {
+	for name, n := range cost {
+		if r[name] < n {
+			return false
+		}
+	}
+	return true
+}
+
+// Removes the cost from the resources. Check the
+// cost can be paid with has() before spending.
+func (r Resources) spend(cost Resources) {
+	for name, n := range cost {
+		r[name] -= n
+	}
+}
+
+func (r Resources) add(other Resources) {
+	for name, n := range other {
+		r[name] += n
+	}
+}
+
+// Sent to an actor's connection every time
+// the actor's resources have changed.
+type ResourcesMsg struct {
+	Time      stime.Time `json:"time"`
+	Resources Resources  `json:"resources"`
+}
+
+// Sends the actor's resources to its connection.
+func (a *actor) sendResources(now stime.Time) {
+	a.queueMsg(ResourcesMsg{now, a.resources.clone()})
+}
@@ -2,17 +2,16 @@ package game
 
 import (
 	"fmt"
-	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"text/template"
+	"time"
 
 	"github.com/ghthor/aodd/game/datastore"
 	"github.com/ghthor/filu/rpg2d"
 	"github.com/ghthor/filu/rpg2d/coord"
 	"github.com/ghthor/filu/rpg2d/entity"
-	"github.com/ghthor/filu/rpg2d/quad"
-	"github.com/ghthor/filu/sim/stime"
 )
 
 // Store actor's indexed by id
@@ -53,12 +52,35 @@ func NewActorIndexLocker(around ActorIndex) *ActorIndexLocker {
 type simulation struct {
 	*ActorIndexLocker
 	rpg2d.RunningSimulation
+
+	edits   *worldEdits
+	legend  *legendRecorder
+	chat    *chatChannels
+	parties *partyTable
+
+	// Cells actors controlled by players are spawned at
+	spawns []coord.Cell
 }
 
 func (s simulation) ConnectActor(a rpg2d.Actor) {
 	switch a := a.(type) {
 	case *actor:
+		a.placeAtSpawn(s.spawns)
+
+		a.edits = s.edits
+		a.legend = s.legend
+		a.connectedAt = time.Now()
 		a.startIO()
+
+		// Npcs can't be sent chat messages
+		if a.hasLegend() {
+			a.chat = s.chat
+			a.chatMod = newChatModeration()
+			a.chat.join(a)
+			a.parties = s.parties
+			a.parties.publish(a)
+		}
+
 		actorIndex := s.ActorIndexLocker.Lock()
 		actorIndex[a.Id()] = a
 		s.ActorIndexLocker.Unlock(actorIndex)
@@ -75,7 +97,11 @@ func (s simulation) RemoveActor(a rpg2d.Actor) {
 
 	switch a := a.(type) {
 	case *actor:
+		a.chat.leave(a)
+		a.parties.remove(a)
+		a.sendDeliveredMsgs()
 		a.stopIO()
+		a.recordTimePlayed()
 		actorIndex := s.ActorIndexLocker.Lock()
 		delete(actorIndex, a.Id())
 		s.ActorIndexLocker.Unlock(actorIndex)
@@ -85,10 +111,21 @@ func (s simulation) RemoveActor(a rpg2d.Actor) {
 	}
 }
 
-func NewSimulation(actorIndex *ActorIndexLocker, sim rpg2d.RunningSimulation) rpg2d.RunningSimulation {
+// Places an actor controlled by a player at one of the spawns.
+func (a *actor) placeAtSpawn(spawns []coord.Cell) {
+	if a.hasLegend() && len(spawns) > 0 {
+		a.spawn = spawns[int(a.id)%len(spawns)]
+		a.actorEntity.cell = a.spawn
+	}
+}
+
+func NewSimulation(actorIndex *ActorIndexLocker, sim rpg2d.RunningSimulation, edits *worldEdits, legend *legendRecorder, spawns []coord.Cell) rpg2d.RunningSimulation {
 	return simulation{
 		ActorIndexLocker:  actorIndex,
 		RunningSimulation: sim,
+		edits:             edits,
+		legend:            legend,
+		spawns:            spawns,
 	}
 }
 
@@ -153,6 +190,56 @@ type ShardConfig struct {
 	// when setting the Handler field of the *http.Server.
 	// If Handler is nil, the Mux will be used instead.
 	Handler http.Handler
+
+	// Generates the world that will be simulated during
+	// each epoch. If nil the arena will be simulated
+	// with the Npcs and ResourceNodes.
+	NewWorld WorldGenerator
+
+	// Npcs that will be spawned into the world
+	// when the simulation begins.
+	Npcs []NpcSpawn
+
+	// Inspects every chat message before it's sent.
+	// May be nil.
+	ChatFilter ChatFilter
+
+	// Names of the actors that are permitted to
+	// use the slash commands of administrators.
+	Admins []string
+
+	// Path to the file the changes players make to the
+	// world are saved in. If empty the changes will be
+	// lost when the server stops.
+	WorldEditsPath string
+
+	// Resource nodes that will be placed into
+	// the world when the simulation begins.
+	ResourceNodes []ResourceNodeSpawn
+
+	// Path to the file the legends of characters are
+	// appended to. Legends are kept separately from the
+	// world so they survive the world being reset. If
+	// empty the legends will be lost when the server stops.
+	LegendPath string
+
+	// Path to the file the guilds and the allegiances of
+	// characters are saved in. If empty the factions will
+	// be lost when the server stops.
+	FactionPath string
+
+	// The bearer token that authorizes requests to the
+	// admin api. The admin api isn't served if empty.
+	AdminToken string
+
+	// A mux the metrics of the shard will be served on at
+	// /metrics. May be the Mux if the metrics should be
+	// public. The metrics aren't served if nil.
+	MetricsMux *http.ServeMux
+
+	// Writes the logs of the shard and its connections.
+	// If nil logs are written to stderr at the info level.
+	Logger *Logger
 }
 
 type inputReceiver struct {
@@ -165,48 +252,48 @@ func (i inputReceiver) Close() {
 }
 
 func NewSimShard(c ShardConfig) (*http.Server, error) {
-	// TODO pull this information from a datastore
-	bounds := coord.Bounds{
-		coord.Cell{1, -1},
-		coord.Cell{128, -128},
+	newWorld := c.NewWorld
+	if newWorld == nil {
+		newWorld = ArenaWorld(c.Npcs, c.ResourceNodes)
 	}
 
-	quadTree, err := quad.New(bounds, quadMaxSize, nil)
-	if err != nil {
-		return nil, err
+	var worldStore datastore.WorldStore
+	if c.WorldEditsPath != "" {
+		worldStore = datastore.NewFileWorldStore(c.WorldEditsPath)
+	} else {
+		worldStore = datastore.NewMemWorldStore()
 	}
 
-	terrainMap, err := rpg2d.NewTerrainMap(bounds, startingTerrain)
-	if err != nil {
-		return nil, err
+	var legendStore datastore.LegendStore
+	if c.LegendPath != "" {
+		legendStore = datastore.NewFileLegendStore(c.LegendPath)
+	} else {
+		legendStore = datastore.NewMemLegendStore()
 	}
 
-	now := stime.Time(0)
-
-	actorIndex := NewActorIndexLocker(make(ActorIndex))
-
-	entityIdGen := entity.NewIdGenerator()
-
-	quadTree = addWalls(quadTree, entityIdGen)
-
-	simDef := rpg2d.SimulationDef{
-		FPS: 40,
-
-		// Initial World State
-		Now:        now,
-		QuadTree:   quadTree,
-		TerrainMap: terrainMap,
+	var factionStore datastore.FactionStore
+	if c.FactionPath != "" {
+		factionStore = datastore.NewFileFactionStore(c.FactionPath)
+	} else {
+		factionStore = datastore.NewMemFactionStore()
+	}
 
-		UpdatePhaseHandler: updatePhaseLocker{actorIndex},
-		InputPhaseHandler:  inputPhaseLocker{actorIndex, entityIdGen},
-		NarrowPhaseHandler: newNarrowPhaseLocker(actorIndex),
+	logger := c.Logger
+	if logger == nil {
+		logger = defaultLogger
 	}
 
-	runningSim, err := simDef.Begin()
+	shard, err := newShard(newWorld, worldStore, legendStore, factionStore, c.ChatFilter, logger)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, name := range c.Admins {
+		shard.permissions[strings.ToLower(name)] = PERM_ADMIN
+	}
+
+	bounds := shard.world.bounds
+
 	wsUrl := "ws://" + c.Domain
 	wsRoute := "/actor/socket/gob"
 
@@ -222,8 +309,8 @@ func NewSimShard(c ShardConfig) (*http.Server, error) {
 			c.JsMain,
 			wsUrl,
 			SimulationSettings{
-				Width:  quadTree.Bounds().Width(),
-				Height: quadTree.Bounds().Height(),
+				Width:  bounds.Width(),
+				Height: bounds.Height(),
 			},
 		},
 	}
@@ -231,26 +318,29 @@ func NewSimShard(c ShardConfig) (*http.Server, error) {
 	mux := c.Mux
 
 	ds := datastore.NewMemDatastore()
-	sim := NewSimulation(actorIndex, runningSim)
 
 	mux.Handle("/", indexHandler)
+	mux.Handle("/legend/", legendHandler{legendStore, logger})
+	if c.AdminToken != "" {
+		mux.Handle("/admin/", adminHandler{shard, c.AdminToken})
+	}
+	if c.MetricsMux != nil {
+		c.MetricsMux.Handle("/metrics", metricsHandler{shard})
+	}
 	mux.Handle("/js/", http.StripPrefix("/js/", http.FileServer(http.Dir(c.JsDir))))
 	mux.Handle("/asset/", http.StripPrefix("/asset/", http.FileServer(http.Dir(c.AssetDir))))
 	mux.Handle("/css/", http.StripPrefix("/css/", http.FileServer(http.Dir(c.CssDir))))
 	mux.Handle(wsRoute, newGobWebsocketHandler(
 		ds,
-		func(dsactor datastore.Actor, stateWriter InitialStateWriter) (InputReceiver, entity.State) {
-			actor := NewActor(entityIdGen(), dsactor, stateWriter)
-			sim.ConnectActor(actor)
+		func(dsactor datastore.Actor, stateWriter InitialStateWriter, logger *Logger) (InputReceiver, entity.State) {
+			actor, disconnect := shard.connect(dsactor, stateWriter, logger)
 
 			return inputReceiver{
-				actor: actor,
-				disconnect: func() {
-					sim.RemoveActor(actor)
-					log.Println("actor removed", actor.name)
-				},
+				actor:      actor,
+				disconnect: disconnect,
 			}, actor.Entity().ToState()
 		},
+		logger,
 	))
 
 	defaultHandler := c.Handler
@@ -203,6 +203,30 @@ func TestUnitSpecs(t *testing.T) {
 	r.AddSpec(game.Describe3Actors)
 	r.AddSpec(game.DescribeSomeActors)
 
+	r.AddSpec(game.DescribeAoeSkills)
+	r.AddSpec(game.DescribeCasting)
+	r.AddSpec(game.DescribeCooldowns)
+	r.AddSpec(game.DescribeNpcs)
+	r.AddSpec(game.DescribePathfinding)
+	r.AddSpec(game.DescribeTerrain)
+	r.AddSpec(game.DescribeBuilding)
+	r.AddSpec(game.DescribeResourceNodes)
+	r.AddSpec(game.DescribeCrafting)
+	r.AddSpec(game.DescribeEquipment)
+	r.AddSpec(game.DescribeLegend)
+	r.AddSpec(game.DescribeWorldEpochs)
+	r.AddSpec(game.DescribeWorldGeneration)
+	r.AddSpec(game.DescribeZones)
+	r.AddSpec(game.DescribeChatChannels)
+	r.AddSpec(game.DescribeSlashCommands)
+	r.AddSpec(game.DescribeEmotes)
+	r.AddSpec(game.DescribeParties)
+	r.AddSpec(game.DescribeFactions)
+	r.AddSpec(game.DescribeTrades)
+	r.AddSpec(game.DescribeAdminApi)
+	r.AddSpec(game.DescribeMetrics)
+	r.AddSpec(game.DescribeLogger)
+
 	var err error
 
 	if web {
@@ -1,6 +1,15 @@
 package game
 
-import "github.com/ghthor/filu/rpg2d/coord"
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+)
 
 const startingTerrain = `
 GGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGG
@@ -134,3 +143,321 @@ GGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGG
 `
 
 var origin = coord.Cell{65, -65}
+
+const TT_WATER rpg2d.TerrainType = 'W'
+
+// Gameplay properties of a type of terrain.
+type terrainProperties struct {
+	// If actors can move into the terrain
+	walkable bool
+
+	// Multiplies the speed of an actor moving into
+	// the terrain. Less than 1 will slow the actor.
+	speed float64
+}
+
+// The properties of every type of terrain. Terrain
+// that isn't in the table can't be walked on.
+var terrainTable = map[rpg2d.TerrainType]terrainProperties{
+	rpg2d.TT_GRASS: {walkable: true, speed: 1},
+	rpg2d.TT_DIRT:  {walkable: true, speed: 1},
+	rpg2d.TT_ROCK:  {walkable: true, speed: 0.5},
+	TT_WATER:       {walkable: false},
+}
+
+// Used when a phase hasn't been given any terrain.
+var defaultTerrain = terrainProperties{walkable: true, speed: 1}
+
+// Provides the terrain of a world one chunk at a time
+// so the whole world never has to be held in memory.
+type TerrainSource interface {
+	// Returns the terrain of the cells within the bounds
+	// indexed by [row][column] from the top left cell.
+	TerrainOf(coord.Bounds) [][]rpg2d.TerrainType
+}
+
+// A source that has every cell of the world in memory.
+type terrainArray rpg2d.TerrainMap
+
+func (m terrainArray) TerrainOf(b coord.Bounds) [][]rpg2d.TerrainType {
+	types := make([][]rpg2d.TerrainType, b.Height())
+	for y := range types {
+		row := m.TerrainTypes[m.Bounds.TopL.Y-b.TopL.Y+y]
+		x := b.TopL.X - m.Bounds.TopL.X
+		types[y] = append([]rpg2d.TerrainType(nil), row[x:x+b.Width()]...)
+	}
+	return types
+}
+
+// Width and height in cells of the chunks the
+// terrain of a world is stored in.
+const terrainChunkSize = 64
+
+// Chunks are numbered from the top left of the world.
+type terrainChunkKey struct {
+	x, y int
+}
+
+type terrainChunk struct {
+	types [][]rpg2d.TerrainType
+
+	// The last sweep the chunk was used during
+	used int64
+}
+
+// The terrain type of every cell in the world. Terrain
+// is loaded from the source one chunk at a time when it
+// is first needed and is unloaded once it hasn't been
+// used for an entire sweep. Every actor's view of the
+// terrain is sliced from the index each tick so the
+// chunks near actors are never unloaded.
+type terrainIndex struct {
+	mu     sync.RWMutex
+	bounds coord.Bounds
+	source TerrainSource
+
+	chunks map[terrainChunkKey]*terrainChunk
+
+	// Incremented each time unused chunks are unloaded
+	sweep int64
+
+	// Cells that have been changed since the world began.
+	// Are applied when a chunk is loaded so the changes
+	// survive the chunk being unloaded.
+	changed map[terrainChunkKey]map[coord.Cell]rpg2d.TerrainType
+}
+
+func newTerrainIndex(bounds coord.Bounds, source TerrainSource) *terrainIndex {
+	return &terrainIndex{
+		bounds:  bounds,
+		source:  source,
+		chunks:  make(map[terrainChunkKey]*terrainChunk),
+		changed: make(map[terrainChunkKey]map[coord.Cell]rpg2d.TerrainType),
+	}
+}
+
+// Returns an index of the terrain in the map. The map
+// isn't modified when the terrain of the index is changed.
+func terrainIndexOf(m rpg2d.TerrainMap) *terrainIndex {
+	return newTerrainIndex(m.Bounds, terrainArray(m))
+}
+
+// The cell must be inside of the world.
+func (t *terrainIndex) chunkOf(c coord.Cell) terrainChunkKey {
+	return terrainChunkKey{
+		(c.X - t.bounds.TopL.X) / terrainChunkSize,
+		(t.bounds.TopL.Y - c.Y) / terrainChunkSize,
+	}
+}
+
+// Chunks at the right and bottom edges of the
+// world can be smaller than the chunk size.
+func (t *terrainIndex) chunkBounds(k terrainChunkKey) coord.Bounds {
+	topL := t.bounds.TopL.Add(k.x*terrainChunkSize, -k.y*terrainChunkSize)
+	botR := topL.Add(terrainChunkSize-1, -(terrainChunkSize - 1))
+
+	if botR.X > t.bounds.BotR.X {
+		botR.X = t.bounds.BotR.X
+	}
+
+	if botR.Y < t.bounds.BotR.Y {
+		botR.Y = t.bounds.BotR.Y
+	}
+
+	return coord.Bounds{topL, botR}
+}
+
+// Must be called with the write lock held.
+func (t *terrainIndex) load(k terrainChunkKey) [][]rpg2d.TerrainType {
+	chunk, loaded := t.chunks[k]
+	if loaded {
+		return chunk.types
+	}
+
+	b := t.chunkBounds(k)
+	types := t.source.TerrainOf(b)
+	for c, tt := range t.changed[k] {
+		types[b.TopL.Y-c.Y][c.X-b.TopL.X] = tt
+	}
+
+	t.chunks[k] = &terrainChunk{types, t.sweep}
+	return types
+}
+
+// Returns the chunk with the cell loaded and
+// marks it as used during the current sweep.
+func (t *terrainIndex) chunkWith(c coord.Cell) ([][]rpg2d.TerrainType, coord.Bounds) {
+	k := t.chunkOf(c)
+
+	t.mu.RLock()
+	chunk, loaded := t.chunks[k]
+	if loaded {
+		atomic.StoreInt64(&chunk.used, t.sweep)
+	}
+	t.mu.RUnlock()
+
+	if loaded {
+		return chunk.types, t.chunkBounds(k)
+	}
+
+	t.mu.Lock()
+	types := t.load(k)
+	t.mu.Unlock()
+
+	return types, t.chunkBounds(k)
+}
+
+// Returns false if the cell is outside of the world.
+func (t *terrainIndex) typeAt(c coord.Cell) (rpg2d.TerrainType, bool) {
+	if !t.bounds.Contains(c) {
+		return 0, false
+	}
+
+	chunk, b := t.chunkWith(c)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return chunk[b.TopL.Y-c.Y][c.X-b.TopL.X], true
+}
+
+// The cell must be inside of the world.
+func (t *terrainIndex) setType(tt rpg2d.TerrainType, c coord.Cell) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := t.chunkOf(c)
+	b := t.chunkBounds(k)
+	t.load(k)[b.TopL.Y-c.Y][c.X-b.TopL.X] = tt
+
+	if t.changed[k] == nil {
+		t.changed[k] = make(map[coord.Cell]rpg2d.TerrainType)
+	}
+	t.changed[k][c] = tt
+}
+
+// Returns the terrain within the bounds. Cells outside of
+// the world are excluded. Returns nil if none of the
+// bounds are inside of the world.
+func (t *terrainIndex) slice(b coord.Bounds) *rpg2d.TerrainMapState {
+	if !t.bounds.Overlaps(b) {
+		return nil
+	}
+
+	// Clip the bounds to the world
+	if b.TopL.X < t.bounds.TopL.X {
+		b.TopL.X = t.bounds.TopL.X
+	}
+	if b.TopL.Y > t.bounds.TopL.Y {
+		b.TopL.Y = t.bounds.TopL.Y
+	}
+	if b.BotR.X > t.bounds.BotR.X {
+		b.BotR.X = t.bounds.BotR.X
+	}
+	if b.BotR.Y < t.bounds.BotR.Y {
+		b.BotR.Y = t.bounds.BotR.Y
+	}
+
+	types := make([][]rpg2d.TerrainType, b.Height())
+	for y := range types {
+		types[y] = make([]rpg2d.TerrainType, 0, b.Width())
+	}
+
+	// Copy each chunk's rows that are within the bounds
+	topK, botK := t.chunkOf(b.TopL), t.chunkOf(b.BotR)
+	for ky := topK.y; ky <= botK.y; ky++ {
+		for kx := topK.x; kx <= botK.x; kx++ {
+			chunk, cb := t.chunkWith(t.chunkBounds(terrainChunkKey{kx, ky}).TopL)
+
+			x0, x1 := cb.TopL.X, cb.BotR.X
+			if x0 < b.TopL.X {
+				x0 = b.TopL.X
+			}
+			if x1 > b.BotR.X {
+				x1 = b.BotR.X
+			}
+
+			y0, y1 := cb.TopL.Y, cb.BotR.Y
+			if y0 > b.TopL.Y {
+				y0 = b.TopL.Y
+			}
+			if y1 < b.BotR.Y {
+				y1 = b.BotR.Y
+			}
+
+			t.mu.RLock()
+			for y := y0; y >= y1; y-- {
+				row := chunk[cb.TopL.Y-y][x0-cb.TopL.X : x1-cb.TopL.X+1]
+				types[b.TopL.Y-y] = append(types[b.TopL.Y-y], row...)
+			}
+			t.mu.RUnlock()
+		}
+	}
+
+	return &rpg2d.TerrainMapState{rpg2d.TerrainMap{
+		Bounds:       b,
+		TerrainTypes: types,
+	}}
+}
+
+// Unloads the chunks that haven't been used since
+// the previous sweep. Changes made to the terrain of
+// an unloaded chunk are kept. Returns the number of
+// chunks that were unloaded.
+func (t *terrainIndex) unloadUnused() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	unloaded := 0
+	for k, chunk := range t.chunks {
+		if atomic.LoadInt64(&chunk.used) < t.sweep {
+			delete(t.chunks, k)
+			unloaded++
+		}
+	}
+
+	t.sweep++
+	return unloaded
+}
+
+// Sweeps the index every period until stopped.
+func (t *terrainIndex) unloadUnusedEvery(period time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			t.unloadUnused()
+		}
+	}
+}
+
+// Returns the number of chunks in memory.
+func (t *terrainIndex) loadedChunks() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.chunks)
+}
+
+// Returns the properties of the terrain at the cell.
+// A nil index has walkable terrain everywhere.
+func (t *terrainIndex) propertiesAt(c coord.Cell) terrainProperties {
+	if t == nil {
+		return defaultTerrain
+	}
+
+	tt, exists := t.typeAt(c)
+	if !exists {
+		return terrainProperties{}
+	}
+
+	return terrainTable[tt]
+}
+
+// Returns the number of frames it will take to
+// move into the cell at the base speed.
+func (p terrainProperties) moveDuration(speed int) stime.Time {
+	return stime.Time(math.Ceil(float64(speed) / p.speed))
+}
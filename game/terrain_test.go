@@ -0,0 +1,303 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeTerrain(c gospec.Context) {
+	// G R
+	// W D
+	terrain := terrainIndexOf(rpg2d.TerrainMap{
+		Bounds: coord.Bounds{cell(0, 0), cell(1, -1)},
+		TerrainTypes: [][]rpg2d.TerrainType{
+			{rpg2d.TT_GRASS, rpg2d.TT_ROCK},
+			{TT_WATER, rpg2d.TT_DIRT},
+		},
+	})
+
+	c.Specify("terrain", func() {
+		c.Specify("is looked up by cell", func() {
+			tt, _ := terrain.typeAt(cell(1, 0))
+			c.Expect(tt, Equals, rpg2d.TT_ROCK)
+			tt, _ = terrain.typeAt(cell(0, -1))
+			c.Expect(tt, Equals, TT_WATER)
+		})
+
+		c.Specify("can be walked on", func() {
+			c.Expect(terrain.propertiesAt(cell(0, 0)).walkable, IsTrue)
+			c.Expect(terrain.propertiesAt(cell(1, -1)).walkable, IsTrue)
+		})
+
+		c.Specify("can't be walked on", func() {
+			c.Specify("if it is water", func() {
+				c.Expect(terrain.propertiesAt(cell(0, -1)).walkable, Not(IsTrue))
+			})
+
+			c.Specify("if it is outside the world", func() {
+				c.Expect(terrain.propertiesAt(cell(2, 0)).walkable, Not(IsTrue))
+			})
+		})
+
+		c.Specify("changes how long it takes to move", func() {
+			c.Expect(terrain.propertiesAt(cell(0, 0)).moveDuration(baseSpeed), Equals, stime.Time(baseSpeed))
+			c.Expect(terrain.propertiesAt(cell(1, 0)).moveDuration(baseSpeed), Equals, stime.Time(baseSpeed*2))
+		})
+
+		c.Specify("is walkable everywhere if there isn't an index", func() {
+			var none *terrainIndex
+			c.Expect(none.propertiesAt(cell(100, 100)), Equals, defaultTerrain)
+		})
+
+		c.Specify("prevents an actor from moving into it", func() {
+			a := &actor{
+				actorEntity: actorEntity{
+					cell:   cell(0, 0),
+					facing: coord.South,
+					speed:  baseSpeed,
+				},
+			}
+
+			c.Expect(a.step(coord.South, terrain, 10), Not(IsTrue))
+			c.Expect(a.pathAction, IsNil)
+		})
+
+		c.Specify("is avoided by a path", func() {
+			steps, found := findPath(cell(0, 0), cell(1, -1), terrain.bounds, func(c coord.Cell) bool {
+				return !terrain.propertiesAt(c).walkable
+			})
+
+			c.Assume(found, IsTrue)
+			c.Expect(steps, ContainsExactly, []coord.Direction{coord.East, coord.South})
+		})
+	})
+
+	c.Specify("a chunked terrain index", func() {
+		noise := NoiseTerrain{Seed: 1, Water: 0.2, Rock: 0.2}
+		source := &countingTerrainSource{TerrainSource: noise}
+
+		// 3 chunks wide and 2 chunks high
+		bounds := coord.Bounds{
+			cell(1, -1),
+			cell(terrainChunkSize*2+10, -(terrainChunkSize + 10)),
+		}
+		terrain := newTerrainIndex(bounds, source)
+
+		c.Specify("only loads the chunk with a cell", func() {
+			at := cell(terrainChunkSize+5, -5)
+			tt, exists := terrain.typeAt(at)
+			c.Assume(exists, IsTrue)
+			c.Expect(tt, Equals, noise.typeAt(at))
+			c.Expect(terrain.loadedChunks(), Equals, 1)
+			c.Expect(source.loaded, Equals, 1)
+
+			terrain.typeAt(at.Add(1, -1))
+			c.Expect(source.loaded, Equals, 1)
+		})
+
+		c.Specify("has smaller chunks at the edges of the world", func() {
+			b := terrain.chunkBounds(terrainChunkKey{2, 1})
+			c.Expect(b.Width(), Equals, 10)
+			c.Expect(b.Height(), Equals, 10)
+		})
+
+		c.Specify("slices terrain across chunks", func() {
+			b := coord.Bounds{
+				cell(terrainChunkSize-2, -(terrainChunkSize - 2)),
+				cell(terrainChunkSize+2, -(terrainChunkSize + 2)),
+			}
+
+			slice := terrain.slice(b)
+			c.Assume(slice, Not(IsNil))
+			c.Expect(slice.Bounds, Equals, b)
+			c.Expect(fmt.Sprint(slice.TerrainTypes), Equals, fmt.Sprint(noise.TerrainOf(b)))
+			c.Expect(terrain.loadedChunks(), Equals, 4)
+		})
+
+		c.Specify("slices terrain clipped to the world", func() {
+			slice := terrain.slice(coord.Bounds{cell(-5, 5), cell(3, -3)})
+			c.Assume(slice, Not(IsNil))
+			c.Expect(slice.Bounds, Equals, coord.Bounds{cell(1, -1), cell(3, -3)})
+			c.Expect(len(slice.TerrainTypes), Equals, 3)
+			c.Expect(len(slice.TerrainTypes[0]), Equals, 3)
+		})
+
+		c.Specify("has no terrain outside of the world", func() {
+			c.Expect(terrain.slice(coord.Bounds{cell(-5, 5), cell(-1, 1)}), IsNil)
+		})
+
+		c.Specify("unloads chunks that haven't been used", func() {
+			terrain.typeAt(cell(1, -1))
+			terrain.typeAt(cell(terrainChunkSize+1, -1))
+
+			c.Expect(terrain.unloadUnused(), Equals, 0)
+
+			// Only 1 chunk is used before the next sweep
+			terrain.typeAt(cell(1, -1))
+			c.Expect(terrain.unloadUnused(), Equals, 1)
+			c.Expect(terrain.loadedChunks(), Equals, 1)
+		})
+
+		c.Specify("keeps changes to chunks that are unloaded", func() {
+			at := cell(terrainChunkSize+1, -1)
+			terrain.setType(TT_WATER, at)
+			terrain.setType(rpg2d.TT_ROCK, at.Add(1, 0))
+
+			terrain.unloadUnused()
+			terrain.unloadUnused()
+			c.Assume(terrain.loadedChunks(), Equals, 0)
+
+			tt, _ := terrain.typeAt(at)
+			c.Expect(tt, Equals, TT_WATER)
+			tt, _ = terrain.typeAt(at.Add(1, 0))
+			c.Expect(tt, Equals, rpg2d.TT_ROCK)
+		})
+	})
+
+	c.Specify("noise terrain", func() {
+		noise := NoiseTerrain{Seed: 1, Water: 0.2, Rock: 0.2}
+
+		c.Specify("is the same however it's sliced", func() {
+			whole := noise.TerrainOf(coord.Bounds{cell(-20, 20), cell(20, -20)})
+			part := noise.TerrainOf(coord.Bounds{cell(-3, 3), cell(3, -3)})
+
+			for y, row := range part {
+				c.Expect(fmt.Sprint(row), Equals, fmt.Sprint(whole[17+y][17:24]))
+			}
+		})
+
+		c.Specify("is different for another seed", func() {
+			b := coord.Bounds{cell(1, -1), cell(32, -32)}
+			other := NoiseTerrain{Seed: 2, Water: 0.2, Rock: 0.2}
+			c.Expect(fmt.Sprint(other.TerrainOf(b)), Not(Equals), fmt.Sprint(noise.TerrainOf(b)))
+		})
+	})
+}
+
+// Counts the chunks that are loaded from the source.
+type countingTerrainSource struct {
+	TerrainSource
+	loaded int
+}
+
+func (s *countingTerrainSource) TerrainOf(b coord.Bounds) [][]rpg2d.TerrainType {
+	s.loaded++
+	return s.TerrainSource.TerrainOf(b)
+}
+
+// Width and height in cells of the world used by the benchmarks.
+const benchmarkWorldSize = 50000
+
+func benchmarkWorldBounds() coord.Bounds {
+	return coord.Bounds{
+		cell(1, -1),
+		cell(benchmarkWorldSize, -benchmarkWorldSize),
+	}
+}
+
+// Slices the terrain each actor would be sent. The actors
+// are spread across the world so a chunk is loaded for
+// most of the slices.
+func BenchmarkTerrainSliceChunked(b *testing.B) {
+	terrain := newTerrainIndex(benchmarkWorldBounds(), NoiseTerrain{Seed: 1, Water: 0.2, Rock: 0.2})
+	r := rand.New(rand.NewSource(1))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		center := cell(r.Intn(benchmarkWorldSize)+1, -(r.Intn(benchmarkWorldSize) + 1))
+		terrain.slice(ActorCullBounds(center))
+
+		// Keep the memory used by the benchmark bounded
+		if terrain.loadedChunks() > 1024 {
+			terrain.unloadUnused()
+			terrain.unloadUnused()
+		}
+	}
+}
+
+// Slices the terrain an actor would be sent while the
+// chunks around the actor remain loaded.
+func BenchmarkTerrainSliceLoaded(b *testing.B) {
+	terrain := newTerrainIndex(benchmarkWorldBounds(), NoiseTerrain{Seed: 1, Water: 0.2, Rock: 0.2})
+	bounds := ActorCullBounds(cell(benchmarkWorldSize/2, -benchmarkWorldSize/2))
+	terrain.slice(bounds)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		terrain.slice(bounds)
+	}
+}
+
+func BenchmarkTerrainTypeAt(b *testing.B) {
+	terrain := newTerrainIndex(benchmarkWorldBounds(), NoiseTerrain{Seed: 1, Water: 0.2, Rock: 0.2})
+	center := cell(benchmarkWorldSize/2, -benchmarkWorldSize/2)
+	terrain.slice(ActorCullBounds(center))
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		terrain.typeAt(center.Add(i%20-10, i%16-8))
+	}
+}
+
+// Inserts walls spread across the world into a quad tree.
+func BenchmarkQuadTreeInsert(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			r := rand.New(rand.NewSource(1))
+			cells := make([]coord.Cell, n)
+			for i := range cells {
+				cells[i] = cell(r.Intn(benchmarkWorldSize)+1, -(r.Intn(benchmarkWorldSize) + 1))
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				qt, err := quad.New(benchmarkWorldBounds(), quadMaxSize, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				qt = addWalls(qt, cells, entity.NewIdGenerator())
+			}
+		})
+	}
+}
+
+// Queries the entities within an actor's view of
+// a quad tree filled with walls.
+func BenchmarkQuadTreeQueryBounds(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	cells := make([]coord.Cell, 100000)
+	for i := range cells {
+		cells[i] = cell(r.Intn(benchmarkWorldSize)+1, -(r.Intn(benchmarkWorldSize) + 1))
+	}
+
+	qt, err := quad.New(benchmarkWorldBounds(), quadMaxSize, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	qt = addWalls(qt, cells, entity.NewIdGenerator())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		qt.QueryBounds(ActorCullBounds(cells[i%len(cells)]))
+	}
+}
@@ -0,0 +1,387 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+type TradeRequestType int
+
+//go:generate stringer -type=TradeRequestType
+const (
+	TR_ERROR TradeRequestType = iota
+	TR_REQUEST
+	TR_OFFER
+	TR_LOCK
+	TR_CONFIRM
+	TR_CANCEL
+	TR_SIZE
+)
+
+type TradeRequest struct {
+	TradeRequestType
+	stime.Time
+
+	// The actor a trade is being requested with.
+	// Only used by trade requests.
+	Name string
+
+	// The items being offered. Replaces any previous
+	// offer. Only used by offer requests.
+	Items Resources
+}
+
+// Params of a trade request are the name of the actor.
+// Params of an offer are a list of item counts
+// such as "wood 5 stone 2".
+func newTradeRequest(t TradeRequestType, timeIssued stime.Time, params string) (TradeRequest, error) {
+	r := TradeRequest{TradeRequestType: t, Time: timeIssued}
+
+	switch t {
+	case TR_REQUEST:
+		r.Name = strings.TrimSpace(params)
+		if r.Name == "" {
+			return TradeRequest{}, errors.New("trade request without a name")
+		}
+
+	case TR_OFFER:
+		items, err := parseItems(params)
+		if err != nil {
+			return TradeRequest{}, err
+		}
+		r.Items = items
+
+	case TR_LOCK, TR_CONFIRM, TR_CANCEL:
+	default:
+		return TradeRequest{}, fmt.Errorf("unknown trade request: %v", t)
+	}
+
+	return r, nil
+}
+
+func parseItems(params string) (Resources, error) {
+	fields := strings.Fields(params)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid items: %s", params)
+	}
+
+	items := make(Resources, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid item count: %s", fields[i+1])
+		}
+
+		items[fields[i]] += n
+	}
+
+	return items, nil
+}
+
+type tradeCmd struct {
+	TradeRequestType
+	stime.Time
+	name  string
+	items Resources
+}
+
+type TradeRejectedReason int
+
+//go:generate stringer -type=TradeRejectedReason
+const (
+	TRR_ERROR TradeRejectedReason = iota
+	TRR_NO_ACTOR
+	TRR_NOT_ADJACENT
+	TRR_TRADING
+	TRR_BUSY
+	TRR_NOT_TRADING
+	TRR_LOCKED
+	TRR_NOT_LOCKED
+	TRR_RESOURCES
+)
+
+// Sent to an actor's connection when a
+// trade request could not be performed.
+type TradeRejectedMsg struct {
+	Time    stime.Time          `json:"time"`
+	Request TradeRequestType    `json:"request"`
+	Name    string              `json:"name"`
+	Reason  TradeRejectedReason `json:"reason"`
+}
+
+// Sent to an actor another actor has requested to trade with.
+type TradeInviteMsg struct {
+	Time stime.Time `json:"time"`
+	From string     `json:"from"`
+}
+
+type TradeStatus int
+
+//go:generate stringer -type=TradeStatus
+const (
+	TS_OPEN TradeStatus = iota
+	TS_COMPLETED
+	TS_CANCELLED
+
+	// The trade was cancelled automatically
+	TS_MOVED
+	TS_DAMAGED
+	TS_DISCONNECTED
+	TS_UNAVAILABLE
+)
+
+type TradeOfferState struct {
+	Name      string    `json:"name"`
+	Items     Resources `json:"items"`
+	Locked    bool      `json:"locked"`
+	Confirmed bool      `json:"confirmed"`
+}
+
+// Sent to both actors every time their trade changes.
+type TradeMsg struct {
+	Time   stime.Time  `json:"time"`
+	Status TradeStatus `json:"status"`
+
+	// The offer of the actor the message is sent to
+	Mine TradeOfferState `json:"mine"`
+
+	// The offer of the other actor
+	Theirs TradeOfferState `json:"theirs"`
+}
+
+type tradeOffer struct {
+	actor *actor
+	items Resources
+
+	locked, confirmed bool
+
+	// Where the actor was when the trade opened
+	// and its hp during the last tick.
+	cell coord.Cell
+	hp   int
+}
+
+func (o tradeOffer) state() TradeOfferState {
+	return TradeOfferState{o.actor.name, o.items.clone(), o.locked, o.confirmed}
+}
+
+// A trade between 2 adjacent actors in the same zone. Trades
+// are only ever changed by the simulation of the zone so
+// the items are exchanged atomically during a single tick.
+type trade struct {
+	offers [2]*tradeOffer
+}
+
+func openTrade(a, b *actor, now stime.Time) {
+	t := &trade{}
+	for i, actor := range []*actor{a, b} {
+		t.offers[i] = &tradeOffer{
+			actor: actor,
+			items: Resources{},
+			cell:  actor.Cell(),
+			hp:    actor.hp,
+		}
+
+		actor.trade = t
+		actor.tradeRequested = nil
+	}
+
+	t.sendTo(a, TS_OPEN, now)
+	t.sendTo(b, TS_OPEN, now)
+}
+
+// Returns the offer of the actor and the other actor.
+func (t *trade) offersOf(a *actor) (mine, theirs *tradeOffer) {
+	if t.offers[0].actor == a {
+		return t.offers[0], t.offers[1]
+	}
+	return t.offers[1], t.offers[0]
+}
+
+func (t *trade) sendTo(a *actor, status TradeStatus, now stime.Time) {
+	mine, theirs := t.offersOf(a)
+	a.queueMsg(TradeMsg{now, status, mine.state(), theirs.state()})
+}
+
+func (t *trade) changed(now stime.Time) {
+	for _, o := range t.offers {
+		t.sendTo(o.actor, TS_OPEN, now)
+	}
+}
+
+// Closes the trade and sends both actors the reason.
+func (t *trade) close(status TradeStatus, now stime.Time) {
+	for _, o := range t.offers {
+		o.actor.trade = nil
+		t.sendTo(o.actor, status, now)
+	}
+}
+
+// Exchanges the items if both actors still have the items
+// they offered. Otherwise the trade is cancelled.
+func (t *trade) complete(now stime.Time) {
+	for _, o := range t.offers {
+		if !o.actor.resources.has(o.items) {
+			t.close(TS_UNAVAILABLE, now)
+			return
+		}
+	}
+
+	a, b := t.offers[0], t.offers[1]
+	a.actor.resources.spend(a.items)
+	b.actor.resources.spend(b.items)
+	a.actor.resources.add(b.items)
+	b.actor.resources.add(a.items)
+
+	t.close(TS_COMPLETED, now)
+	a.actor.sendResources(now)
+	b.actor.sendResources(now)
+}
+
+func isAdjacent(a, b coord.Cell) bool {
+	for _, dir := range []coord.Direction{coord.North, coord.East, coord.South, coord.West} {
+		if a.Neighbor(dir) == b {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *actor) ReadTradeCmd() *tradeCmd {
+	return <-a.readTradeCmd
+}
+
+func (a *actor) rejectTradeCmd(cmd *tradeCmd, reason TradeRejectedReason, now stime.Time) {
+	a.queueMsg(TradeRejectedMsg{now, cmd.TradeRequestType, cmd.name, reason})
+}
+
+func (phase inputPhase) processTradeCmd(a *actor, now stime.Time) {
+	cmd := a.ReadTradeCmd()
+	if cmd == nil {
+		return
+	}
+
+	a.runTradeCmd(cmd, now)
+}
+
+func (a *actor) runTradeCmd(cmd *tradeCmd, now stime.Time) {
+	if cmd.TradeRequestType == TR_REQUEST {
+		a.requestTrade(cmd, now)
+		return
+	}
+
+	t := a.trade
+	if t == nil {
+		if cmd.TradeRequestType == TR_CANCEL && a.tradeRequested != nil {
+			a.tradeRequested = nil
+			return
+		}
+
+		a.rejectTradeCmd(cmd, TRR_NOT_TRADING, now)
+		return
+	}
+
+	mine, theirs := t.offersOf(a)
+
+	switch cmd.TradeRequestType {
+	case TR_OFFER:
+		switch {
+		case mine.locked:
+			a.rejectTradeCmd(cmd, TRR_LOCKED, now)
+			return
+		case !a.resources.has(cmd.items):
+			a.rejectTradeCmd(cmd, TRR_RESOURCES, now)
+			return
+		}
+
+		// Both actors must lock the offers again
+		// so neither can be tricked by a change.
+		mine.items = cmd.items.clone()
+		mine.locked, mine.confirmed = false, false
+		theirs.locked, theirs.confirmed = false, false
+		t.changed(now)
+
+	case TR_LOCK:
+		mine.locked = true
+		t.changed(now)
+
+	case TR_CONFIRM:
+		if !mine.locked || !theirs.locked {
+			a.rejectTradeCmd(cmd, TRR_NOT_LOCKED, now)
+			return
+		}
+
+		mine.confirmed = true
+		if theirs.confirmed {
+			t.complete(now)
+			return
+		}
+
+		t.changed(now)
+
+	case TR_CANCEL:
+		t.close(TS_CANCELLED, now)
+	}
+}
+
+// Opens a trade if the other actor has already requested
+// to trade with the actor. Otherwise the other actor is
+// asked to trade by requesting a trade in return.
+func (a *actor) requestTrade(cmd *tradeCmd, now stime.Time) {
+	to := a.chat.actorNamed(cmd.name)
+	switch {
+	case to == nil, to == a, to.zone != a.zone:
+		a.rejectTradeCmd(cmd, TRR_NO_ACTOR, now)
+		return
+	case a.trade != nil:
+		a.rejectTradeCmd(cmd, TRR_TRADING, now)
+		return
+	case to.trade != nil:
+		a.rejectTradeCmd(cmd, TRR_BUSY, now)
+		return
+	case !isAdjacent(a.Cell(), to.Cell()):
+		a.rejectTradeCmd(cmd, TRR_NOT_ADJACENT, now)
+		return
+	}
+
+	if to.tradeRequested == a {
+		openTrade(to, a, now)
+		return
+	}
+
+	a.tradeRequested = to
+	to.queueMsg(TradeInviteMsg{now, a.name})
+}
+
+// Cancels the actor's trade if either actor has moved,
+// been damaged or disconnected since the last tick.
+func (a *actor) updateTrade(index ActorIndex, now stime.Time) {
+	t := a.trade
+	if t == nil {
+		return
+	}
+
+	mine, theirs := t.offersOf(a)
+	switch {
+	case index[theirs.actor.Id()] != theirs.actor:
+		// The other actor isn't sent the reason
+		// since it's no longer in the simulation.
+		a.trade = nil
+		t.sendTo(a, TS_DISCONNECTED, now)
+
+	case a.pathAction != nil || a.Cell() != mine.cell:
+		t.close(TS_MOVED, now)
+
+	case a.hp < mine.hp:
+		t.close(TS_DAMAGED, now)
+
+	default:
+		mine.hp = a.hp
+	}
+}
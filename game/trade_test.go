@@ -0,0 +1,183 @@
+package game
+
+import (
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/sim/stime"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+// Returns the status of the last trade msg
+// the actor was sent, or -1 if it wasn't.
+func lastTradeStatus(msgs []ActorMsg) TradeStatus {
+	status := TradeStatus(-1)
+	for _, msg := range msgs {
+		if msg, ok := msg.(TradeMsg); ok {
+			status = msg.Status
+		}
+	}
+	return status
+}
+
+func DescribeTrades(c gospec.Context) {
+	c.Specify("an offer request", func() {
+		c.Specify("includes the count of each item", func() {
+			r, err := newTradeRequest(TR_OFFER, 1, "wood 5 stone 2")
+			c.Assume(err, IsNil)
+			c.Expect(r.Items["wood"], Equals, 5)
+			c.Expect(r.Items["stone"], Equals, 2)
+		})
+
+		c.Specify("is invalid if a count is missing", func() {
+			_, err := newTradeRequest(TR_OFFER, 1, "wood 5 stone")
+			c.Expect(err, Not(IsNil))
+		})
+
+		c.Specify("is invalid if a count is negative", func() {
+			_, err := newTradeRequest(TR_OFFER, 1, "wood -5")
+			c.Expect(err, Not(IsNil))
+		})
+	})
+
+	c.Specify("a trade", func() {
+		chat := newChatChannels(nil)
+
+		alice := newPartyTestActor(chat, nil, 1, "Alice", coord.Cell{0, 0})
+		bob := newPartyTestActor(chat, nil, 2, "Bob", coord.Cell{1, 0})
+		carol := newPartyTestActor(chat, nil, 3, "Carol", coord.Cell{5, 0})
+
+		alice.resources = Resources{"wood": 10}
+		bob.resources = Resources{"stone": 10}
+
+		index := ActorIndex{alice.id: alice, bob.id: bob, carol.id: carol}
+
+		run := func(a *actor, t TradeRequestType, name string, items Resources) []ActorMsg {
+			a.runTradeCmd(&tradeCmd{t, 1, name, items}, 1)
+			return a.deliveredMsgs()
+		}
+
+		c.Specify("can't be requested with an actor that isn't adjacent", func() {
+			c.Expect(run(alice, TR_REQUEST, "carol", nil), ContainsExactly, []ActorMsg{
+				TradeRejectedMsg{1, TR_REQUEST, "carol", TRR_NOT_ADJACENT},
+			})
+		})
+
+		c.Specify("is opened when both actors request it", func() {
+			c.Expect(len(run(alice, TR_REQUEST, "bob", nil)), Equals, 0)
+			c.Expect(bob.deliveredMsgs(), ContainsExactly, []ActorMsg{TradeInviteMsg{1, "Alice"}})
+			c.Expect(alice.trade, IsNil)
+
+			c.Expect(lastTradeStatus(run(bob, TR_REQUEST, "alice", nil)), Equals, TS_OPEN)
+			c.Expect(lastTradeStatus(alice.deliveredMsgs()), Equals, TS_OPEN)
+			c.Assume(alice.trade, Not(IsNil))
+			c.Expect(alice.trade == bob.trade, IsTrue)
+
+			c.Specify("and can't be confirmed until both offers are locked", func() {
+				run(alice, TR_LOCK, "", nil)
+				c.Expect(run(alice, TR_CONFIRM, "", nil), ContainsExactly, []ActorMsg{
+					TradeRejectedMsg{1, TR_CONFIRM, "", TRR_NOT_LOCKED},
+				})
+			})
+
+			c.Specify("and can't offer items the actor doesn't have", func() {
+				c.Expect(run(alice, TR_OFFER, "", Resources{"stone": 1}), ContainsExactly, []ActorMsg{
+					TradeRejectedMsg{1, TR_OFFER, "", TRR_RESOURCES},
+				})
+			})
+
+			c.Specify("and exchanges the items when both actors confirm", func() {
+				run(alice, TR_OFFER, "", Resources{"wood": 4})
+				run(bob, TR_OFFER, "", Resources{"stone": 3})
+				run(alice, TR_LOCK, "", nil)
+				run(bob, TR_LOCK, "", nil)
+				run(alice, TR_CONFIRM, "", nil)
+
+				c.Expect(alice.resources["wood"], Equals, 10)
+
+				c.Expect(lastTradeStatus(run(bob, TR_CONFIRM, "", nil)), Equals, TS_COMPLETED)
+				c.Expect(lastTradeStatus(alice.deliveredMsgs()), Equals, TS_COMPLETED)
+
+				c.Expect(alice.resources["wood"], Equals, 6)
+				c.Expect(alice.resources["stone"], Equals, 3)
+				c.Expect(bob.resources["wood"], Equals, 4)
+				c.Expect(bob.resources["stone"], Equals, 7)
+				c.Expect(alice.trade, IsNil)
+				c.Expect(bob.trade, IsNil)
+			})
+
+			c.Specify("and must be locked again after an offer changes", func() {
+				run(alice, TR_LOCK, "", nil)
+				run(bob, TR_OFFER, "", Resources{"stone": 1})
+
+				mine, _ := alice.trade.offersOf(alice)
+				c.Expect(mine.locked, IsFalse)
+
+				c.Specify("unless it's locked", func() {
+					run(bob, TR_LOCK, "", nil)
+					c.Expect(run(bob, TR_OFFER, "", Resources{"stone": 2}), ContainsExactly, []ActorMsg{
+						TradeRejectedMsg{1, TR_OFFER, "", TRR_LOCKED},
+					})
+				})
+			})
+
+			c.Specify("and doesn't exchange items that were spent", func() {
+				run(alice, TR_OFFER, "", Resources{"wood": 4})
+				run(alice, TR_LOCK, "", nil)
+				run(bob, TR_LOCK, "", nil)
+				run(alice, TR_CONFIRM, "", nil)
+
+				alice.resources.spend(Resources{"wood": 8})
+
+				c.Expect(lastTradeStatus(run(bob, TR_CONFIRM, "", nil)), Equals, TS_UNAVAILABLE)
+				c.Expect(bob.resources["wood"], Equals, 0)
+			})
+
+			c.Specify("and is cancelled", func() {
+				alice.deliveredMsgs()
+				bob.deliveredMsgs()
+
+				c.Specify("by either actor", func() {
+					c.Expect(lastTradeStatus(run(bob, TR_CANCEL, "", nil)), Equals, TS_CANCELLED)
+					c.Expect(lastTradeStatus(alice.deliveredMsgs()), Equals, TS_CANCELLED)
+					c.Expect(alice.trade, IsNil)
+				})
+
+				c.Specify("if either actor moves", func() {
+					bob.pathAction = &coord.PathAction{
+						Span: stime.NewSpan(1, 10),
+						Orig: bob.Cell(),
+						Dest: bob.Cell().Neighbor(coord.East),
+					}
+					bob.updateTrade(index, 2)
+					c.Expect(lastTradeStatus(alice.deliveredMsgs()), Equals, TS_MOVED)
+					c.Expect(alice.trade, IsNil)
+				})
+
+				c.Specify("if either actor is damaged", func() {
+					alice.takeDamage(1)
+					alice.updateTrade(index, 2)
+					c.Expect(lastTradeStatus(bob.deliveredMsgs()), Equals, TS_DAMAGED)
+					c.Expect(bob.trade, IsNil)
+				})
+
+				c.Specify("if either actor disconnects", func() {
+					delete(index, bob.id)
+					alice.updateTrade(index, 2)
+					c.Expect(lastTradeStatus(alice.deliveredMsgs()), Equals, TS_DISCONNECTED)
+					c.Expect(alice.trade, IsNil)
+				})
+			})
+		})
+
+		c.Specify("can be requested with a slash command", func() {
+			alice.issueCommand("/trade bob", 1)
+			bob.issueCommand("/trade alice", 1)
+			c.Assume(alice.trade, Not(IsNil))
+
+			bob.issueCommand("/trade offer stone 2", 1)
+			_, theirs := alice.trade.offersOf(alice)
+			c.Expect(theirs.items["stone"], Equals, 2)
+		})
+	})
+}
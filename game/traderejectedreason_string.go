@@ -0,0 +1,32 @@
+// Code generated by "stringer -type=TradeRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[TRR_ERROR-0]
+	_ = x[TRR_NO_ACTOR-1]
+	_ = x[TRR_NOT_ADJACENT-2]
+	_ = x[TRR_TRADING-3]
+	_ = x[TRR_BUSY-4]
+	_ = x[TRR_NOT_TRADING-5]
+	_ = x[TRR_LOCKED-6]
+	_ = x[TRR_NOT_LOCKED-7]
+	_ = x[TRR_RESOURCES-8]
+}
+
+const _TradeRejectedReason_name = "TRR_ERRORTRR_NO_ACTORTRR_NOT_ADJACENTTRR_TRADINGTRR_BUSYTRR_NOT_TRADINGTRR_LOCKEDTRR_NOT_LOCKEDTRR_RESOURCES"
+
+var _TradeRejectedReason_index = [...]uint8{0, 9, 21, 37, 48, 56, 71, 81, 95, 108}
+
+func (i TradeRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TradeRejectedReason_index)-1 {
+		return "TradeRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _TradeRejectedReason_name[_TradeRejectedReason_index[idx]:_TradeRejectedReason_index[idx+1]]
+}
@@ -0,0 +1,30 @@
+// Code generated by "stringer -type=TradeRequestType"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[TR_ERROR-0]
+	_ = x[TR_REQUEST-1]
+	_ = x[TR_OFFER-2]
+	_ = x[TR_LOCK-3]
+	_ = x[TR_CONFIRM-4]
+	_ = x[TR_CANCEL-5]
+	_ = x[TR_SIZE-6]
+}
+
+const _TradeRequestType_name = "TR_ERRORTR_REQUESTTR_OFFERTR_LOCKTR_CONFIRMTR_CANCELTR_SIZE"
+
+var _TradeRequestType_index = [...]uint8{0, 8, 18, 26, 33, 43, 52, 59}
+
+func (i TradeRequestType) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TradeRequestType_index)-1 {
+		return "TradeRequestType(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _TradeRequestType_name[_TradeRequestType_index[idx]:_TradeRequestType_index[idx+1]]
+}
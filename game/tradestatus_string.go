@@ -0,0 +1,30 @@
+// Code generated by "stringer -type=TradeStatus"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[TS_OPEN-0]
+	_ = x[TS_COMPLETED-1]
+	_ = x[TS_CANCELLED-2]
+	_ = x[TS_MOVED-3]
+	_ = x[TS_DAMAGED-4]
+	_ = x[TS_DISCONNECTED-5]
+	_ = x[TS_UNAVAILABLE-6]
+}
+
+const _TradeStatus_name = "TS_OPENTS_COMPLETEDTS_CANCELLEDTS_MOVEDTS_DAMAGEDTS_DISCONNECTEDTS_UNAVAILABLE"
+
+var _TradeStatus_index = [...]uint8{0, 7, 19, 31, 39, 49, 64, 78}
+
+func (i TradeStatus) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_TradeStatus_index)-1 {
+		return "TradeStatus(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _TradeStatus_name[_TradeStatus_index[idx]:_TradeStatus_index[idx+1]]
+}
@@ -0,0 +1,29 @@
+// Code generated by "stringer -type=UseRejectedReason"; DO NOT EDIT.
+
+package game
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[URR_ERROR-0]
+	_ = x[URR_UNKNOWN_SKILL-1]
+	_ = x[URR_COOLDOWN-2]
+	_ = x[URR_MOVING-3]
+	_ = x[URR_NO_NODE-4]
+	_ = x[URR_DEPLETED-5]
+}
+
+const _UseRejectedReason_name = "URR_ERRORURR_UNKNOWN_SKILLURR_COOLDOWNURR_MOVINGURR_NO_NODEURR_DEPLETED"
+
+var _UseRejectedReason_index = [...]uint8{0, 9, 26, 38, 48, 59, 71}
+
+func (i UseRejectedReason) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_UseRejectedReason_index)-1 {
+		return "UseRejectedReason(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _UseRejectedReason_name[_UseRejectedReason_index[idx]:_UseRejectedReason_index[idx+1]]
+}
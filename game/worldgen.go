@@ -0,0 +1,419 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+)
+
+// Configures the worlds created by the procedural
+// world generator. Generating a world with the same
+// config will always produce the same world.
+type WorldGenConfig struct {
+	Seed int64
+
+	// In cells
+	Width, Height int
+
+	// Fractions of the world that are covered by
+	// water and by rock. Between 0 and 1.
+	Water, Rock float64
+
+	// Number of ruined wall segments in the world
+	Ruins int
+
+	// Number of resource nodes. Trees grow on grass
+	// and dirt and ore is found in rock.
+	ResourceNodes int
+
+	// Number of cells actors can be spawned at
+	Spawns int
+
+	// Npcs that will be placed at random walkable
+	// cells. The cell of each spawn is ignored.
+	Npcs []NpcSpawn
+
+	// Generate the terrain one chunk at a time when it's
+	// needed by the simulation. Required for worlds that
+	// are too large to be held in memory. The fractions of
+	// water and rock are only approximate.
+	Chunked bool
+
+	// The width and height in cells of the zones
+	// the world is divided into. See WorldDef.
+	ZoneSize int
+}
+
+// The configuration used when none is provided.
+var DefaultWorldGenConfig = WorldGenConfig{
+	Width:  128,
+	Height: 128,
+
+	Water: 0.2,
+	Rock:  0.15,
+
+	Ruins:         12,
+	ResourceNodes: 40,
+	Spawns:        4,
+}
+
+// Distance in cells between the points of the noise
+// that the terrain is interpolated from.
+const worldGenNoiseScale = 8
+
+// Limits the time spent searching large worlds for a cell.
+const worldGenMaxPlaceAttempts = 1 << 16
+
+// Returns a generator that creates a new procedural world
+// every epoch. The seed of each world is offset by its epoch
+// so a shard restarted in the same epoch loads the same world.
+func ProceduralWorld(config WorldGenConfig) WorldGenerator {
+	return func(epoch int) (WorldDef, error) {
+		c := config
+		c.Seed += int64(epoch)
+		return GenerateWorld(c)
+	}
+}
+
+// Generates the world described by the config.
+func GenerateWorld(c WorldGenConfig) (WorldDef, error) {
+	if c.Width <= 0 || c.Height <= 0 {
+		return WorldDef{}, errors.New("world must be at least 1 cell wide and high")
+	}
+
+	g := worldGen{
+		WorldGenConfig: c,
+		rand:           rand.New(rand.NewSource(c.Seed)),
+
+		bounds: coord.Bounds{
+			coord.Cell{1, -1},
+			coord.Cell{c.Width, -c.Height},
+		},
+
+		occupied: make(map[coord.Cell]bool),
+	}
+
+	def := WorldDef{
+		Bounds:   g.bounds,
+		ZoneSize: c.ZoneSize,
+	}
+
+	if c.Chunked {
+		g.noise = &NoiseTerrain{c.Seed, c.Water, c.Rock}
+		def.TerrainSource = g.noise
+	} else {
+		g.generateTerrain()
+		def.Terrain = g.terrainString()
+	}
+
+	def.Walls = g.generateRuins()
+	def.ResourceNodes = g.generateResourceNodes()
+
+	def.Spawns = g.placeAll(c.Spawns, func(tt rpg2d.TerrainType) bool {
+		return tt == rpg2d.TT_GRASS
+	})
+
+	if len(def.Spawns) == 0 {
+		return WorldDef{}, errors.New("world has no grass to spawn actors on")
+	}
+
+	npcCells := g.placeAll(len(c.Npcs), isWalkable)
+	for i, cell := range npcCells {
+		npc := c.Npcs[i]
+		npc.Cell = cell
+		def.Npcs = append(def.Npcs, npc)
+	}
+
+	return def, nil
+}
+
+// The state of a world while it is being generated.
+type worldGen struct {
+	WorldGenConfig
+	rand *rand.Rand
+
+	bounds coord.Bounds
+
+	// Indexed by [row][column] with row 0 as the top of the world
+	terrain [][]rpg2d.TerrainType
+
+	// Used instead of the terrain if the world is chunked
+	noise *NoiseTerrain
+
+	// Cells that have a wall, resource node, spawn or npc
+	occupied map[coord.Cell]bool
+}
+
+func isWalkable(tt rpg2d.TerrainType) bool {
+	return terrainTable[tt].walkable
+}
+
+// Interpolates a grid of random values to create smooth
+// regions of terrain. The lowest values become water and
+// the highest become rock.
+func (g *worldGen) generateTerrain() {
+	cols := g.Width/worldGenNoiseScale + 2
+	rows := g.Height/worldGenNoiseScale + 2
+
+	noise := make([][]float64, rows)
+	for y := range noise {
+		noise[y] = make([]float64, cols)
+		for x := range noise[y] {
+			noise[y][x] = g.rand.Float64()
+		}
+	}
+
+	lerp := func(a, b, t float64) float64 { return a + (b-a)*t }
+
+	values := make([]float64, 0, g.Width*g.Height)
+	for y := 0; y < g.Height; y++ {
+		for x := 0; x < g.Width; x++ {
+			nx, ny := x/worldGenNoiseScale, y/worldGenNoiseScale
+			tx := float64(x%worldGenNoiseScale) / worldGenNoiseScale
+			ty := float64(y%worldGenNoiseScale) / worldGenNoiseScale
+
+			values = append(values, lerp(
+				lerp(noise[ny][nx], noise[ny][nx+1], tx),
+				lerp(noise[ny+1][nx], noise[ny+1][nx+1], tx),
+				ty,
+			))
+		}
+	}
+
+	// Thresholds are chosen from the sorted values so
+	// the fractions of the world covered by water and
+	// rock match the config.
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	threshold := func(fraction float64) float64 {
+		i := int(fraction * float64(len(sorted)))
+		if i <= 0 {
+			return -1
+		}
+		if i >= len(sorted) {
+			return 2
+		}
+		return sorted[i]
+	}
+
+	water := threshold(g.Water)
+	rock := threshold(1 - g.Rock)
+
+	// Noise values just above the water threshold become
+	// dirt shores. The dirt doesn't always ring the water
+	// as the noise can rise past it between two cells.
+	shore := water
+	if g.Water > 0 {
+		shore = threshold(g.Water + 0.05)
+	}
+
+	g.terrain = make([][]rpg2d.TerrainType, g.Height)
+	for y := range g.terrain {
+		g.terrain[y] = make([]rpg2d.TerrainType, g.Width)
+		for x := range g.terrain[y] {
+			g.terrain[y][x] = terrainTypeOf(values[y*g.Width+x], water, shore, rock)
+		}
+	}
+}
+
+// Returns the type of terrain for a noise value.
+func terrainTypeOf(v, water, shore, rock float64) rpg2d.TerrainType {
+	switch {
+	case v < water:
+		return TT_WATER
+	case v < shore:
+		return rpg2d.TT_DIRT
+	case v >= rock:
+		return rpg2d.TT_ROCK
+	default:
+		return rpg2d.TT_GRASS
+	}
+}
+
+// Formatted like startingTerrain.
+func (g *worldGen) terrainString() string {
+	var b strings.Builder
+	b.Grow((g.Width + 1) * (g.Height + 1))
+
+	b.WriteByte('\n')
+	for _, row := range g.terrain {
+		for _, tt := range row {
+			b.WriteByte(byte(tt))
+		}
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+func (g *worldGen) typeAt(c coord.Cell) rpg2d.TerrainType {
+	if g.noise != nil {
+		return g.noise.typeAt(c)
+	}
+	return g.terrain[-c.Y-1][c.X-1]
+}
+
+func (g *worldGen) randomCell() coord.Cell {
+	return coord.Cell{
+		g.rand.Intn(g.Width) + 1,
+		-(g.rand.Intn(g.Height) + 1),
+	}
+}
+
+// Returns a random unoccupied cell with terrain that
+// is accepted. Returns false if none could be found.
+func (g *worldGen) place(accept func(rpg2d.TerrainType) bool) (coord.Cell, bool) {
+	// Give up eventually if the world has little of the terrain
+	maxAttempts := g.Width * g.Height
+	if maxAttempts > worldGenMaxPlaceAttempts {
+		maxAttempts = worldGenMaxPlaceAttempts
+	}
+
+	for attempts := 0; attempts < maxAttempts; attempts++ {
+		c := g.randomCell()
+		if !g.occupied[c] && accept(g.typeAt(c)) {
+			g.occupied[c] = true
+			return c, true
+		}
+	}
+
+	return coord.Cell{}, false
+}
+
+// Places up to n cells.
+func (g *worldGen) placeAll(n int, accept func(rpg2d.TerrainType) bool) []coord.Cell {
+	cells := make([]coord.Cell, 0, n)
+	for i := 0; i < n; i++ {
+		c, ok := g.place(accept)
+		if !ok {
+			break
+		}
+		cells = append(cells, c)
+	}
+	return cells
+}
+
+// Ruins are straight segments of wall built on walkable
+// terrain. A segment stops early if it reaches terrain
+// that isn't walkable or the edge of the world.
+func (g *worldGen) generateRuins() []coord.Cell {
+	var walls []coord.Cell
+
+	for i := 0; i < g.Ruins; i++ {
+		start, ok := g.place(isWalkable)
+		if !ok {
+			break
+		}
+		walls = append(walls, start)
+
+		dir := []coord.Direction{coord.North, coord.East, coord.South, coord.West}[g.rand.Intn(4)]
+		length := 3 + g.rand.Intn(6)
+
+		c := start
+		for j := 1; j < length; j++ {
+			c = c.Neighbor(dir)
+			if !g.bounds.Contains(c) || g.occupied[c] || !isWalkable(g.typeAt(c)) {
+				break
+			}
+
+			g.occupied[c] = true
+			walls = append(walls, c)
+		}
+	}
+
+	return walls
+}
+
+func (g *worldGen) generateResourceNodes() []ResourceNodeSpawn {
+	nodes := make([]ResourceNodeSpawn, 0, g.ResourceNodes)
+
+	for i := 0; i < g.ResourceNodes; i++ {
+		c, ok := g.place(isWalkable)
+		if !ok {
+			break
+		}
+
+		kind := "tree"
+		if g.typeAt(c) == rpg2d.TT_ROCK {
+			kind = "ore"
+		}
+
+		nodes = append(nodes, ResourceNodeSpawn{kind, c})
+	}
+
+	return nodes
+}
+
+// A procedural source of terrain for worlds that are too
+// large to be generated all at once. The terrain of a cell
+// only depends on the seed and the cell so chunks can be
+// generated in any order and regenerated after unloading.
+type NoiseTerrain struct {
+	Seed int64
+
+	// Roughly the fractions of the world that are
+	// covered by water and by rock. Between 0 and 1.
+	Water, Rock float64
+}
+
+// Returns a pseudo-random value between 0 and 1 for
+// a point of the noise lattice.
+func (n NoiseTerrain) latticeValue(x, y int) float64 {
+	h := uint64(n.Seed)*0x9E3779B97F4A7C15 ^ uint64(x)*0xBF58476D1CE4E5B9 ^ uint64(y)*0x94D049BB133111EB
+
+	// Mix the bits so neighboring points are unrelated
+	h ^= h >> 30
+	h *= 0xBF58476D1CE4E5B9
+	h ^= h >> 27
+	h *= 0x94D049BB133111EB
+	h ^= h >> 31
+
+	return float64(h>>11) / (1 << 53)
+}
+
+// Rounds towards negative infinity.
+func floorDiv(a, b int) int {
+	if a < 0 {
+		return -((-a + b - 1) / b)
+	}
+	return a / b
+}
+
+func (n NoiseTerrain) valueAt(c coord.Cell) float64 {
+	nx, ny := floorDiv(c.X, worldGenNoiseScale), floorDiv(-c.Y, worldGenNoiseScale)
+	tx := float64(c.X-nx*worldGenNoiseScale) / worldGenNoiseScale
+	ty := float64(-c.Y-ny*worldGenNoiseScale) / worldGenNoiseScale
+
+	lerp := func(a, b, t float64) float64 { return a + (b-a)*t }
+
+	return lerp(
+		lerp(n.latticeValue(nx, ny), n.latticeValue(nx+1, ny), tx),
+		lerp(n.latticeValue(nx, ny+1), n.latticeValue(nx+1, ny+1), tx),
+		ty,
+	)
+}
+
+func (n NoiseTerrain) typeAt(c coord.Cell) rpg2d.TerrainType {
+	shore := n.Water
+	if n.Water > 0 {
+		shore += 0.05
+	}
+
+	return terrainTypeOf(n.valueAt(c), n.Water, shore, 1-n.Rock)
+}
+
+func (n NoiseTerrain) TerrainOf(b coord.Bounds) [][]rpg2d.TerrainType {
+	types := make([][]rpg2d.TerrainType, b.Height())
+	for y := range types {
+		types[y] = make([]rpg2d.TerrainType, b.Width())
+		for x := range types[y] {
+			types[y][x] = n.typeAt(b.TopL.Add(x, -y))
+		}
+	}
+	return types
+}
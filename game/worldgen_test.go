@@ -0,0 +1,201 @@
+package game
+
+import (
+	"reflect"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+func DescribeWorldGeneration(c gospec.Context) {
+	config := WorldGenConfig{
+		Seed:   1,
+		Width:  24,
+		Height: 12,
+
+		Water: 0.2,
+		Rock:  0.15,
+
+		Ruins:         2,
+		ResourceNodes: 3,
+		Spawns:        2,
+
+		Npcs: []NpcSpawn{{Name: "npc", Behavior: NpcGuard{}}},
+	}
+
+	def, err := GenerateWorld(config)
+	c.Assume(err, IsNil)
+
+	c.Specify("a generated world", func() {
+		c.Specify("is the same every time it is generated from a seed", func() {
+			again, err := GenerateWorld(config)
+			c.Assume(err, IsNil)
+			c.Expect(reflect.DeepEqual(def, again), IsTrue)
+
+			// Snapshot of the terrain generated with the
+			// seed. Changing the generator will change the
+			// worlds that are generated from existing seeds.
+			c.Expect(def.Terrain, Equals, `
+GRRRRRRRRRRRRRRRRGGGGGGG
+GGRRRRRRRRRRRRRGGGGGGGGG
+GGRRRRRRRRRRGGGGGGGGGGGG
+GGGGGGGGGGGGGGGGGGGGGGGG
+GGGGGGGGGGGGGGGGGGGGGGGG
+RGGGGGGGGGGGGGGGGGGGGGGG
+RGGGGGGGGGGGGGGGGGGGGGDD
+RGGGGGGGWWWWDDDDDDDWWWWW
+RGGGGGGWWWWWWWWWWWWWWWWW
+RGGGGGGGWWWWWWWWWWWWWWWW
+GGGGGGGGGGGGDDWWWWWWWWWW
+GGGGGGGGGGGGGGGDWWWWWDDD
+`)
+		})
+
+		c.Specify("is different when generated from another seed", func() {
+			other := config
+			other.Seed = 2
+
+			otherDef, err := GenerateWorld(other)
+			c.Assume(err, IsNil)
+			c.Expect(otherDef.Terrain, Not(Equals), def.Terrain)
+		})
+
+		c.Specify("has the configured size", func() {
+			c.Expect(def.Bounds, Equals, coord.Bounds{
+				coord.Cell{1, -1},
+				coord.Cell{24, -12},
+			})
+		})
+
+		g := worldGen{
+			WorldGenConfig: config,
+			terrain:        make([][]rpg2d.TerrainType, 0, config.Height),
+		}
+
+		row := []rpg2d.TerrainType{}
+		for _, r := range def.Terrain[1:] {
+			if r == '\n' {
+				g.terrain = append(g.terrain, row)
+				row = []rpg2d.TerrainType{}
+				continue
+			}
+			row = append(row, rpg2d.TerrainType(r))
+		}
+
+		c.Assume(len(g.terrain), Equals, config.Height)
+
+		c.Specify("has walls, resource nodes, spawns and npcs", func() {
+			c.Expect(len(def.Walls) >= config.Ruins, IsTrue)
+			c.Expect(len(def.ResourceNodes), Equals, config.ResourceNodes)
+			c.Expect(len(def.Spawns), Equals, config.Spawns)
+			c.Expect(len(def.Npcs), Equals, len(config.Npcs))
+		})
+
+		c.Specify("only places things on walkable cells", func() {
+			cells := append([]coord.Cell(nil), def.Walls...)
+			cells = append(cells, def.Spawns...)
+			for _, n := range def.ResourceNodes {
+				cells = append(cells, n.Cell)
+			}
+			for _, n := range def.Npcs {
+				cells = append(cells, n.Cell)
+			}
+
+			occupied := make(map[coord.Cell]bool)
+			for _, cell := range cells {
+				c.Expect(def.Bounds.Contains(cell), IsTrue)
+				c.Expect(isWalkable(g.typeAt(cell)), IsTrue)
+				c.Expect(occupied[cell], Not(IsTrue))
+				occupied[cell] = true
+			}
+		})
+
+		c.Specify("spawns actors on grass", func() {
+			for _, cell := range def.Spawns {
+				c.Expect(g.typeAt(cell), Equals, rpg2d.TT_GRASS)
+			}
+		})
+
+		c.Specify("has ore in rock", func() {
+			for _, n := range def.ResourceNodes {
+				if n.Kind == "ore" {
+					c.Expect(g.typeAt(n.Cell), Equals, rpg2d.TT_ROCK)
+				}
+			}
+		})
+	})
+
+	c.Specify("a procedural world generator", func() {
+		newWorld := ProceduralWorld(config)
+
+		first, err := newWorld(0)
+		c.Assume(err, IsNil)
+
+		c.Specify("generates the same world for an epoch", func() {
+			again, err := newWorld(0)
+			c.Assume(err, IsNil)
+			c.Expect(again.Terrain, Equals, first.Terrain)
+		})
+
+		c.Specify("generates a new world each epoch", func() {
+			next, err := newWorld(1)
+			c.Assume(err, IsNil)
+			c.Expect(next.Terrain, Not(Equals), first.Terrain)
+		})
+	})
+
+	c.Specify("an actor connected to a world with spawns", func() {
+		spawns := []coord.Cell{{5, -5}, {6, -6}}
+		sim := NewSimulation(NewActorIndexLocker(make(ActorIndex)), idleSimulation{}, nil, nil, spawns)
+
+		a := &actor{}
+		a.id = 1
+		a.hp, a.hpMax = 10, 10
+
+		sim.ConnectActor(a)
+		defer sim.RemoveActor(a)
+
+		c.Specify("is placed at a spawn", func() {
+			c.Expect(a.Cell(), Equals, coord.Cell{6, -6})
+		})
+
+		c.Specify("is respawned at its spawn", func() {
+			a.actorEntity.cell = coord.Cell{1, -1}
+			died := a.takeDamage(10)
+			c.Assume(died, IsTrue)
+			c.Expect(a.Cell(), Equals, coord.Cell{6, -6})
+		})
+	})
+
+	c.Specify("a chunked world", func() {
+		chunked := config
+		chunked.Chunked = true
+		chunked.Width, chunked.Height = 20000, 20000
+
+		def, err := GenerateWorld(chunked)
+		c.Assume(err, IsNil)
+
+		c.Specify("has its terrain generated by a source", func() {
+			c.Expect(def.Terrain, Equals, "")
+			c.Expect(def.TerrainSource, Not(IsNil))
+		})
+
+		c.Specify("only places things on walkable cells", func() {
+			noise := def.TerrainSource.(*NoiseTerrain)
+			for _, cell := range def.Spawns {
+				c.Expect(noise.typeAt(cell), Equals, rpg2d.TT_GRASS)
+			}
+			for _, n := range def.ResourceNodes {
+				c.Expect(isWalkable(noise.typeAt(n.Cell)), IsTrue)
+			}
+		})
+	})
+
+	c.Specify("a world without a size can't be generated", func() {
+		_, err := GenerateWorld(WorldGenConfig{})
+		c.Expect(err, Not(IsNil))
+	})
+}
@@ -0,0 +1,803 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+	"github.com/ghthor/filu/rpg2d/quad"
+	"github.com/ghthor/filu/sim/stime"
+)
+
+// Distance in cells from the edge of a zone's bounds that
+// the zone's entities are visible to neighbouring zones.
+// Matches the distance an actor can see.
+const zoneBorderSize = 26
+
+// A border snapshot older than this is from a zone that
+// has stopped ticking and its entities are ignored.
+const zoneBorderTimeout = time.Second
+
+// A zone that hasn't begun a tick for this long has
+// no entities in its quad tree and isn't ticking.
+const zoneIdleTimeout = 100 * time.Millisecond
+
+// How long moving an actor near a cell waits for
+// every zone to record a snapshot of its entities.
+const zoneTeleportTimeout = time.Second
+
+// A simulation of part of a world. Actors are handed off
+// between zones when they walk over the boundary of a zone.
+// A zone in another process would proxy the IO of the
+// actors it's handed. Entities of neighbouring zones are
+// visible to each other but don't collide.
+type Zone interface {
+	// The cells of the world the zone is responsible for
+	Bounds() coord.Bounds
+
+	// Begins simulating an actor that has connected.
+	ConnectActor(*actor)
+
+	// Stops simulating an actor that has disconnected.
+	RemoveActor(*actor)
+
+	// Begins simulating an actor that has been handed off
+	// by another zone without interrupting its connection.
+	AcceptHandoff(*actor)
+
+	// Stops simulating an actor that is being handed
+	// off to another zone without interrupting its
+	// connection.
+	ReleaseHandoff(*actor)
+
+	// Returns the states of the zone's entities near its
+	// boundary that overlap the bounds as of its last tick.
+	BorderEntities(coord.Bounds) entity.StateSlice
+
+	// Halts the zone and returns the actors it was simulating.
+	Halt() ([]*actor, error)
+
+	// Returns the actors the zone is simulating.
+	Actors() []*actor
+
+	// Returns the states of every entity in the zone as
+	// of its next tick. The state is sent on the channel.
+	Snapshot() <-chan rpg2d.WorldState
+
+	// Returns the stats of the zone's last complete tick.
+	Stats() ZoneStats
+}
+
+// The entities near a zone's boundary. Is rebuilt
+// during the update phase of each tick.
+type zoneBorder struct {
+	mu sync.RWMutex
+
+	// The tick the next snapshot is being built for
+	building     stime.Time
+	nextEntities entity.StateSlice
+
+	entities    entity.StateSlice
+	publishedAt time.Time
+}
+
+// Publishes the snapshot that was being built when
+// the update phase of a new tick has begun.
+func (b *zoneBorder) tick(now stime.Time) {
+	if b.building == now {
+		return
+	}
+
+	b.entities = b.nextEntities
+	b.publishedAt = time.Now()
+
+	b.building = now
+	b.nextEntities = make(entity.StateSlice, 0, len(b.entities))
+}
+
+// The states of every entity in a zone. Is only built
+// during the ticks a snapshot has been requested for.
+type zoneSnapshot struct {
+	mu sync.Mutex
+
+	// The tick the state is being built for
+	building stime.Time
+	state    *rpg2d.WorldState
+
+	// When the tick being built for began
+	tickedAt time.Time
+
+	// Waiting for the state being built
+	sending []chan<- rpg2d.WorldState
+
+	// Waiting for the next tick to begin
+	requested []chan<- rpg2d.WorldState
+}
+
+// Sends the state that was being built when a new tick
+// has begun and records the entity if a snapshot has
+// been requested.
+func (s *zoneSnapshot) record(e entity.Entity, bounds coord.Bounds, now stime.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.building != now {
+		for _, ch := range s.sending {
+			ch <- *s.state
+		}
+
+		s.building = now
+		s.tickedAt = time.Now()
+		s.sending, s.requested = s.requested, nil
+		s.state = nil
+
+		if len(s.sending) > 0 {
+			s.state = &rpg2d.WorldState{Time: now, Bounds: bounds}
+		}
+	}
+
+	if s.state == nil || e == nil {
+		return
+	}
+
+	if _, removed := e.(entity.Removed); !removed {
+		s.state.Entities = append(s.state.Entities, e.ToState())
+	}
+}
+
+// A zone that isn't ticking has an empty quad tree
+// and is sent an empty state instead of waiting.
+func (s *zoneSnapshot) request(bounds coord.Bounds) <-chan rpg2d.WorldState {
+	ch := make(chan rpg2d.WorldState, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.tickedAt) > zoneIdleTimeout {
+		ch <- rpg2d.WorldState{Time: s.building, Bounds: bounds}
+		return ch
+	}
+
+	s.requested = append(s.requested, ch)
+	return ch
+}
+
+// A zone simulated in this process.
+type localZone struct {
+	bounds coord.Bounds
+
+	// Entities outside of these bounds are near the border
+	inner coord.Bounds
+
+	sim simulation
+
+	// Zones with bounds within the border size of this zone
+	neighbors []Zone
+
+	// Called when an actor has moved outside of the
+	// zone's bounds. Is called on its own go routine.
+	onCrossed func(a *actor, from Zone, cell coord.Cell)
+
+	// Moves an actor to the unblocked cell within the
+	// radius closest to the cell. Returns false if the
+	// actor couldn't be moved.
+	onTeleport func(a *actor, cell coord.Cell, radius int) bool
+
+	mu         sync.Mutex
+	handingOff map[*actor]bool
+
+	border   zoneBorder
+	snapshot zoneSnapshot
+	metrics  tickMetrics
+}
+
+func (z *localZone) Bounds() coord.Bounds { return z.bounds }
+
+func (z *localZone) ConnectActor(a *actor) {
+	a.zone = z
+	z.sim.ConnectActor(a)
+}
+
+func (z *localZone) RemoveActor(a *actor) {
+	z.sim.RemoveActor(a)
+	z.finishedHandoff(a)
+}
+
+// The actor's IO isn't started because it's
+// still running from when the actor connected.
+func (z *localZone) AcceptHandoff(a *actor) {
+	a.zone = z
+
+	actorIndex := z.sim.ActorIndexLocker.Lock()
+	actorIndex[a.Id()] = a
+	z.sim.ActorIndexLocker.Unlock(actorIndex)
+
+	z.sim.RunningSimulation.ConnectActor(a)
+}
+
+func (z *localZone) ReleaseHandoff(a *actor) {
+	z.sim.RunningSimulation.RemoveActor(a)
+
+	actorIndex := z.sim.ActorIndexLocker.Lock()
+	delete(actorIndex, a.Id())
+	z.sim.ActorIndexLocker.Unlock(actorIndex)
+
+	z.finishedHandoff(a)
+}
+
+func (z *localZone) finishedHandoff(a *actor) {
+	z.mu.Lock()
+	delete(z.handingOff, a)
+	z.mu.Unlock()
+}
+
+func (z *localZone) BorderEntities(bounds coord.Bounds) entity.StateSlice {
+	z.border.mu.RLock()
+	defer z.border.mu.RUnlock()
+
+	if time.Since(z.border.publishedAt) > zoneBorderTimeout {
+		return nil
+	}
+
+	var entities entity.StateSlice
+	for _, e := range z.border.entities {
+		if e.Bounds().Overlaps(bounds) {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (z *localZone) Halt() ([]*actor, error) {
+	_, err := z.sim.RunningSimulation.Halt()
+	if err != nil {
+		return nil, err
+	}
+
+	actorIndex := z.sim.ActorIndexLocker.Lock()
+	actors := make([]*actor, 0, len(actorIndex))
+	for id, a := range actorIndex {
+		actors = append(actors, a)
+		delete(actorIndex, id)
+	}
+	z.sim.ActorIndexLocker.Unlock(actorIndex)
+
+	return actors, nil
+}
+
+func (z *localZone) Actors() []*actor {
+	actorIndex := z.sim.ActorIndexLocker.RLock()
+	defer z.sim.ActorIndexLocker.RUnlock()
+
+	actors := make([]*actor, 0, len(actorIndex))
+	for _, a := range actorIndex {
+		actors = append(actors, a)
+	}
+	return actors
+}
+
+func (z *localZone) Snapshot() <-chan rpg2d.WorldState {
+	return z.snapshot.request(z.bounds)
+}
+
+func (z *localZone) Stats() ZoneStats {
+	s := z.metrics.stats()
+	s.Bounds = z.bounds
+	return s
+}
+
+// Called by the update phase for every entity after it has been updated.
+func (z *localZone) recordSnapshot(e entity.Entity, now stime.Time) {
+	if z == nil {
+		return
+	}
+
+	z.snapshot.record(e, z.bounds, now)
+}
+
+// Returns the entities of neighbouring zones
+// that overlap the bounds.
+func (z *localZone) neighborEntities(bounds coord.Bounds) entity.StateSlice {
+	var entities entity.StateSlice
+	for _, n := range z.neighbors {
+		if n.Bounds().Overlaps(bounds) || nearBounds(n.Bounds(), bounds) {
+			entities = append(entities, n.BorderEntities(bounds)...)
+		}
+	}
+	return entities
+}
+
+// Returns true if the cell is outside of the zone and is
+// blocked by the entities near a neighbouring zone's border.
+// The neighbour's entities aren't in the zone's quad tree so
+// its narrow phase can't stop an actor moving into the cell.
+func (z *localZone) blocksCrossing(c coord.Cell, self entity.Id) bool {
+	if z == nil || z.bounds.Contains(c) {
+		return false
+	}
+
+	entities := z.neighborEntities(coord.Bounds{c, c})
+	return blockedCells(rpg2d.WorldState{Entities: entities}, self)[c]
+}
+
+// Moves the actor to the unblocked cell within the radius
+// closest to the cell. The actor is moved on its own go
+// routine so it can be called during any phase. Moved is
+// called once it's done. Returns false if the actor isn't
+// being simulated by a zone.
+func (z *localZone) teleportNear(a *actor, cell coord.Cell, radius int, moved func(bool)) bool {
+	if z == nil || z.onTeleport == nil {
+		return false
+	}
+
+	go func() {
+		moved(z.onTeleport(a, cell, radius))
+	}()
+	return true
+}
+
+// Returns true if the bounds are within the border size of each other.
+func nearBounds(a, b coord.Bounds) bool {
+	return coord.Bounds{
+		a.TopL.Add(-zoneBorderSize, zoneBorderSize),
+		a.BotR.Add(zoneBorderSize, -zoneBorderSize),
+	}.Overlaps(b)
+}
+
+// Called by the update phase for every entity after it has
+// been updated. Records the entities near the zone's border
+// and hands off actors that have left the zone.
+func (z *localZone) observe(e entity.Entity, index ActorIndex, now stime.Time) {
+	if z == nil {
+		return
+	}
+
+	z.border.mu.Lock()
+	z.border.tick(now)
+	if e != nil && !z.inner.Contains(e.Cell()) {
+		z.border.nextEntities = append(z.border.nextEntities, e.ToState())
+	}
+	z.border.mu.Unlock()
+
+	ae, isActor := e.(actorEntity)
+	if !isActor || z.bounds.Contains(ae.cell) || ae.pathAction != nil {
+		return
+	}
+
+	a := index[ae.ActorId()]
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.handingOff[a] {
+		return
+	}
+	z.handingOff[a] = true
+	a.logger.Debug("actor crossed the zone's border", "tick", now, "cell", ae.cell)
+
+	// The actor must be removed from the simulation
+	// outside of the update phase.
+	go z.onCrossed(a, z, ae.cell)
+}
+
+// Divides a world into zones and routes actors to
+// the zone responsible for the cell they're in.
+type zoneRouter struct {
+	mu     sync.Mutex
+	zones  []Zone
+	actors map[*actor]Zone
+	halted bool
+
+	// Actors are only teleported onto walkable
+	// cells that are inside of the world.
+	bounds  coord.Bounds
+	terrain *terrainIndex
+
+	// Cells actors controlled by players are spawned at
+	spawns []coord.Cell
+}
+
+// The state shared by every zone of a world.
+type zoneWorld struct {
+	def     WorldDef
+	maxSize int
+
+	// Contains every entity in the world
+	quadTree quad.Quad
+
+	terrainMap rpg2d.TerrainMap
+	terrain    *terrainIndex
+	edits      *worldEdits
+	nodes      resourceNodeIndex
+	legend     *legendRecorder
+	chat       *chatChannels
+	parties    *partyTable
+	nextId     func() entity.Id
+}
+
+// Returns the bounds of each zone. The bounds of the
+// world are divided into squares of the zone size. If
+// the size is 0 the world is a single zone.
+func zoneBoundsOf(world coord.Bounds, size int) []coord.Bounds {
+	if size <= 0 {
+		return []coord.Bounds{world}
+	}
+
+	var zones []coord.Bounds
+	for y := world.TopL.Y; y >= world.BotR.Y; y -= size {
+		for x := world.TopL.X; x <= world.BotR.X; x += size {
+			topL := coord.Cell{x, y}
+			botR := topL.Add(size-1, -(size - 1))
+
+			if botR.X > world.BotR.X {
+				botR.X = world.BotR.X
+			}
+			if botR.Y < world.BotR.Y {
+				botR.Y = world.BotR.Y
+			}
+
+			zones = append(zones, coord.Bounds{topL, botR})
+		}
+	}
+	return zones
+}
+
+// Begins simulating every zone of the world. Each zone's
+// quad tree is given the entities within its bounds.
+func newZoneRouter(w zoneWorld) (*zoneRouter, error) {
+	bounds := zoneBoundsOf(w.def.Bounds, w.def.ZoneSize)
+	zones := make([]*localZone, 0, len(bounds))
+
+	for _, b := range bounds {
+		// Each zone's quad tree covers the entire world so an
+		// actor that has left the zone's bounds remains in
+		// the quad tree until it has been handed off.
+		quadTree := w.quadTree
+		if len(bounds) > 1 {
+			var err error
+			quadTree, err = quad.New(w.def.Bounds, w.maxSize, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, e := range w.quadTree.QueryBounds(b) {
+				if b.Contains(e.Cell()) {
+					quadTree = quadTree.Insert(e)
+				}
+			}
+		}
+
+		z, err := w.beginZone(b, quadTree, len(bounds) > 1)
+		if err != nil {
+			return nil, err
+		}
+
+		zones = append(zones, z)
+	}
+
+	return newZoneRouterOf(zones, w.def.Bounds, w.terrain, w.def.Spawns), nil
+}
+
+// Routes actors between the zones and makes the entities
+// near each zone's border visible to its neighbours.
+func newZoneRouterOf(zones []*localZone, bounds coord.Bounds, terrain *terrainIndex, spawns []coord.Cell) *zoneRouter {
+	r := &zoneRouter{
+		zones:   make([]Zone, 0, len(zones)),
+		actors:  make(map[*actor]Zone),
+		bounds:  bounds,
+		terrain: terrain,
+		spawns:  spawns,
+	}
+
+	for _, z := range zones {
+		z.onCrossed = r.handOff
+		z.onTeleport = r.teleportNear
+		r.zones = append(r.zones, z)
+	}
+
+	for _, z := range zones {
+		for _, n := range r.zones {
+			if n != Zone(z) && nearBounds(z.bounds, n.Bounds()) {
+				z.neighbors = append(z.neighbors, n)
+			}
+		}
+	}
+
+	return r
+}
+
+func newLocalZone(bounds coord.Bounds, sim simulation) *localZone {
+	return &localZone{
+		bounds: bounds,
+		inner: coord.Bounds{
+			bounds.TopL.Add(zoneBorderSize, -zoneBorderSize),
+			bounds.BotR.Add(-zoneBorderSize, zoneBorderSize),
+		},
+
+		sim:        sim,
+		handingOff: make(map[*actor]bool),
+	}
+}
+
+// A zone that has no neighbours isn't observed by
+// its update phase because it has no border to
+// record and actors can't be handed off.
+func (w zoneWorld) beginZone(bounds coord.Bounds, quadTree quad.Quad, hasNeighbors bool) (*localZone, error) {
+	actorIndex := NewActorIndexLocker(make(ActorIndex))
+
+	z := newLocalZone(bounds, simulation{
+		ActorIndexLocker: actorIndex,
+		edits:            w.edits,
+		legend:           w.legend,
+		chat:             w.chat,
+		parties:          w.parties,
+	})
+
+	updatePhase := updatePhaseLocker{actorIndex, nil, z, &z.metrics}
+	if hasNeighbors {
+		updatePhase.zone = z
+	}
+
+	simDef := rpg2d.SimulationDef{
+		FPS: 40,
+
+		// Initial World State
+		Now:        stime.Time(0),
+		QuadTree:   quadTree,
+		TerrainMap: w.terrainMap,
+
+		UpdatePhaseHandler: updatePhase,
+		InputPhaseHandler:  inputPhaseLocker{actorIndex, w.nextId, w.terrain, w.edits, w.nodes, &z.metrics},
+		NarrowPhaseHandler: newNarrowPhaseLocker(actorIndex, w.terrain, w.def.FriendlyFire, &z.metrics),
+	}
+
+	runningSim, err := simDef.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	z.sim.RunningSimulation = runningSim
+	return z, nil
+}
+
+// Returns the zone responsible for the cell. Cells outside
+// of the world are given to the zone that is closest.
+func (r *zoneRouter) zoneAt(c coord.Cell) Zone {
+	var closest Zone
+	closestDist := -1
+
+	for _, z := range r.zones {
+		b := z.Bounds()
+		if b.Contains(c) {
+			return z
+		}
+
+		dist := distanceOutside(b, c)
+		if closestDist < 0 || dist < closestDist {
+			closest, closestDist = z, dist
+		}
+	}
+
+	return closest
+}
+
+func distanceOutside(b coord.Bounds, c coord.Cell) int {
+	dist := 0
+	switch {
+	case c.X < b.TopL.X:
+		dist += b.TopL.X - c.X
+	case c.X > b.BotR.X:
+		dist += c.X - b.BotR.X
+	}
+
+	switch {
+	case c.Y > b.TopL.Y:
+		dist += c.Y - b.TopL.Y
+	case c.Y < b.BotR.Y:
+		dist += b.BotR.Y - c.Y
+	}
+	return dist
+}
+
+func (r *zoneRouter) ConnectActor(a rpg2d.Actor) {
+	switch a := a.(type) {
+	case *actor:
+		a.placeAtSpawn(r.spawns)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		z := r.zoneAt(a.Cell())
+		r.actors[a] = z
+		z.ConnectActor(a)
+
+	default:
+		panic(fmt.Sprint("unexpected sim.Actor:", a))
+	}
+}
+
+func (r *zoneRouter) RemoveActor(a rpg2d.Actor) {
+	switch a := a.(type) {
+	case *actor:
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		z, exists := r.actors[a]
+		if !exists {
+			return
+		}
+
+		delete(r.actors, a)
+		z.RemoveActor(a)
+
+	default:
+		panic(fmt.Sprint("unexpected sim.Actor:", a))
+	}
+}
+
+// Moves the actor from the zone to the zone responsible
+// for the cell the actor has moved into. Does nothing if
+// the actor has been removed or moved by another handoff.
+func (r *zoneRouter) handOff(a *actor, from Zone, cell coord.Cell) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.halted || r.actors[a] != from {
+		return
+	}
+
+	to := r.zoneAt(cell)
+	from.ReleaseHandoff(a)
+
+	if to == from {
+		from.AcceptHandoff(a)
+		return
+	}
+
+	r.actors[a] = to
+	to.AcceptHandoff(a)
+}
+
+// Moves the actor to the cell. The actor is released by its
+// zone while it's moved so it isn't changed during a tick.
+// Returns false if the actor isn't in any zone.
+func (r *zoneRouter) teleport(a *actor, cell coord.Cell) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	from, exists := r.actors[a]
+	if r.halted || !exists {
+		return false
+	}
+
+	from.ReleaseHandoff(a)
+
+	a.actorEntity.cell = cell
+	a.pathAction = nil
+
+	to := r.zoneAt(cell)
+	r.actors[a] = to
+	to.AcceptHandoff(a)
+	return true
+}
+
+// Moves the actor to the unblocked cell within the radius
+// closest to the cell as of the next tick of every zone.
+// Returns false if the actor isn't in any zone or every
+// cell within the radius is blocked.
+func (r *zoneRouter) teleportNear(a *actor, cell coord.Cell, radius int) bool {
+	bounds := coord.Bounds{cell.Add(-radius, radius), cell.Add(radius, -radius)}
+
+	state, err := r.snapshot(bounds, zoneTeleportTimeout)
+	if err != nil {
+		a.logger.Warn("error finding an unblocked cell", "cell", cell, "err", err)
+		return false
+	}
+
+	unblocked, ok := closestUnblockedCell(cell, radius, r.bounds, r.isBlocked(state, a.actorEntity.id))
+	if !ok {
+		return false
+	}
+
+	return r.teleport(a, unblocked)
+}
+
+// Returns a func that reports if an actor can't be teleported
+// onto a cell because the terrain can't be walked on or an
+// entity in the state is blocking it.
+func (r *zoneRouter) isBlocked(state rpg2d.WorldState, self entity.Id) func(coord.Cell) bool {
+	blocked := blockedCells(state, self)
+	return func(c coord.Cell) bool {
+		return blocked[c] || !r.terrain.propertiesAt(c).walkable
+	}
+}
+
+// Returns the actors being simulated by every zone.
+func (r *zoneRouter) allActors() []*actor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var actors []*actor
+	for _, z := range r.zones {
+		actors = append(actors, z.Actors()...)
+	}
+	return actors
+}
+
+// Returns the stats of every zone's last complete tick.
+func (r *zoneRouter) stats() []ZoneStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]ZoneStats, 0, len(r.zones))
+	for _, z := range r.zones {
+		stats = append(stats, z.Stats())
+	}
+	return stats
+}
+
+var errSnapshotTimeout = errors.New("a zone didn't tick before the snapshot timed out")
+
+// Returns the states of every entity in the world. Zones
+// without any entities don't tick and are empty.
+func (r *zoneRouter) snapshot(bounds coord.Bounds, timeout time.Duration) (rpg2d.WorldState, error) {
+	r.mu.Lock()
+	if r.halted {
+		r.mu.Unlock()
+		return rpg2d.WorldState{}, errZonesHalted
+	}
+
+	snapshots := make([]<-chan rpg2d.WorldState, 0, len(r.zones))
+	for _, z := range r.zones {
+		snapshots = append(snapshots, z.Snapshot())
+	}
+	r.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	state := rpg2d.WorldState{Bounds: bounds}
+	for _, snapshot := range snapshots {
+		select {
+		case s := <-snapshot:
+			if s.Time > state.Time {
+				state.Time = s.Time
+			}
+			state.Entities = append(state.Entities, s.Entities...)
+
+		case <-timer.C:
+			return rpg2d.WorldState{}, errSnapshotTimeout
+		}
+	}
+
+	return state, nil
+}
+
+var errZonesHalted = errors.New("zones have already been halted")
+
+// Halts every zone and returns the actors that were being simulated.
+func (r *zoneRouter) halt() ([]*actor, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.halted {
+		return nil, errZonesHalted
+	}
+	r.halted = true
+
+	var actors []*actor
+	for _, z := range r.zones {
+		zoneActors, err := z.Halt()
+		if err != nil {
+			return actors, err
+		}
+		actors = append(actors, zoneActors...)
+	}
+
+	r.actors = nil
+	return actors, nil
+}
@@ -0,0 +1,206 @@
+package game
+
+import (
+	"time"
+
+	"github.com/ghthor/filu/rpg2d"
+	"github.com/ghthor/filu/rpg2d/coord"
+	"github.com/ghthor/filu/rpg2d/entity"
+
+	"github.com/ghthor/gospec"
+	. "github.com/ghthor/gospec"
+)
+
+// A simulation that records the actors connected to it.
+type zoneTestSimulation struct {
+	rpg2d.RunningSimulation
+
+	connected map[rpg2d.Actor]bool
+	halted    bool
+}
+
+func (s *zoneTestSimulation) ConnectActor(a rpg2d.Actor) { s.connected[a] = true }
+func (s *zoneTestSimulation) RemoveActor(a rpg2d.Actor)  { delete(s.connected, a) }
+
+func (s *zoneTestSimulation) Halt() (rpg2d.HaltedSimulation, error) {
+	s.halted = true
+	return nil, nil
+}
+
+func newTestZone(bounds coord.Bounds) (*localZone, *zoneTestSimulation) {
+	sim := &zoneTestSimulation{connected: make(map[rpg2d.Actor]bool)}
+	return newLocalZone(bounds, simulation{
+		ActorIndexLocker:  NewActorIndexLocker(make(ActorIndex)),
+		RunningSimulation: sim,
+	}), sim
+}
+
+func (z *localZone) hasActor(a *actor) bool {
+	actorIndex := z.sim.ActorIndexLocker.RLock()
+	defer z.sim.ActorIndexLocker.RUnlock()
+	return actorIndex[a.Id()] == a
+}
+
+func DescribeZones(c gospec.Context) {
+	c.Specify("a world divided into zones", func() {
+		world := coord.Bounds{coord.Cell{1, -1}, coord.Cell{100, -100}}
+
+		c.Specify("is divided into squares of the zone size", func() {
+			zones := zoneBoundsOf(world, 64)
+			c.Expect(len(zones), Equals, 4)
+			c.Expect(zones[0], Equals, coord.Bounds{coord.Cell{1, -1}, coord.Cell{64, -64}})
+
+			c.Specify("that are clipped to the world", func() {
+				c.Expect(zones[3], Equals, coord.Bounds{coord.Cell{65, -65}, coord.Cell{100, -100}})
+			})
+		})
+
+		c.Specify("is a single zone if the zone size is 0", func() {
+			c.Expect(zoneBoundsOf(world, 0), ContainsExactly, []coord.Bounds{world})
+		})
+	})
+
+	c.Specify("a zone router", func() {
+		west, westSim := newTestZone(coord.Bounds{coord.Cell{1, -1}, coord.Cell{64, -64}})
+		east, eastSim := newTestZone(coord.Bounds{coord.Cell{65, -1}, coord.Cell{128, -64}})
+
+		r := newZoneRouterOf([]*localZone{west, east}, coord.Bounds{coord.Cell{1, -1}, coord.Cell{128, -64}}, nil, []coord.Cell{{70, -10}})
+
+		c.Specify("links zones that are near each other", func() {
+			c.Expect(west.neighbors, ContainsExactly, []Zone{east})
+			c.Expect(east.neighbors, ContainsExactly, []Zone{west})
+		})
+
+		a := &actor{}
+		a.id = 1
+		a.actorEntity.actorId = 1
+
+		r.ConnectActor(a)
+
+		c.Specify("connects an actor to the zone of its spawn", func() {
+			c.Expect(a.Cell(), Equals, coord.Cell{70, -10})
+			c.Expect(a.zone, Equals, east)
+			c.Expect(east.hasActor(a), IsTrue)
+			c.Expect(eastSim.connected[a], IsTrue)
+			c.Expect(west.hasActor(a), IsFalse)
+		})
+
+		c.Specify("hands off an actor that has crossed into another zone", func() {
+			sendMsgs := a.sendMsgs
+			a.actorEntity.cell = coord.Cell{64, -10}
+
+			r.handOff(a, east, a.Cell())
+
+			c.Expect(a.zone, Equals, west)
+			c.Expect(west.hasActor(a), IsTrue)
+			c.Expect(westSim.connected[a], IsTrue)
+			c.Expect(east.hasActor(a), IsFalse)
+			c.Expect(eastSim.connected[a], IsFalse)
+
+			c.Specify("without interrupting its connection", func() {
+				c.Expect(a.sendMsgs, Equals, sendMsgs)
+			})
+
+			c.Specify("and removes it from its new zone", func() {
+				r.RemoveActor(a)
+				c.Expect(west.hasActor(a), IsFalse)
+				c.Expect(westSim.connected[a], IsFalse)
+			})
+		})
+
+		c.Specify("ignores a handoff from a zone the actor has already left", func() {
+			r.handOff(a, west, coord.Cell{10, -10})
+			c.Expect(a.zone, Equals, east)
+			c.Expect(west.hasActor(a), IsFalse)
+		})
+
+		c.Specify("observes an actor that has stopped outside of its zone", func() {
+			type crossing struct {
+				from Zone
+				cell coord.Cell
+			}
+
+			crossed := make(chan crossing, 2)
+			east.onCrossed = func(_ *actor, from Zone, cell coord.Cell) {
+				crossed <- crossing{from, cell}
+			}
+
+			a.actorEntity.cell = coord.Cell{64, -10}
+			actorIndex := east.sim.ActorIndexLocker.RLock()
+			east.observe(a.Entity(), actorIndex, 1)
+			east.observe(a.Entity(), actorIndex, 2)
+			east.sim.ActorIndexLocker.RUnlock()
+
+			c.Specify("and hands it off once", func() {
+				crossing := <-crossed
+				c.Expect(crossing.from, Equals, Zone(east))
+				c.Expect(crossing.cell, Equals, coord.Cell{64, -10})
+
+				var again bool
+				select {
+				case <-crossed:
+					again = true
+				case <-time.After(50 * time.Millisecond):
+				}
+				c.Expect(again, IsFalse)
+			})
+		})
+
+		c.Specify("shows the entities near a zone's border to its neighbours", func() {
+			border := actorEntity{id: 10, cell: coord.Cell{60, -10}}
+			inner := actorEntity{id: 11, cell: coord.Cell{30, -30}}
+
+			west.observe(border, nil, 1)
+			west.observe(inner, nil, 1)
+
+			// Published once the next tick has begun
+			west.observe(nil, nil, 2)
+
+			entities := east.neighborEntities(ActorCullBounds(coord.Cell{70, -10}))
+			c.Expect(len(entities), Equals, 1)
+			c.Expect(entities[0].EntityId(), Equals, entity.Id(10))
+
+			c.Specify("unless the neighbour has stopped ticking", func() {
+				west.border.publishedAt = time.Now().Add(-2 * zoneBorderTimeout)
+				c.Expect(len(east.neighborEntities(ActorCullBounds(coord.Cell{70, -10}))), Equals, 0)
+			})
+
+			c.Specify("and stops an actor moving into a cell they block", func() {
+				a.actorEntity.cell = coord.Cell{65, -10}
+				a.speed = baseSpeed
+
+				west.observe(wallEntity{id: 12, cell: coord.Cell{64, -10}}, nil, 3)
+				west.observe(nil, nil, 4)
+
+				c.Expect(a.step(coord.West, nil, 10), IsFalse)
+				c.Expect(a.pathAction, IsNil)
+				c.Expect(a.step(coord.North, nil, 10), IsTrue)
+			})
+
+			c.Specify("and adds them to the state an actor is sent", func() {
+				state := rpg2d.WorldState{Entities: make(entity.StateSlice, 1, 4)}
+				state = a.withNeighborEntities(state, ActorCullBounds(a.Cell()))
+				c.Expect(len(state.Entities), Equals, 2)
+			})
+		})
+
+		c.Specify("halts every zone", func() {
+			actors, err := r.halt()
+			c.Assume(err, IsNil)
+			c.Expect(actors, ContainsExactly, []*actor{a})
+			c.Expect(westSim.halted, IsTrue)
+			c.Expect(eastSim.halted, IsTrue)
+
+			c.Specify("only once", func() {
+				_, err := r.halt()
+				c.Expect(err, Equals, errZonesHalted)
+			})
+		})
+
+		if r.actors[a] != nil {
+			r.RemoveActor(a)
+		} else if r.halted {
+			a.stopIO()
+		}
+	})
+}
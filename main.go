@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"text/template"
 
 	_ "net/http/pprof"
@@ -26,10 +27,28 @@ func serverUrl(onHeroku bool, domain, port string) string {
 func main() {
 	domain := os.Getenv("DOMAIN")
 	port := os.Getenv("PORT")
+	adminToken := os.Getenv("ADMIN_TOKEN")
 
 	isHeroku := flag.Bool("heroku", true, "enable is the app is running on heroku")
+	worldEditsPath := flag.String("world", "", "file the changes players make to the world are saved in")
+	legendPath := flag.String("legend", "", "file the legends of characters are saved in")
+	factionPath := flag.String("factions", "", "file the guilds and allegiances of characters are saved in")
+	generate := flag.Bool("generate", false, "generate a procedural world each epoch instead of the arena")
+	seed := flag.Int64("seed", 0, "seed of the procedural worlds")
+	size := flag.Int("size", game.DefaultWorldGenConfig.Width, "width and height in cells of the procedural worlds")
+	chunked := flag.Bool("chunked", false, "generate the terrain of procedural worlds one chunk at a time")
+	zoneSize := flag.Int("zone-size", 0, "width and height in cells of the zones procedural worlds are divided into")
+	admins := flag.String("admins", "", "comma separated names of the actors that can use admin commands")
+	logLevel := flag.String("log-level", "info", "the lowest level of the lines logged: debug, info, warn or error")
 	flag.Parse()
 
+	level, err := game.ParseLogLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	logger := game.NewLogger(os.Stderr, level)
+
 	c := game.ShardConfig{
 		OnHeroku: *isHeroku,
 
@@ -45,6 +64,34 @@ func main() {
 		IndexTmpl: indexTmpl,
 
 		Mux: http.NewServeMux(),
+
+		Npcs:          game.ArenaNpcs,
+		ResourceNodes: game.ArenaResourceNodes,
+
+		WorldEditsPath: *worldEditsPath,
+		LegendPath:     *legendPath,
+		FactionPath:    *factionPath,
+
+		AdminToken: adminToken,
+
+		// Served by the profiling server
+		MetricsMux: http.DefaultServeMux,
+
+		Logger: logger,
+	}
+
+	if *admins != "" {
+		c.Admins = strings.Split(*admins, ",")
+	}
+
+	if *generate {
+		worldGen := game.DefaultWorldGenConfig
+		worldGen.Seed = *seed
+		worldGen.Width, worldGen.Height = *size, *size
+		worldGen.Chunked = *chunked
+		worldGen.ZoneSize = *zoneSize
+		worldGen.Npcs = game.ArenaNpcs
+		c.NewWorld = game.ProceduralWorld(worldGen)
 	}
 
 	s, err := game.NewSimShard(c)
@@ -53,14 +100,14 @@ func main() {
 	}
 
 	go func() {
-		log.Println("starting profiling server at", "http://localhost:6060")
+		logger.Info("starting profiling and metrics server", "url", "http://localhost:6060")
 		err := http.ListenAndServe("localhost:6060", nil)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}()
 
-	log.Println("starting server at", serverUrl(*isHeroku, domain, port))
+	logger.Info("starting server", "url", serverUrl(*isHeroku, domain, port))
 	err = s.ListenAndServe()
 	if err != nil {
 		log.Fatal(err)
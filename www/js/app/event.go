@@ -22,7 +22,35 @@ const (
 
 	EV_RECV_UPDATE
 
+	// Messages sent privately to the actor
+	EV_RECV_COOLDOWNS
+	EV_RECV_USE_REJECTED
+	EV_RECV_RESOURCES
+	EV_RECV_BUILD_REJECTED
+	EV_RECV_CRAFT
+	EV_RECV_CRAFT_REJECTED
+	EV_RECV_EQUIP_REJECTED
+	EV_RECV_WORLD_ENDED
+	EV_RECV_KICKED
+	EV_RECV_CHAT_REJECTED
+	EV_RECV_COMMAND_REPLY
+	EV_RECV_PARTY
+	EV_RECV_PARTY_INVITE
+	EV_RECV_PARTY_REJECTED
+	EV_RECV_FACTION
+	EV_RECV_TRADE
+	EV_RECV_TRADE_INVITE
+	EV_RECV_TRADE_REJECTED
+
 	EV_RECV_CHAT_SAY
+	EV_RECV_CHAT_WHISPER
+	EV_RECV_CHAT_SHOUT
+	EV_RECV_CHAT_PARTY
+	EV_RECV_CHAT_GLOBAL
+	EV_RECV_CHAT_FACTION
+	EV_RECV_CHAT_GUILD
+	EV_RECV_CHAT_EMOTE
+	EV_RECV_CHAT_ANNOUNCE
 	EV_SENT_CHAT_SAY
 
 	EV_TERRAIN_RESET
@@ -19,21 +19,48 @@ func _() {
 	_ = x[EV_RECV_INPUT_CONN-8]
 	_ = x[EV_RECV_INITIAL_STATE-9]
 	_ = x[EV_RECV_UPDATE-10]
-	_ = x[EV_RECV_CHAT_SAY-11]
-	_ = x[EV_SENT_CHAT_SAY-12]
-	_ = x[EV_TERRAIN_RESET-13]
-	_ = x[EV_TERRAIN_CANVAS_SHIFT-14]
-	_ = x[EV_TERRAIN_DRAW_TILE-15]
-	_ = x[EV_SIZE-16]
+	_ = x[EV_RECV_COOLDOWNS-11]
+	_ = x[EV_RECV_USE_REJECTED-12]
+	_ = x[EV_RECV_RESOURCES-13]
+	_ = x[EV_RECV_BUILD_REJECTED-14]
+	_ = x[EV_RECV_CRAFT-15]
+	_ = x[EV_RECV_CRAFT_REJECTED-16]
+	_ = x[EV_RECV_EQUIP_REJECTED-17]
+	_ = x[EV_RECV_WORLD_ENDED-18]
+	_ = x[EV_RECV_KICKED-19]
+	_ = x[EV_RECV_CHAT_REJECTED-20]
+	_ = x[EV_RECV_COMMAND_REPLY-21]
+	_ = x[EV_RECV_PARTY-22]
+	_ = x[EV_RECV_PARTY_INVITE-23]
+	_ = x[EV_RECV_PARTY_REJECTED-24]
+	_ = x[EV_RECV_FACTION-25]
+	_ = x[EV_RECV_TRADE-26]
+	_ = x[EV_RECV_TRADE_INVITE-27]
+	_ = x[EV_RECV_TRADE_REJECTED-28]
+	_ = x[EV_RECV_CHAT_SAY-29]
+	_ = x[EV_RECV_CHAT_WHISPER-30]
+	_ = x[EV_RECV_CHAT_SHOUT-31]
+	_ = x[EV_RECV_CHAT_PARTY-32]
+	_ = x[EV_RECV_CHAT_GLOBAL-33]
+	_ = x[EV_RECV_CHAT_FACTION-34]
+	_ = x[EV_RECV_CHAT_GUILD-35]
+	_ = x[EV_RECV_CHAT_EMOTE-36]
+	_ = x[EV_RECV_CHAT_ANNOUNCE-37]
+	_ = x[EV_SENT_CHAT_SAY-38]
+	_ = x[EV_TERRAIN_RESET-39]
+	_ = x[EV_TERRAIN_CANVAS_SHIFT-40]
+	_ = x[EV_TERRAIN_DRAW_TILE-41]
+	_ = x[EV_SIZE-42]
 }
 
-const _event_name = "EV_ERROREV_CONNECTEDEV_ACTOR_ALREADY_CONNECTEDEV_ACTOR_DOESNT_EXISTEV_ACTOR_EXISTSEV_AUTH_FAILEDEV_LOGIN_SUCCESSEV_CREATE_SUCCESSEV_RECV_INPUT_CONNEV_RECV_INITIAL_STATEEV_RECV_UPDATEEV_RECV_CHAT_SAYEV_SENT_CHAT_SAYEV_TERRAIN_RESETEV_TERRAIN_CANVAS_SHIFTEV_TERRAIN_DRAW_TILEEV_SIZE"
+const _event_name = "EV_ERROREV_CONNECTEDEV_ACTOR_ALREADY_CONNECTEDEV_ACTOR_DOESNT_EXISTEV_ACTOR_EXISTSEV_AUTH_FAILEDEV_LOGIN_SUCCESSEV_CREATE_SUCCESSEV_RECV_INPUT_CONNEV_RECV_INITIAL_STATEEV_RECV_UPDATEEV_RECV_COOLDOWNSEV_RECV_USE_REJECTEDEV_RECV_RESOURCESEV_RECV_BUILD_REJECTEDEV_RECV_CRAFTEV_RECV_CRAFT_REJECTEDEV_RECV_EQUIP_REJECTEDEV_RECV_WORLD_ENDEDEV_RECV_KICKEDEV_RECV_CHAT_REJECTEDEV_RECV_COMMAND_REPLYEV_RECV_PARTYEV_RECV_PARTY_INVITEEV_RECV_PARTY_REJECTEDEV_RECV_FACTIONEV_RECV_TRADEEV_RECV_TRADE_INVITEEV_RECV_TRADE_REJECTEDEV_RECV_CHAT_SAYEV_RECV_CHAT_WHISPEREV_RECV_CHAT_SHOUTEV_RECV_CHAT_PARTYEV_RECV_CHAT_GLOBALEV_RECV_CHAT_FACTIONEV_RECV_CHAT_GUILDEV_RECV_CHAT_EMOTEEV_RECV_CHAT_ANNOUNCEEV_SENT_CHAT_SAYEV_TERRAIN_RESETEV_TERRAIN_CANVAS_SHIFTEV_TERRAIN_DRAW_TILEEV_SIZE"
 
-var _event_index = [...]uint16{0, 8, 20, 46, 67, 82, 96, 112, 129, 147, 168, 182, 198, 214, 230, 253, 273, 280}
+var _event_index = [...]uint16{0, 8, 20, 46, 67, 82, 96, 112, 129, 147, 168, 182, 199, 219, 236, 258, 271, 293, 315, 334, 348, 369, 390, 403, 423, 445, 460, 473, 493, 515, 531, 551, 569, 587, 606, 626, 644, 662, 683, 699, 715, 738, 758, 765}
 
 func (i event) String() string {
-	if i < 0 || i >= event(len(_event_index)-1) {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_event_index)-1 {
 		return "event(" + strconv.FormatInt(int64(i), 10) + ")"
 	}
-	return _event_name[_event_index[i]:_event_index[i+1]]
+	return _event_name[_event_index[idx]:_event_index[idx+1]]
 }
@@ -150,6 +150,22 @@ func initialize(settings js.Value) js.Value {
 		gameModule.Set(game.ChatRequestType(i).String(), int(game.ChatRequestType(i)))
 	}
 
+	for i := game.BR_ERROR; i < game.BR_SIZE; i++ {
+		gameModule.Set(game.BuildRequestType(i).String(), int(game.BuildRequestType(i)))
+	}
+
+	for i := game.PR_ERROR; i < game.PR_SIZE; i++ {
+		gameModule.Set(game.PartyRequestType(i).String(), int(game.PartyRequestType(i)))
+	}
+
+	for i := game.TR_ERROR; i < game.TR_SIZE; i++ {
+		gameModule.Set(game.TradeRequestType(i).String(), int(game.TradeRequestType(i)))
+	}
+
+	for i := game.ES_WEAPON; i < game.ES_SIZE; i++ {
+		gameModule.Set(game.EquipmentSlot(i).String(), int(game.EquipmentSlot(i)))
+	}
+
 	// require("github.com/ghthor/filu/rpg2d/coord")
 	module.Set("coord", coordModule)
 	// require("github.com/ghthor/aodd/game")
@@ -263,6 +279,14 @@ func (w *world) update(diff rpg2d.WorldStateDiff) {
 	}
 }
 
+// Returns the cell the actor is facing.
+func (w *world) facingCell() coord.Cell {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.entity.Cell.Neighbor(w.entity.Facing)
+}
+
 func (w *world) actorEntityById(id entity.Id) (game.ActorEntityState, error) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
@@ -343,13 +367,20 @@ func newLoggedInConn(name string, loggedInConn client.LoggedInConn) (result js.V
 							return
 						}
 
+						if update.Msgs != nil {
+							for _, msg := range update.Msgs {
+								emitActorMsgEvent(msg, pub)
+							}
+							continue
+						}
+
 						// TODO Fix unsafe concurrent access of world.state
-						err = canvas.ApplyTerrainDiff(terrainCanvas{pub}, world.state, update)
+						err = canvas.ApplyTerrainDiff(terrainCanvas{pub}, world.state, update.WorldStateDiff)
 						if err != nil {
 							pub.Emit(EV_ERROR, jsArray(errorObj(err)))
 						}
 
-						world.update(update)
+						world.update(update.WorldStateDiff)
 
 						for _, e := range update.Entities {
 							switch e := e.(type) {
@@ -361,7 +392,7 @@ func newLoggedInConn(name string, loggedInConn client.LoggedInConn) (result js.V
 							}
 						}
 
-						pub.Emit(EV_RECV_UPDATE, jsArray(update))
+						pub.Emit(EV_RECV_UPDATE, jsArray(update.WorldStateDiff))
 					}
 
 				case resp := <-trip.ActorAlreadyConnected:
@@ -394,6 +425,209 @@ func emitChatRecvEvent(world *world, say game.SayEntityState, pub EventPublisher
 	return nil
 }
 
+func emitActorMsgEvent(msg game.ActorMsg, pub EventPublisher) {
+	switch msg := msg.(type) {
+	case game.CooldownsMsg:
+		cooldowns := make([]interface{}, 0, len(msg.Cooldowns))
+		for _, cd := range msg.Cooldowns {
+			cooldowns = append(cooldowns, map[string]interface{}{
+				"Skill":   cd.Skill,
+				"ReadyAt": int64(cd.ReadyAt),
+			})
+		}
+
+		pub.Emit(EV_RECV_COOLDOWNS, jsArray(
+			int64(msg.Time),
+			cooldowns,
+		))
+
+	case game.UseRejectedMsg:
+		pub.Emit(EV_RECV_USE_REJECTED, jsArray(
+			msg.Skill,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+
+	case game.ResourcesMsg:
+		resources := make(map[string]interface{}, len(msg.Resources))
+		for name, n := range msg.Resources {
+			resources[name] = n
+		}
+
+		pub.Emit(EV_RECV_RESOURCES, jsArray(
+			int64(msg.Time),
+			resources,
+		))
+
+	case game.BuildRejectedMsg:
+		pub.Emit(EV_RECV_BUILD_REJECTED, jsArray(
+			msg.Request.String(),
+			msg.Cell,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+
+	case game.CraftMsg:
+		pub.Emit(EV_RECV_CRAFT, jsArray(
+			msg.Recipe,
+			msg.Completed,
+			int64(msg.End),
+			int64(msg.Time),
+		))
+
+	case game.CraftRejectedMsg:
+		pub.Emit(EV_RECV_CRAFT_REJECTED, jsArray(
+			msg.Recipe,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+
+	case game.EquipRejectedMsg:
+		pub.Emit(EV_RECV_EQUIP_REJECTED, jsArray(
+			msg.Item,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+
+	case game.WorldEndedMsg:
+		pub.Emit(EV_RECV_WORLD_ENDED, jsArray(
+			msg.Epoch,
+			msg.Reason,
+			int64(msg.Time),
+		))
+
+	case game.KickedMsg:
+		pub.Emit(EV_RECV_KICKED, jsArray(
+			msg.Reason,
+			int64(msg.Time),
+		))
+
+	case game.ChatMsg:
+		// Each channel has its own event so the ui
+		// can display the channels differently.
+		var ev event
+		switch msg.Channel {
+		case game.CR_WHISPER:
+			ev = EV_RECV_CHAT_WHISPER
+		case game.CR_SHOUT:
+			ev = EV_RECV_CHAT_SHOUT
+		case game.CR_PARTY:
+			ev = EV_RECV_CHAT_PARTY
+		case game.CR_GLOBAL:
+			ev = EV_RECV_CHAT_GLOBAL
+		case game.CR_FACTION:
+			ev = EV_RECV_CHAT_FACTION
+		case game.CR_GUILD:
+			ev = EV_RECV_CHAT_GUILD
+		case game.CR_EMOTE:
+			ev = EV_RECV_CHAT_EMOTE
+		case game.CR_ANNOUNCE:
+			ev = EV_RECV_CHAT_ANNOUNCE
+		default:
+			return
+		}
+
+		pub.Emit(ev, jsArray(
+			msg.From,
+			msg.To,
+			msg.Msg,
+			int64(msg.Time),
+		))
+
+	case game.CommandReplyMsg:
+		pub.Emit(EV_RECV_COMMAND_REPLY, jsArray(
+			msg.Command,
+			msg.Reply,
+			msg.Failed,
+			int64(msg.Time),
+		))
+
+	case game.ChatRejectedMsg:
+		pub.Emit(EV_RECV_CHAT_REJECTED, jsArray(
+			msg.Channel.String(),
+			msg.To,
+			msg.Reason.String(),
+			int64(msg.Time),
+			msg.Dropped,
+		))
+
+	case game.PartyMsg:
+		members := js.Global().Get("Array").New(len(msg.Members))
+		for i, m := range msg.Members {
+			member := newJSObject()
+			member.Set("Name", m.Name)
+			member.Set("Cell", m.Cell)
+			member.Set("Hp", m.Hp)
+			member.Set("HpMax", m.HpMax)
+			members.SetIndex(i, member)
+		}
+
+		pub.Emit(EV_RECV_PARTY, jsArray(
+			msg.Leader,
+			members,
+			int64(msg.Time),
+		))
+
+	case game.FactionMsg:
+		pub.Emit(EV_RECV_FACTION, jsArray(
+			string(msg.Allegiance),
+			msg.Guild,
+			msg.Standing,
+			int64(msg.Time),
+		))
+
+	case game.PartyInviteMsg:
+		pub.Emit(EV_RECV_PARTY_INVITE, jsArray(
+			msg.From,
+			int64(msg.Time),
+		))
+
+	case game.PartyRejectedMsg:
+		pub.Emit(EV_RECV_PARTY_REJECTED, jsArray(
+			msg.Request.String(),
+			msg.Name,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+
+	case game.TradeMsg:
+		offerState := func(o game.TradeOfferState) js.Value {
+			items := make(map[string]interface{}, len(o.Items))
+			for name, n := range o.Items {
+				items[name] = n
+			}
+
+			offer := newJSObject()
+			offer.Set("Name", o.Name)
+			offer.Set("Items", items)
+			offer.Set("Locked", o.Locked)
+			offer.Set("Confirmed", o.Confirmed)
+			return offer
+		}
+
+		pub.Emit(EV_RECV_TRADE, jsArray(
+			msg.Status.String(),
+			offerState(msg.Mine),
+			offerState(msg.Theirs),
+			int64(msg.Time),
+		))
+
+	case game.TradeInviteMsg:
+		pub.Emit(EV_RECV_TRADE_INVITE, jsArray(
+			msg.From,
+			int64(msg.Time),
+		))
+
+	case game.TradeRejectedMsg:
+		pub.Emit(EV_RECV_TRADE_REJECTED, jsArray(
+			msg.Request.String(),
+			msg.Name,
+			msg.Reason.String(),
+			int64(msg.Time),
+		))
+	}
+}
+
 func newInputConn(world *world, conn client.InputConn, pub EventPublisher) (result js.Value) {
 	result = newJSObject()
 	result.Set("sendMoveRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
@@ -413,6 +647,22 @@ func newInputConn(world *world, conn client.InputConn, pub EventPublisher) (resu
 		return nil
 	}))
 
+	result.Set("sendMoveToRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		dest := coord.Cell{args[0].Int(), args[1].Int()}
+
+		func(dest coord.Cell) {
+			go func() {
+				conn.SendMoveRequest(game.MoveRequest{
+					MoveRequestType: game.MR_MOVE_TO,
+					Time:            world.now(),
+					Dest:            dest,
+				})
+			}()
+		}(dest)
+
+		return nil
+	}))
+
 	result.Set("sendUseRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		typ := game.UseRequestType(args[0].Int())
 		skill := args[1].String()
@@ -433,17 +683,149 @@ func newInputConn(world *world, conn client.InputConn, pub EventPublisher) (resu
 		typ := game.ChatRequestType(args[0].Int())
 		msg := args[1].String()
 
-		func(typ game.ChatRequestType, msg string) {
+		// The recipient of a whisper
+		var to string
+		if len(args) > 2 {
+			to = args[2].String()
+		}
+
+		func(typ game.ChatRequestType, msg, to string) {
 			go func() {
 				conn.SendChatRequest(game.ChatRequest{
 					ChatRequestType: typ,
 					Time:            world.now(),
 					Msg:             msg,
+					To:              to,
 				})
 			}()
 
 			pub.Emit(EV_SENT_CHAT_SAY, jsArray())
-		}(typ, msg)
+		}(typ, msg, to)
+		return nil
+	}))
+
+	// Builds on the cell the actor is facing
+	result.Set("sendBuildRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		typ := game.BuildRequestType(args[0].Int())
+
+		var terrain rpg2d.TerrainType
+		if len(args) > 1 && args[1].String() != "" {
+			terrain = rpg2d.TerrainType(args[1].String()[0])
+		}
+
+		func(typ game.BuildRequestType, terrain rpg2d.TerrainType) {
+			go func() {
+				conn.SendBuildRequest(game.BuildRequest{
+					BuildRequestType: typ,
+					Time:             world.now(),
+					Cell:             world.facingCell(),
+					Terrain:          terrain,
+				})
+			}()
+		}(typ, terrain)
+		return nil
+	}))
+
+	result.Set("sendCraftRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		recipe := args[0].String()
+
+		func(recipe string) {
+			go func() {
+				conn.SendCraftRequest(game.CraftRequest{
+					Time:   world.now(),
+					Recipe: recipe,
+				})
+			}()
+		}(recipe)
+		return nil
+	}))
+
+	// An empty item unequips the slot
+	result.Set("sendEquipRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		slot := game.EquipmentSlot(args[0].Int())
+
+		var item string
+		if len(args) > 1 {
+			item = args[1].String()
+		}
+
+		func(slot game.EquipmentSlot, item string) {
+			go func() {
+				conn.SendEquipRequest(game.EquipRequest{
+					Time: world.now(),
+					Slot: slot,
+					Item: item,
+				})
+			}()
+		}(slot, item)
+		return nil
+	}))
+
+	// The name is unused by PR_LEAVE requests
+	result.Set("sendPartyRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		typ := game.PartyRequestType(args[0].Int())
+
+		var name string
+		if len(args) > 1 {
+			name = args[1].String()
+		}
+
+		func(typ game.PartyRequestType, name string) {
+			go func() {
+				conn.SendPartyRequest(game.PartyRequest{
+					PartyRequestType: typ,
+					Time:             world.now(),
+					Name:             name,
+				})
+			}()
+		}(typ, name)
+		return nil
+	}))
+
+	// The name is only used by TR_REQUEST requests and the
+	// items, an object of item counts, by TR_OFFER requests.
+	result.Set("sendTradeRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		typ := game.TradeRequestType(args[0].Int())
+
+		var name string
+		if len(args) > 1 && args[1].Type() == js.TypeString {
+			name = args[1].String()
+		}
+
+		var items game.Resources
+		if len(args) > 2 && args[2].Type() == js.TypeObject {
+			keys := js.Global().Get("Object").Call("keys", args[2])
+			items = make(game.Resources, keys.Length())
+			for i := 0; i < keys.Length(); i++ {
+				item := keys.Index(i).String()
+				items[item] = args[2].Get(item).Int()
+			}
+		}
+
+		func(typ game.TradeRequestType, name string, items game.Resources) {
+			go func() {
+				conn.SendTradeRequest(game.TradeRequest{
+					TradeRequestType: typ,
+					Time:             world.now(),
+					Name:             name,
+					Items:            items,
+				})
+			}()
+		}(typ, name, items)
+		return nil
+	}))
+
+	result.Set("sendEmoteRequest", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		emote := args[0].String()
+
+		func(emote string) {
+			go func() {
+				conn.SendEmoteRequest(game.EmoteRequest{
+					Time:  world.now(),
+					Emote: emote,
+				})
+			}()
+		}(emote)
 		return nil
 	}))
 
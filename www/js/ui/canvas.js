@@ -24,6 +24,10 @@ define(["jquery",
 
             var world = new World(director, scene);
 
+            scene.mouseClick = function(e) {
+                client.emit("cellClicked", [world.sceneToCell(e.point)]);
+            };
+
             client.on(app.EV_ERROR, function(error) {
                 console.log(error);
             });
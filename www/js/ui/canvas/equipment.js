@@ -0,0 +1,47 @@
+define(["underscore",
+       "CAAT",
+], function(_) {
+    // Colors of the items an actor can equip
+    var itemColors = {
+        sword: "silver",
+        armor: "sienna",
+        boots: "saddlebrown",
+        torch: "orange",
+    };
+
+    // Displays a row of markers, one for each equipment
+    // slot, filled with the color of the equipped item.
+    var Equipment = function(width, height, slots) {
+        var equipment = this;
+
+        equipment.actor = new CAAT.ActorContainer().
+            setSize(width, height);
+
+        var size = width / slots;
+        var markers = _.map(_.range(slots), function(i) {
+            var marker = new CAAT.ShapeActor().
+                setShape(CAAT.ShapeActor.SHAPE_RECTANGLE).
+                setSize(size - 1, height).
+                setPositionAnchored(i * size, 0, 0, 0).
+                setVisible(false);
+            equipment.actor.addChild(marker);
+            return marker;
+        });
+
+        equipment.setEquipment = function(items) {
+            _.each(markers, function(marker, i) {
+                var item = items[i];
+                if (_.isUndefined(item) || item === "") {
+                    marker.setVisible(false);
+                    return;
+                }
+
+                marker.setFillStyle(itemColors[item] || "white").setVisible(true);
+            });
+        };
+
+        return this;
+    };
+
+    return Equipment;
+});
@@ -3,8 +3,9 @@ define(["underscore",
        "ui/canvas/bar",
        "ui/canvas/chat_bubble",
        "ui/canvas/sprite/human",
+       "ui/canvas/equipment",
        "CAAT"
-], function(_, Bar, Bubble, Human) {
+], function(_, Bar, Bubble, Human, Equipment) {
 
     var Player = function(params) {
         var player = this;
@@ -51,6 +52,15 @@ define(["underscore",
                 healthBar.setPercent(percent);
             };
 
+            var equipment = new Equipment(width, height/4, 4);
+            equipment.actor.
+                setPositionAnchored(width/2, height+2, 0.5, 0.5);
+            actor.addChild(equipment.actor);
+
+            player.setEquipment = function(items) {
+                equipment.setEquipment(items);
+            };
+
             var bubble = new Bubble(150, 80);
             bubble.actor.setPositionAnchored(width/2, -10, 0.5, 1);
             actor.addChild(bubble.actor);
@@ -104,6 +114,9 @@ define(["underscore",
                 // update health display
                 player.setHealthPercentage(entity.Hp/entity.HpMax);
 
+                // update equipped items
+                player.setEquipment(entity.Equipment);
+
                 playerEntity = entity;
             };
         };
@@ -10,7 +10,14 @@ define(["github.com/ghthor/filu/rpg2d/coord",
         walkNorth:  [0,1,2,1],
         walkEast:   [3,4,5,4],
         walkSouth:  [6,7,8,7],
-        walkWest:   [9,10,11,10]
+        walkWest:   [9,10,11,10],
+
+        // Emotes are played facing south
+        wave:       [7,6,7,8],
+        bow:        [7,8],
+        cheer:      [6,8],
+        dance:      [1,4,7,10],
+        sit:        [7]
     };
 
     var Human = function(gender) {
@@ -38,7 +45,10 @@ define(["github.com/ghthor/filu/rpg2d/coord",
     var setAnimation = function(entity) {
         var sprite, sprites = this.sprites;
 
-        if (!_.isNull(entity.PathAction)) {
+        if (!_.isNull(entity.Animation) && _.has(sprites, entity.Animation.Name)) {
+            // Emoting
+            sprite = sprites[entity.Animation.Name];
+        } else if (!_.isNull(entity.PathAction)) {
             // Walking
             switch (entity.Facing) {
             case coord.North:
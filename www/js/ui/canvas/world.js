@@ -4,8 +4,9 @@ define(["underscore",
        "ui/canvas/bar",
        "ui/canvas/chat_bubble",
        "ui/canvas/player",
+       "ui/canvas/equipment",
        "CAAT",
-], function(_, Human, Bar, Bubble, Player) {
+], function(_, Human, Bar, Bubble, Player, Equipment) {
     var World = function(director, scene) {
         var world = this;
 
@@ -36,6 +37,19 @@ define(["underscore",
             };
         };
 
+        // Converts a point in the scene to the cell drawn under it
+        world.sceneToCell = function(p) {
+            var local = {
+                x: p.x - container.x,
+                y: p.y - container.y,
+            };
+
+            return {
+                X: Math.round((local.x - width/2) / grid),
+                Y: Math.round((height/2 - local.y) / grid),
+            };
+        };
+
         var newWall = function(entity) {
             var p = cellToLocal(entity.Cell);
             var actor = new CAAT.ActorContainer().
@@ -52,6 +66,58 @@ define(["underscore",
             return actor;
         };
 
+        // Draws the cells covered by an area of effect. The
+        // opacity of each cell represents the damage it does.
+        var newAoe = function(entity) {
+            var actor = new CAAT.ActorContainer().
+                setSize(width, height).
+                setPosition(0, 0);
+
+            _.each(entity.Cells, function(cell) {
+                var p = cellToLocal(cell.Cell);
+                actor.addChild(new CAAT.Actor().
+                    setSize(grid, grid).
+                    setPositionAnchored(p.x, p.y, 0.5, 0.5).
+                    setFillStyle("red").
+                    setAlpha(Math.min(cell.Damage / 50, 1)));
+            });
+
+            // The entity only exists for a single frame
+            actor.addBehavior(new CAAT.AlphaBehavior().
+                setValues(1, 0).
+                setDelayTime(0, 500));
+            actor.setDiscardable(true).setFrameTime(0, 500);
+
+            return actor;
+        };
+
+        var resourceNodeColors = {
+            tree: "forestgreen",
+            ore:  "slategray",
+        };
+
+        // Draws a resource node as a colored cell. A depleted
+        // node is faded until it respawns.
+        var newResourceNode = function(entity) {
+            var p = cellToLocal(entity.Cell);
+            var actor = new CAAT.Actor().
+                setSize(grid, grid).
+                setPositionAnchored(p.x, p.y, 0.5, 0.5).
+                setFillStyle(resourceNodeColors[entity.Kind] || "brown");
+
+            actor.setAmount = function(amount) {
+                actor.setAlpha(amount > 0 ? 1 : 0.3);
+            };
+
+            actor.setAmount(entity.Amount);
+            return actor;
+        };
+
+        var allegianceColors = {
+            good: "#1f5fbf",
+            bad:  "#9f1f1f",
+        };
+
         var newActor = function(entity) {
             var p = cellToLocal(entity.Cell);
             var actor = new CAAT.ActorContainer().
@@ -80,6 +146,21 @@ define(["underscore",
                 healthBar.setPercent(percent);
             };
 
+            var equipment = new Equipment(grid, grid/4, 4);
+            equipment.actor.
+                setPositionAnchored(grid/2, grid+2, 0.5, 0.5);
+            actor.addChild(equipment.actor);
+
+            actor.setEquipment = function(items) {
+                equipment.setEquipment(items);
+            };
+
+            // Names are colored by the power the actor serves
+            actor.setAllegiance = function(allegiance, guild) {
+                name.setTextFillStyle(allegianceColors[allegiance] || "black").
+                    setText(guild === "" ? entity.Name : entity.Name + " <" + guild + ">");
+            };
+
             var bubble = new Bubble(150, 80);
             bubble.actor.setPositionAnchored(grid/2, -10, 0.5, 1);
             actor.addChild(bubble.actor);
@@ -184,6 +265,11 @@ define(["underscore",
                         }
                     }
 
+                    if (entity.Type === "structure" || entity.Type === "resourceNode") {
+                        actors[entity.Id].destroy();
+                        delete actors[entity.Id];
+                    }
+
                     delete entities[entity.Id];
                     return; //continue
                 }
@@ -208,6 +294,11 @@ define(["underscore",
                         (new Audio("asset/audio/assail.wav")).play();
                     }
 
+                    if (entity.Type === "aoe") {
+                        (new Audio("asset/audio/assail.wav")).play();
+                        container.addChild(newAoe(entity));
+                    }
+
                     if (entity.Type === "say") {
                         if (player.is(entity.SaidBy)) {
                             player.setSayMsg(entity.Id, entity.Msg);
@@ -220,7 +311,7 @@ define(["underscore",
                         entities[entity.Id] = entity;
                     }
 
-                    if (entity.Type === "wall") {
+                    if (entity.Type === "wall" || entity.Type === "structure") {
                         (function() {
                             var actor = newWall(entity);
                             container.addChild(actor);
@@ -229,6 +320,20 @@ define(["underscore",
                         }());
                     }
 
+                    if (entity.Type === "resourceNode") {
+                        (function() {
+                            var actor = actors[entity.Id];
+                            if (_.isUndefined(actor)) {
+                                actor = newResourceNode(entity);
+                                container.addChild(actor);
+                                actors[entity.Id] = actor;
+                            }
+
+                            actor.setAmount(entity.Amount);
+                            entities[entity.Id] = entity;
+                        }());
+                    }
+
                     if (entity.Type === "removed") {
                         removeEntity(entity);
                     }
@@ -271,6 +376,11 @@ define(["underscore",
 
                 // update health display
                 actor.setHealthPercentage(entity.Hp/entity.HpMax);
+
+                // update equipped items
+                actor.setEquipment(entity.Equipment);
+
+                actor.setAllegiance(entity.Allegiance, entity.Guild);
             };
 
             // Update all entities
@@ -15,12 +15,24 @@ define([
 ], function(Canvas, InputState, app, game, coord, react, $, _, pubsub) {
         var Message = react.createFactory(react.createClass({
                     render: function() {
+                        if (this.props.emote) {
+                            return react.DOM.li({
+                                    className: "chat-message chat-message-emote",
+                            }, react.DOM.span({
+                                    className: "chat-message-said-by",
+                            }, this.props.saidBy),
+                            " ",
+                            react.DOM.span({
+                                    className: "chat-message-text",
+                            }, this.props.text));
+                        }
+
                         return react.DOM.li({
                                 className: "chat-message",
                         }, react.DOM.span({
                                 className: "chat-message-said-by",
                         }, this.props.saidBy),
-                        " says, \"",
+                        " " + (this.props.verb || "says") + ", \"",
                         react.DOM.span({
                                 className: "chat-message-text",
                         }, this.props.text),
@@ -65,7 +77,19 @@ define([
                         this.refs.input.getDOMNode().value = "";
                         this.setState({message: ""});
 
-                        this.props.chat.sendSay(msg);
+                        var channel = this.refs.channel.getDOMNode().value;
+                        if (channel === "whisper") {
+                            // Whispers begin with the name of the recipient
+                            var i = msg.indexOf(" ");
+                            if (i <= 0) {
+                                return;
+                            }
+
+                            this.props.chat.send(channel, msg.slice(i + 1), msg.slice(0, i));
+                            return;
+                        }
+
+                        this.props.chat.send(channel, msg);
                     },
 
                     handleChange: function(event) {
@@ -104,9 +128,16 @@ define([
                                 onChange: this.handleChange,
                         }),
 
+                        react.DOM.select({
+                                ref: "channel",
+                                className: "chat-channel",
+                        }, _.map(chatChannels, function(typ, channel) {
+                            return react.DOM.option({key: channel, value: channel}, channel);
+                        })),
+
                         react.DOM.input({
                                 type: "submit",
-                                value: "say",
+                                value: "send",
                         }));
                     },
         }));
@@ -138,6 +169,88 @@ define([
             },
         }));
 
+    var rejectedReasons = {
+        URR_UNKNOWN_SKILL: "is not a skill",
+        URR_COOLDOWN:      "is not ready",
+        URR_MOVING:        "can't be used while moving",
+        URR_NO_NODE:       "needs a resource in front of you",
+        URR_DEPLETED:      "has nothing left to gather",
+
+        BRR_OUT_OF_RANGE:    "is out of range",
+        BRR_UNKNOWN_TERRAIN: "can't make that terrain",
+        BRR_RESOURCES:       "needs more resources",
+        BRR_OCCUPIED:        "is occupied",
+        BRR_NO_STRUCTURE:    "has nothing to demolish",
+        BRR_NOT_BUILDER:     "was built by someone else",
+
+        CRR_UNKNOWN_RECIPE: "is not a recipe",
+        CRR_CRAFTING:       "must wait until you've finished crafting",
+        CRR_RESOURCES:      "needs more resources",
+        CRR_NO_WORKSTATION: "needs a structure next to you",
+
+        ERR_UNKNOWN_ITEM: "is not an item",
+        ERR_WRONG_SLOT:   "can't be equipped there",
+        ERR_NOT_OWNED:    "isn't in your inventory",
+    };
+
+    var chatChannels = {
+        say:     "CR_SAY",
+        whisper: "CR_WHISPER",
+        shout:   "CR_SHOUT",
+        party:   "CR_PARTY",
+        global:  "CR_GLOBAL",
+        faction: "CR_FACTION",
+        guild:   "CR_GUILD",
+    };
+
+    var chatRejectedReasons = {
+        CHRR_NO_RECIPIENT: "isn't in this world",
+        CHRR_NO_PARTY:     "you aren't in a party",
+        CHRR_RATE_LIMITED: "you're sending messages too quickly",
+        CHRR_MUTED:        "you have been muted",
+        CHRR_IGNORED:      "is ignoring you",
+        CHRR_FILTERED:     "your message wasn't allowed",
+        CHRR_NO_FACTION:   "you haven't pledged your allegiance",
+        CHRR_NO_GUILD:     "you aren't in a guild",
+        CHRR_DROPPED:      "of your messages were dropped, you're sending them too quickly",
+    };
+
+    var partyRejectedReasons = {
+        PRR_NO_ACTOR:     "isn't in this world",
+        PRR_NOT_LEADER:   "only the leader of your party can do that",
+        PRR_IN_PARTY:     "is already in a party",
+        PRR_NOT_IN_PARTY: "you aren't in a party",
+        PRR_NOT_INVITED:  "you haven't been invited to a party",
+        PRR_NOT_MEMBER:   "isn't in your party",
+        PRR_FULL:         "the party is full",
+    };
+
+    var tradeRejectedReasons = {
+        TRR_NO_ACTOR:     "isn't near you",
+        TRR_NOT_ADJACENT: "must be standing next to you",
+        TRR_TRADING:      "you're already trading",
+        TRR_BUSY:         "is already trading",
+        TRR_NOT_TRADING:  "you aren't trading",
+        TRR_LOCKED:       "you must cancel the trade to change a locked offer",
+        TRR_NOT_LOCKED:   "both offers must be locked first",
+        TRR_RESOURCES:    "you don't have the items you offered",
+    };
+
+    var tradeStatuses = {
+        TS_COMPLETED:    "the trade is complete",
+        TS_CANCELLED:    "the trade was cancelled",
+        TS_MOVED:        "the trade was cancelled because someone moved",
+        TS_DAMAGED:      "the trade was cancelled because someone was attacked",
+        TS_DISCONNECTED: "the trade was cancelled because they left",
+        TS_UNAVAILABLE:  "the trade was cancelled because an item was no longer available",
+    };
+
+    var buildRequests = {
+        BR_SCULPT:   "sculpting",
+        BR_BUILD:    "building",
+        BR_DEMOLISH: "demolishing",
+    };
+
     var Client = function(container, loggedInConn) {
         var client = this;
 
@@ -151,8 +264,8 @@ define([
                 var inputState = new InputState(inputConn);
 
                 var chat = {
-                    sendSay: function(msg) {
-                        inputConn.sendChatRequest(game.CR_SAY, msg);
+                    send: function(channel, msg, to) {
+                        inputConn.sendChatRequest(game[chatChannels[channel]], msg, to || "");
                     },
                 };
 
@@ -184,6 +297,78 @@ define([
                         case "1":
                             inputState.chargeDown();
                             break;
+                        case "2":
+                            inputState.skillDown("cleave");
+                            break;
+                        case "3":
+                            inputState.skillDown("lance");
+                            break;
+                        case "4":
+                            inputState.skillDown("quake");
+                            break;
+                        case "5":
+                            inputState.skillDown("ioc");
+                            break;
+                        case "E":
+                            inputState.skillDown("gather");
+                            break;
+                        case "6":
+                            inputConn.sendBuildRequest(game.BR_SCULPT, "G");
+                            break;
+                        case "7":
+                            inputConn.sendBuildRequest(game.BR_SCULPT, "D");
+                            break;
+                        case "8":
+                            inputConn.sendBuildRequest(game.BR_SCULPT, "R");
+                            break;
+                        case "9":
+                            inputConn.sendBuildRequest(game.BR_SCULPT, "W");
+                            break;
+                        case "B":
+                            inputConn.sendBuildRequest(game.BR_BUILD);
+                            break;
+                        case "X":
+                            inputConn.sendBuildRequest(game.BR_DEMOLISH);
+                            break;
+                        case "T":
+                            inputConn.sendCraftRequest("torch");
+                            break;
+                        case "Y":
+                            inputConn.sendCraftRequest("boots");
+                            break;
+                        case "U":
+                            inputConn.sendCraftRequest("sword");
+                            break;
+                        case "I":
+                            inputConn.sendCraftRequest("armor");
+                            break;
+                        case "O":
+                            inputConn.sendEquipRequest(game.ES_WEAPON, "sword");
+                            break;
+                        case "P":
+                            inputConn.sendEquipRequest(game.ES_ARMOR, "armor");
+                            break;
+                        case "K":
+                            inputConn.sendEquipRequest(game.ES_FEET, "boots");
+                            break;
+                        case "L":
+                            inputConn.sendEquipRequest(game.ES_OFFHAND, "torch");
+                            break;
+                        case "Z":
+                            inputConn.sendEmoteRequest("wave");
+                            break;
+                        case "C":
+                            inputConn.sendEmoteRequest("bow");
+                            break;
+                        case "V":
+                            inputConn.sendEmoteRequest("cheer");
+                            break;
+                        case "N":
+                            inputConn.sendEmoteRequest("dance");
+                            break;
+                        case "M":
+                            inputConn.sendEmoteRequest("sit");
+                            break;
                         default:
                         }
 
@@ -218,6 +403,21 @@ define([
                         case "1":
                             inputState.chargeUp();
                             break;
+                        case "2":
+                            inputState.skillUp("cleave");
+                            break;
+                        case "3":
+                            inputState.skillUp("lance");
+                            break;
+                        case "4":
+                            inputState.skillUp("quake");
+                            break;
+                        case "5":
+                            inputState.skillUp("ioc");
+                            break;
+                        case "E":
+                            inputState.skillUp("gather");
+                            break;
                         }
 
                         switch (e.keyCode) {
@@ -244,6 +444,10 @@ define([
                 // Setup keybinds
                 setupKeybinds(inputState);
 
+                client.on("cellClicked", function(cell) {
+                    inputState.moveTo(cell);
+                });
+
                 client.on(app.EV_RECV_CHAT_SAY, function(id, saidBy, msg, saidAt) {
                     messages.push({
                         key:    id,
@@ -255,6 +459,296 @@ define([
                     render();
                 });
 
+                var onChannel = function(ev, verb) {
+                    client.on(ev, function(from, to, msg, time) {
+                        messages.push({
+                            key:    ev + "-" + from + "-" + time,
+                            saidBy: from,
+                            verb:   verb(to),
+                            text:   msg,
+                            saidAt: time,
+                        });
+
+                        render();
+                    });
+                };
+
+                onChannel(app.EV_RECV_CHAT_WHISPER, function(to) { return "whispers to " + to; });
+                onChannel(app.EV_RECV_CHAT_SHOUT, function() { return "shouts"; });
+                onChannel(app.EV_RECV_CHAT_PARTY, function() { return "says to the party"; });
+                onChannel(app.EV_RECV_CHAT_GLOBAL, function() { return "says to everyone"; });
+                onChannel(app.EV_RECV_CHAT_FACTION, function() { return "says to their allies"; });
+                onChannel(app.EV_RECV_CHAT_GUILD, function() { return "says to the guild"; });
+                onChannel(app.EV_RECV_CHAT_ANNOUNCE, function() { return "announces"; });
+
+                client.on(app.EV_RECV_CHAT_EMOTE, function(from, to, msg, time) {
+                    messages.push({
+                        key:    "emote-" + from + "-" + time,
+                        saidBy: from,
+                        text:   msg,
+                        saidAt: time,
+                        emote:  true,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_COMMAND_REPLY, function(command, reply, failed, time) {
+                    // Replies such as /help span multiple lines
+                    _.each(reply.split("\n"), function(line, i) {
+                        messages.push({
+                            key:    "reply-" + command + "-" + time + "-" + i,
+                            saidBy: "*",
+                            text:   line,
+                            saidAt: time,
+                        });
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_CHAT_REJECTED, function(channel, to, reason, rejectedAt, dropped) {
+                    var text = chatRejectedReasons[reason];
+                    if (reason === "CHRR_NO_RECIPIENT" || reason === "CHRR_IGNORED") {
+                        text = to + " " + text;
+                    } else if (reason === "CHRR_DROPPED") {
+                        text = dropped + " " + text;
+                    }
+
+                    messages.push({
+                        key:    "rejected-" + channel + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                // Members are only listed when they have changed. The
+                // positions and health of the members are sent more
+                // often so they can be shown outside of the viewport.
+                var partyMembers = "";
+
+                client.on(app.EV_RECV_PARTY, function(leader, members, time) {
+                    var names = _.map(members, function(m) {
+                        return m.Name === leader ? m.Name + " (leader)" : m.Name;
+                    }).join(", ");
+
+                    if (names === partyMembers) {
+                        return;
+                    }
+                    partyMembers = names;
+
+                    messages.push({
+                        key:    "party-" + time,
+                        saidBy: "*",
+                        text:   names === "" ? "you aren't in a party" : "party: " + names,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_FACTION, function(allegiance, guild, standing, time) {
+                    var text = "you don't serve anyone, type /pledge good or /pledge bad to choose a side";
+                    if (allegiance !== "") {
+                        text = "you serve the " + allegiance + " with a standing of " + standing;
+                        if (guild !== "") {
+                            text += " as a member of " + guild;
+                        }
+                    }
+
+                    messages.push({
+                        key:    "faction-" + time + "-" + standing,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_PARTY_INVITE, function(from, time) {
+                    messages.push({
+                        key:    "party-invite-" + from + "-" + time,
+                        saidBy: "*",
+                        text:   from + " invited you to their party, type /accept to join",
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_PARTY_REJECTED, function(request, name, reason, rejectedAt) {
+                    var text = partyRejectedReasons[reason];
+                    if (reason === "PRR_NO_ACTOR" || reason === "PRR_IN_PARTY" || reason === "PRR_NOT_MEMBER") {
+                        text = name + " " + text;
+                    }
+
+                    messages.push({
+                        key:    "rejected-" + request + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                var describeOffer = function(offer) {
+                    var items = _.map(offer.Items, function(n, item) {
+                        return n + " " + item;
+                    }).join(", ");
+
+                    var text = offer.Name + " offers " + (items === "" ? "nothing" : items);
+                    if (offer.Confirmed) {
+                        return text + " (confirmed)";
+                    }
+                    return offer.Locked ? text + " (locked)" : text;
+                };
+
+                client.on(app.EV_RECV_TRADE, function(status, mine, theirs, time) {
+                    var text = tradeStatuses[status];
+                    if (status === "TS_OPEN") {
+                        text = describeOffer(mine) + "; " + describeOffer(theirs);
+                    }
+
+                    messages.push({
+                        key:    "trade-" + time + "-" + text,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_TRADE_INVITE, function(from, time) {
+                    messages.push({
+                        key:    "trade-invite-" + from + "-" + time,
+                        saidBy: "*",
+                        text:   from + " wants to trade, type /trade " + from + " to begin",
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_TRADE_REJECTED, function(request, name, reason, rejectedAt) {
+                    var text = tradeRejectedReasons[reason];
+                    if (reason === "TRR_NO_ACTOR" || reason === "TRR_NOT_ADJACENT" || reason === "TRR_BUSY") {
+                        text = name + " " + text;
+                    }
+
+                    messages.push({
+                        key:    "rejected-" + request + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_USE_REJECTED, function(skill, reason, rejectedAt) {
+                    messages.push({
+                        key:    "rejected-" + skill + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   skill + " " + rejectedReasons[reason],
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_BUILD_REJECTED, function(request, cell, reason, rejectedAt) {
+                    messages.push({
+                        key:    "rejected-" + request + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   buildRequests[request] + " " + rejectedReasons[reason],
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_CRAFT, function(recipe, completed, end, time) {
+                    messages.push({
+                        key:    "craft-" + recipe + "-" + time,
+                        saidBy: "*",
+                        text:   (completed ? "crafted " : "crafting ") + recipe,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_CRAFT_REJECTED, function(recipe, reason, rejectedAt) {
+                    messages.push({
+                        key:    "rejected-" + recipe + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   recipe + " " + rejectedReasons[reason],
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_EQUIP_REJECTED, function(item, reason, rejectedAt) {
+                    messages.push({
+                        key:    "rejected-" + item + "-" + rejectedAt,
+                        saidBy: "*",
+                        text:   item + " " + rejectedReasons[reason],
+                        saidAt: rejectedAt,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_KICKED, function(reason, time) {
+                    messages.push({
+                        key:    "kicked-" + time,
+                        saidBy: "*",
+                        text:   reason === "" ? "you have been kicked" : "you have been kicked, " + reason,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
+                client.on(app.EV_RECV_WORLD_ENDED, function(epoch, reason, time) {
+                    messages.push({
+                        key:    "world-ended-" + epoch,
+                        saidBy: "*",
+                        text:   "the world has ended, " + reason + ". a new world will begin shortly",
+                        saidAt: time,
+                    });
+
+                    render();
+
+                    // The actor is no longer part of any world and
+                    // must reconnect to join the one that replaced it.
+                    setTimeout(function() {
+                        window.location.reload();
+                    }, 5000);
+                });
+
+                client.on(app.EV_RECV_RESOURCES, function(time, resources) {
+                    var text = _.map(resources, function(n, name) {
+                        return name + ": " + n;
+                    }).join(", ");
+
+                    messages.push({
+                        key:    "resources-" + time,
+                        saidBy: "*",
+                        text:   text,
+                        saidAt: time,
+                    });
+
+                    render();
+                });
+
                 client.on(app.EV_SENT_CHAT_SAY, function() {
                     chatDisplayed = false;
                     render();
@@ -72,6 +72,18 @@ define(["underscore",
             sendChargeCancel();
         };
 
+        inputState.moveTo = function(cell) {
+            inputConn.sendMoveToRequest(cell.X, cell.Y);
+        };
+
+        inputState.skillDown = function(skill) {
+            inputConn.sendUseRequest(game.UR_USE, skill);
+        };
+
+        inputState.skillUp = function(skill) {
+            inputConn.sendUseRequest(game.UR_USE_CANCEL, skill);
+        };
+
         return this;
     };
 